@@ -4,23 +4,26 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/ok-ryoko/turret/internal/build"
-	"github.com/ok-ryoko/turret/internal/spec"
 
 	"github.com/containers/storage/pkg/unshare"
-	"github.com/pelletier/go-toml/v2"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 )
 
+var reShellSpecial = regexp.MustCompile(`[^-+,./0-9:=@A-Z_a-z]`)
+
 func newBuildCmd(logger *logrus.Logger) *cli.Command {
 	return &cli.Command{
 		Name:                   "build",
@@ -30,6 +33,28 @@ func newBuildCmd(logger *logrus.Logger) *cli.Command {
 		HideHelpCommand:        true,
 		UseShortOptionHandling: true,
 		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "annotate-package-report",
+				Aliases: []string{"R"},
+				Usage:   "Annotate the image with the report of the packages that the build changed",
+				Value:   false,
+			},
+			&cli.StringSliceFlag{
+				Name:    "arg",
+				Aliases: []string{"a"},
+				Usage:   "Set the build argument KEY to VALUE, overriding SPEC (repeatable)",
+			},
+			&cli.StringFlag{
+				Name:    "authfile",
+				Usage:   "Read registry credentials from `FILE` when copying the image to its destinations",
+				EnvVars: []string{"REGISTRY_AUTH_FILE"},
+			},
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Aliases: []string{"n"},
+				Usage:   "Print the steps of the build without running them",
+				Value:   false,
+			},
 			&cli.BoolFlag{
 				Name:    "force",
 				Aliases: []string{"f"},
@@ -39,7 +64,7 @@ func newBuildCmd(logger *logrus.Logger) *cli.Command {
 			&cli.BoolFlag{
 				Name:    "hash-spec",
 				Aliases: []string{"H"},
-				Usage:   "Annotate the image with the SHA256 hash of SPEC",
+				Usage:   "Annotate the image with the SHA256 hash of SPEC and its build arguments",
 				Value:   false,
 			},
 			&cli.BoolFlag{
@@ -54,6 +79,27 @@ func newBuildCmd(logger *logrus.Logger) *cli.Command {
 				Usage:   "Create or update the 'latest' tag",
 				Value:   false,
 			},
+			&cli.BoolFlag{
+				Name:    "locked",
+				Aliases: []string{"L"},
+				Usage:   "Install the package versions in the lockfile and fail if the build deviates from it",
+				Value:   false,
+			},
+			&cli.BoolFlag{
+				Name:  "no-cache",
+				Usage: "Run every step instead of reusing the results cached by earlier builds",
+				Value: false,
+			},
+			&cli.StringSliceFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Copy the image to `DEST`, given as TRANSPORT:REFERENCE, after committing it (repeatable)",
+			},
+			&cli.StringFlag{
+				Name:    "package-report",
+				Aliases: []string{"r"},
+				Usage:   "Write the packages that the build added, removed and changed to `FILE` in JSON",
+			},
 			&cli.BoolFlag{
 				Name:    "pull",
 				Aliases: []string{"p"},
@@ -66,6 +112,30 @@ func newBuildCmd(logger *logrus.Logger) *cli.Command {
 				Usage:   "Print nothing (overriding alias for --verbosity 0)",
 				Value:   false,
 			},
+			&cli.UintFlag{
+				Name:  "retry",
+				Usage: "Retry copying the image to a destination up to `N` times",
+				Value: 3,
+			},
+			&cli.DurationFlag{
+				Name:  "retry-delay",
+				Usage: "Wait `DURATION` between attempts to copy the image to a destination; 0 for exponential backoff",
+			},
+			&cli.StringFlag{
+				Name:    "sbom",
+				Aliases: []string{"s"},
+				Usage:   "Write a CycloneDX SBOM of the image to `FILE` and annotate the image with its digest",
+			},
+			&cli.BoolFlag{
+				Name:  "tls-verify",
+				Usage: "Verify the TLS certificates of container registries when copying the image to its destinations",
+				Value: true,
+			},
+			&cli.StringFlag{
+				Name:    "vendored",
+				Aliases: []string{"V"},
+				Usage:   "Install packages from the directory `DIR` populated by the vendor command, without network access",
+			},
 			&cli.UintFlag{
 				Name:    "verbosity",
 				Aliases: []string{"v"},
@@ -81,7 +151,10 @@ func newBuildCmd(logger *logrus.Logger) *cli.Command {
 				return nil
 			}
 
-			unshare.MaybeReexecUsingUserNamespace(true)
+			dryRun := cCtx.Bool("dry-run")
+			if !dryRun {
+				unshare.MaybeReexecUsingUserNamespace(true)
+			}
 			ctx := context.Background()
 
 			verbosity := cCtx.Uint("verbosity")
@@ -96,104 +169,176 @@ func newBuildCmd(logger *logrus.Logger) *cli.Command {
 			}
 			logger.Debugln("processed spec path")
 
-			spec, digest, err := createSpec(specPath, cCtx.Bool("hash-spec"))
+			args, err := parseArgs(cCtx.StringSlice("arg"))
+			if err != nil {
+				return fmt.Errorf("parsing build arguments: %w", err)
+			}
+
+			spec, digest, err := createSpec(specPath, cCtx.Bool("hash-spec"), args)
 			if err != nil {
+				logSpecErrors(logger, err)
 				return fmt.Errorf("creating in-memory representation of spec: %w", err)
 			}
 			logger.Debugln("created in-memory representation of spec")
 
 			options := build.ExecuteOptions{
-				Digest:      digest,
-				Force:       cCtx.Bool("force"),
-				Keep:        cCtx.Bool("keep"),
-				Latest:      cCtx.Bool("latest"),
-				LogCommands: verbosity >= 4,
-				Pull:        cCtx.Bool("pull"),
+				AnnotatePackageReport: cCtx.Bool("annotate-package-report"),
+				AuthFile:              cCtx.String("authfile"),
+				Destinations:          cCtx.StringSlice("output"),
+				Digest:                digest,
+				Force:                 cCtx.Bool("force"),
+				Keep:                  cCtx.Bool("keep"),
+				Latest:                cCtx.Bool("latest"),
+				LogCommands:           verbosity >= 4,
+				NoCache:               cCtx.Bool("no-cache"),
+				Pull:                  cCtx.Bool("pull"),
+				Retries:               cCtx.Uint("retry"),
+				RetryDelay:            cCtx.Duration("retry-delay"),
+				SBOM:                  cCtx.String("sbom") != "",
+				SkipTLSVerify:         !cCtx.Bool("tls-verify"),
 			}
 
-			imageID, err := build.Execute(ctx, spec, logger, options)
+			if dir := cCtx.String("vendored"); dir != "" {
+				options.Vendored, err = filepath.Abs(dir)
+				if err != nil {
+					return fmt.Errorf("canonicalizing vendor directory path: %w", err)
+				}
+			}
+
+			if cCtx.Bool("locked") {
+				lock, err := readLock(specPath)
+				if err != nil {
+					return fmt.Errorf("reading lockfile: %w", err)
+				}
+				options.Lock = &lock
+				logger.Debugln("read lockfile")
+			}
+
+			if dryRun {
+				plan, err := build.Plan(spec, logger, options)
+				if err != nil {
+					return fmt.Errorf("planning build according to given spec: %w", err)
+				}
+				printPlan(os.Stdout, plan)
+				return nil
+			}
+
+			result, err := build.Execute(ctx, spec, logger, options)
 			if err != nil {
 				return fmt.Errorf("building image according to given spec: %w", err)
 			}
-			fmt.Println(imageID)
 
-			return nil
-		},
-	}
-}
-
-// createSpec decodes the contents of the TOML file at the absolute path `p`
-// into a build spec, filling in missing values, validating the result, and
-// optionally returning an annotated string representation of the file's SHA256
-// digest.
-func createSpec(p string, hash bool) (spec.Spec, string, error) {
-	if !filepath.IsAbs(p) {
-		return spec.Spec{}, "", fmt.Errorf("expected absolute path, got %q", p)
-	}
+			// An existing lockfile is rewritten only by the lock command, so
+			// that ordinary builds don't resolve packages anew behind its back
+			//
+			if _, err := os.Stat(lockPath(specPath)); errors.Is(err, fs.ErrNotExist) {
+				p, err := writeLock(specPath, result.Lock)
+				if err != nil {
+					return fmt.Errorf("writing lockfile: %w", err)
+				}
+				logger.Debugf("wrote lockfile %s", p)
+			} else if err != nil {
+				return fmt.Errorf("checking for lockfile: %w", err)
+			}
 
-	blob, err := os.ReadFile(p)
-	if err != nil {
-		return spec.Spec{}, "", fmt.Errorf("reading spec file: %w", err)
-	}
+			if p := cCtx.String("package-report"); p != "" {
+				if err := writePackageReport(p, result.PackageReport); err != nil {
+					return fmt.Errorf("writing package report: %w", err)
+				}
+				logger.Debugf("wrote package report %s", p)
+			}
 
-	digest := ""
-	if hash {
-		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(blob))
-	}
+			if p := cCtx.String("sbom"); p != "" {
+				if err := os.WriteFile(p, result.SBOM, 0o644); err != nil {
+					return fmt.Errorf("writing SBOM: %w", err)
+				}
+				logger.Debugf("wrote SBOM %s", p)
+			}
 
-	r := bytes.NewReader(blob)
-	d := toml.NewDecoder(r)
-	d.DisallowUnknownFields()
+			fmt.Println(result.ImageID)
 
-	s := spec.Spec{}
-	if err = d.Decode(&s); err != nil {
-		return spec.Spec{}, "", fmt.Errorf("decoding TOML: %w", err)
+			return nil
+		},
 	}
+}
 
-	s = spec.Fill(s)
-
-	if len(s.Copy) > 0 {
-		parent := filepath.Dir(p)
-		for i, c := range s.Copy {
-			if c.Base == "" {
-				s.Copy[i].Base = parent
-			} else if strings.HasPrefix(c.Base, "~") {
-				home, err := os.UserHomeDir()
-				if err != nil {
-					return spec.Spec{}, "", fmt.Errorf("discovering home directory on host: %w", err)
-				}
-				if c.Base == "~" {
-					s.Copy[i].Base = home
-				} else if strings.HasPrefix(c.Base, "~/") {
-					_, after, _ := strings.Cut(c.Base, "/")
-					s.Copy[i].Base = filepath.Clean(filepath.Join(home, after))
-				}
-			} else if filepath.IsLocal(c.Base) {
-				s.Copy[i].Base, err = filepath.Abs(filepath.Join(parent, c.Base))
-				if err != nil {
-					return spec.Spec{}, "", fmt.Errorf("canonicalizing base path %q", c.Base)
-				}
+// printPlan writes a human-readable representation of the steps of a build
+// pipeline to `w`.
+func printPlan(w io.Writer, plan []build.Step) {
+	for i, st := range plan {
+		if st.Stage != "" {
+			fmt.Fprintf(w, "%d. [%s] %s\n", i+1, st.Stage, st.Description)
+		} else {
+			fmt.Fprintf(w, "%d. %s\n", i+1, st.Description)
+		}
+		for _, p := range st.Processes {
+			fmt.Fprintf(w, "   $ %s\n", quoteCmd(p.Cmd))
+			if p.Probe {
+				fmt.Fprintln(w, "     probe: the outcome decides the capabilities of a later process")
+			}
+			if len(p.Capabilities) > 0 {
+				fmt.Fprintf(w, "     capabilities: %s\n", strings.Join(p.Capabilities, " "))
 			} else {
-				s.Copy[i].Base = filepath.Clean(c.Base)
+				fmt.Fprintln(w, "     capabilities: none")
 			}
-
-			if c.Destination != "" {
-				s.Copy[i].Destination = filepath.Clean(c.Destination)
+			if len(p.ConditionalCapabilities) > 0 {
+				fmt.Fprintf(w, "     capabilities if %s: %s\n", p.Condition, strings.Join(p.ConditionalCapabilities, " "))
 			}
-
-			for j, src := range s.Copy[i].Sources {
-				if src != "" {
-					s.Copy[i].Sources[j] = filepath.Clean(src)
-				}
+			if p.Network {
+				fmt.Fprintln(w, "     network: enabled")
+			} else {
+				fmt.Fprintln(w, "     network: disabled")
+			}
+			if len(p.Env) > 0 {
+				fmt.Fprintf(w, "     env: %s\n", quoteCmd(p.Env))
+			}
+			if p.User != "" {
+				fmt.Fprintf(w, "     user: %s\n", p.User)
 			}
+			if p.WorkDir != "" {
+				fmt.Fprintf(w, "     work-dir: %s\n", p.WorkDir)
+			}
+		}
+		for _, d := range st.Details {
+			fmt.Fprintf(w, "   - %s\n", d)
 		}
 	}
+}
+
+// quoteCmd joins the arguments of a command line with spaces, quoting every
+// argument that a POSIX shell wouldn't interpret literally.
+func quoteCmd(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && !reShellSpecial.MatchString(a) {
+			quoted[i] = a
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
 
-	if err = spec.Validate(s); err != nil {
-		return spec.Spec{}, "", fmt.Errorf("validating spec: %w", err)
+// writePackageReport writes a package report to the file at `p` in JSON.
+func writePackageReport(p string, r build.PackageReport) error {
+	blob, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if err := os.WriteFile(p, append(blob, '\n'), 0o644); err != nil {
+		return fmt.Errorf("%w", err)
 	}
+	return nil
+}
 
-	return s, digest, nil
+// logSpecErrors logs every problem found in a spec on its own line.
+func logSpecErrors(l *logrus.Logger, err error) {
+	var errs specErrors
+	if errors.As(err, &errs) && len(errs) > 1 {
+		for _, e := range errs {
+			l.Errorln(e)
+		}
+	}
 }
 
 func setLoggerLevel(l *logrus.Logger, verbosity uint) {
@@ -0,0 +1,161 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ok-ryoko/turret/internal/build"
+
+	"github.com/containers/storage/pkg/unshare"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+const lockFileHeader string = "# This file is generated by Turret. Do not edit it by hand.\n\n"
+
+func newLockCmd(logger *logrus.Logger) *cli.Command {
+	return &cli.Command{
+		Name:                   "lock",
+		Usage:                  "Record the packages a Turret spec installs without committing an image",
+		ArgsUsage:              "SPEC",
+		HideHelpCommand:        true,
+		UseShortOptionHandling: true,
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "arg",
+				Aliases: []string{"a"},
+				Usage:   "Set the build argument KEY to VALUE, overriding SPEC (repeatable)",
+			},
+			&cli.BoolFlag{
+				Name:  "no-cache",
+				Usage: "Run every step instead of reusing the results cached by earlier builds",
+				Value: false,
+			},
+			&cli.BoolFlag{
+				Name:    "pull",
+				Aliases: []string{"p"},
+				Usage:   "Pull the base image from remote storage if it doesn't exist locally",
+				Value:   false,
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "Print nothing (overriding alias for --verbosity 0)",
+				Value:   false,
+			},
+			&cli.UintFlag{
+				Name:    "verbosity",
+				Aliases: []string{"v"},
+				Usage:   "Set the output level, from nothing (0) to everything (4)",
+				Value:   1,
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			if !cCtx.Args().Present() {
+				if err := cli.ShowCommandHelp(cCtx, cCtx.Command.Name); err != nil {
+					return fmt.Errorf("displaying help: %w", err)
+				}
+				return nil
+			}
+
+			unshare.MaybeReexecUsingUserNamespace(true)
+			ctx := context.Background()
+
+			verbosity := cCtx.Uint("verbosity")
+			if cCtx.Bool("quiet") {
+				verbosity = 0
+			}
+			setLoggerLevel(logger, verbosity)
+
+			specPath, err := filepath.Abs(cCtx.Args().First())
+			if err != nil {
+				return fmt.Errorf("canonicalizing spec path: %w", err)
+			}
+
+			args, err := parseArgs(cCtx.StringSlice("arg"))
+			if err != nil {
+				return fmt.Errorf("parsing build arguments: %w", err)
+			}
+
+			spec, _, err := createSpec(specPath, false, args)
+			if err != nil {
+				logSpecErrors(logger, err)
+				return fmt.Errorf("creating in-memory representation of spec: %w", err)
+			}
+			logger.Debugln("created in-memory representation of spec")
+
+			options := build.ExecuteOptions{
+				LockOnly:    true,
+				LogCommands: verbosity >= 4,
+				NoCache:     cCtx.Bool("no-cache"),
+				Pull:        cCtx.Bool("pull"),
+			}
+
+			result, err := build.Execute(ctx, spec, logger, options)
+			if err != nil {
+				return fmt.Errorf("resolving packages according to given spec: %w", err)
+			}
+
+			p, err := writeLock(specPath, result.Lock)
+			if err != nil {
+				return fmt.Errorf("writing lockfile: %w", err)
+			}
+			fmt.Println(p)
+
+			return nil
+		},
+	}
+}
+
+// lockPath returns the path to the lockfile of the spec at `specPath`, which
+// sits next to the spec and shares its name, e.g., alpine.lock for
+// alpine.toml.
+func lockPath(specPath string) string {
+	return strings.TrimSuffix(specPath, filepath.Ext(specPath)) + ".lock"
+}
+
+// readLock reads the lockfile of the spec at the absolute path `specPath`.
+func readLock(specPath string) (build.Lock, error) {
+	p := lockPath(specPath)
+
+	blob, err := os.ReadFile(p)
+	if err != nil {
+		return build.Lock{}, fmt.Errorf("%w", err)
+	}
+
+	d := toml.NewDecoder(bytes.NewReader(blob))
+	d.DisallowUnknownFields()
+
+	lock := build.Lock{}
+	if err := d.Decode(&lock); err != nil {
+		return build.Lock{}, fmt.Errorf("decoding %s: %w", p, err)
+	}
+
+	return lock, nil
+}
+
+// writeLock writes `lock` to the lockfile of the spec at the absolute path
+// `specPath`, returning the path to the lockfile.
+func writeLock(specPath string, lock build.Lock) (string, error) {
+	lock.Spec = filepath.Base(specPath)
+
+	blob, err := toml.Marshal(lock)
+	if err != nil {
+		return "", fmt.Errorf("encoding lock: %w", err)
+	}
+
+	p := lockPath(specPath)
+	if err := os.WriteFile(p, append([]byte(lockFileHeader), blob...), 0o644); err != nil {
+		return "", fmt.Errorf("%w", err)
+	}
+
+	return p, nil
+}
@@ -32,9 +32,14 @@ func newApp(logger *logrus.Logger) *cli.App {
 		DefaultCommand: "help",
 		Commands: []*cli.Command{
 			newBuildCmd(logger),
+			newLockCmd(logger),
+			newSchemaCmd(),
+			newValidateCmd(),
+			newVendorCmd(logger),
 			newVersionCmd(),
 		},
-		HideVersion: true,
+		HideVersion:               true,
+		DisableSliceFlagSeparator: true,
 		Authors: []*cli.Author{
 			{
 				Name:  "OK Ryoko",
@@ -0,0 +1,36 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ok-ryoko/turret/internal/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newSchemaCmd() *cli.Command {
+	return &cli.Command{
+		Name:            "schema",
+		Usage:           "Print the JSON Schema for Turret specs and exit",
+		HideHelpCommand: true,
+		Action: func(cCtx *cli.Context) error {
+			schema, err := spec.Schema()
+			if err != nil {
+				return fmt.Errorf("generating JSON Schema: %w", err)
+			}
+
+			e := json.NewEncoder(os.Stdout)
+			e.SetIndent("", "  ")
+			if err := e.Encode(schema); err != nil {
+				return fmt.Errorf("encoding JSON Schema: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,575 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ok-ryoko/turret/internal/spec"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// specError describes a problem with a spec, locating it in a spec file when
+// possible.
+type specError struct {
+	// Absolute path to the spec file in which the problem was found
+	File string `json:"file,omitempty"`
+
+	// Line and column at which the problem was found; both are 0 when the
+	// position of the problem is unknown
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+
+	// Path to the offending field using TOML key notation, e.g., copy[2].owner
+	Field string `json:"field,omitempty"`
+
+	// Offending value
+	Value any `json:"value,omitempty"`
+
+	// Description of the problem
+	Message string `json:"message"`
+}
+
+// Error returns a string representation of the problem prefixed by its
+// location.
+func (e specError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d:%d", e.Line, e.Column)
+		}
+		b.WriteString(": ")
+	}
+	if e.Field != "" {
+		b.WriteString(e.Field)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// specErrors holds every problem found in a spec.
+type specErrors []specError
+
+// Error returns a summary of the problems.
+func (e specErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("found %d problems in spec", len(e))
+}
+
+// specFile holds the contents of a spec file.
+type specFile struct {
+	// Absolute path to the spec file
+	path string
+
+	// Raw contents of the spec file
+	blob []byte
+
+	// Decoded contents of the spec file
+	tree map[string]any
+}
+
+// parseArgs converts a slice of "KEY=VALUE"s into a map of build arguments.
+func parseArgs(pairs []string) (map[string]string, error) {
+	args := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected format 'KEY=VALUE' for argument %q", p)
+		}
+		args[k] = v
+	}
+	return args, nil
+}
+
+// createSpec decodes the contents of the TOML file at the absolute path `p`
+// and of every spec it extends into a build spec, overriding the spec's build
+// arguments with `args`, expanding references to build arguments, filling in
+// missing values, validating the result, and optionally returning an annotated
+// string representation of the SHA256 digest of the chain of spec files and
+// the effective build arguments.
+//
+// Problems with the contents of the spec files are reported together as a
+// specErrors.
+func createSpec(p string, hash bool, args map[string]string) (spec.Spec, string, error) {
+	if !filepath.IsAbs(p) {
+		return spec.Spec{}, "", fmt.Errorf("expected absolute path, got %q", p)
+	}
+
+	chain, err := readSpecChain(p)
+	if err != nil {
+		return spec.Spec{}, "", fmt.Errorf("reading spec chain: %w", err)
+	}
+
+	// Paths are anchored to the directory of the spec that declares them
+	// only once the build arguments they reference are known, since an
+	// argument may make a path absolute
+	//
+	effective := effectiveArgs(chain, args)
+	for _, f := range chain {
+		dir := filepath.Dir(f.path)
+		anchorCopyBases(f.tree, dir, effective)
+		anchorPackagePaths(f.tree, dir, effective)
+		anchorDestinations(f.tree, dir, effective)
+	}
+
+	// The build arguments are hashed together with the spec files because
+	// different arguments make different images from the same files
+	//
+	digest := ""
+	if hash {
+		h := sha256.New()
+		for _, f := range chain {
+			h.Write(f.blob)
+		}
+		keys := make([]string, 0, len(effective))
+		for k := range effective {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "%s=%q\n", k, effective[k])
+		}
+		digest = fmt.Sprintf("sha256:%x", h.Sum(nil))
+	}
+
+	tree := chain[len(chain)-1].tree
+	for i := len(chain) - 2; i >= 0; i-- {
+		tree = spec.Merge(tree, chain[i].tree)
+	}
+	delete(tree, "extends")
+
+	blob, err := toml.Marshal(tree)
+	if err != nil {
+		return spec.Spec{}, "", fmt.Errorf("encoding merged spec: %w", err)
+	}
+
+	r := bytes.NewReader(blob)
+	d := toml.NewDecoder(r)
+	d.DisallowUnknownFields()
+
+	s := spec.Spec{}
+	if err = d.Decode(&s); err != nil {
+		return spec.Spec{}, "", fmt.Errorf("decoding TOML: %w", err)
+	}
+
+	if len(args) > 0 {
+		if s.Args == nil {
+			s.Args = map[string]string{}
+		}
+		for k, v := range args {
+			s.Args[k] = v
+		}
+	}
+
+	s, err = spec.Expand(s)
+	if err != nil {
+		return spec.Spec{}, "", locateValidationErrors(err, chain)
+	}
+
+	s = spec.Fill(s)
+
+	if err = resolveCopyPaths(s.Copy); err != nil {
+		return spec.Spec{}, "", err
+	}
+	if err = resolvePackagePaths(s.Packages); err != nil {
+		return spec.Spec{}, "", err
+	}
+	for _, st := range s.Stages {
+		if err = resolveCopyPaths(st.Copy); err != nil {
+			return spec.Spec{}, "", err
+		}
+		if err = resolvePackagePaths(st.Packages); err != nil {
+			return spec.Spec{}, "", err
+		}
+	}
+
+	if err = spec.Validate(s); err != nil {
+		return spec.Spec{}, "", locateValidationErrors(err, chain)
+	}
+
+	return s, digest, nil
+}
+
+// readSpecChain reads the spec at the absolute path `p` followed by every spec
+// it transitively extends.
+//
+// Each spec is decoded strictly so that unknown fields are reported against
+// the file that contains them.
+func readSpecChain(p string) ([]specFile, error) {
+	var chain []specFile
+
+	seen := map[string]bool{}
+	for p != "" {
+		if seen[p] {
+			return nil, newExtendsError(chain, fmt.Sprintf("spec %q extends itself", p))
+		}
+		seen[p] = true
+
+		blob, err := os.ReadFile(p)
+		if err != nil {
+			if len(chain) > 0 {
+				return nil, newExtendsError(chain, fmt.Sprintf("reading parent spec: %v", err))
+			}
+			return nil, fmt.Errorf("reading spec file: %w", err)
+		}
+
+		r := bytes.NewReader(blob)
+		d := toml.NewDecoder(r)
+		d.DisallowUnknownFields()
+
+		s := spec.Spec{}
+		if err = d.Decode(&s); err != nil {
+			return nil, newDecodeErrors(p, err)
+		}
+
+		tree := map[string]any{}
+		if err = toml.Unmarshal(blob, &tree); err != nil {
+			return nil, newDecodeErrors(p, err)
+		}
+
+		parent := filepath.Dir(p)
+		chain = append(chain, specFile{path: p, blob: blob, tree: tree})
+
+		p = ""
+		if s.Extends != "" {
+			p = s.Extends
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(parent, p)
+			}
+			p = filepath.Clean(p)
+		}
+	}
+
+	return chain, nil
+}
+
+// resolveCopyPaths expands a leading tilde in the bases of the copy tables
+// that copy files from the host and cleans all paths in the copy tables.
+func resolveCopyPaths(copies []spec.Copy) error {
+	for i, c := range copies {
+		if c.FromStage == "" && strings.HasPrefix(c.Base, "~") {
+			base, err := expandTilde(c.Base)
+			if err != nil {
+				return err
+			}
+			copies[i].Base = base
+		} else {
+			copies[i].Base = filepath.Clean(c.Base)
+		}
+
+		if c.Destination != "" {
+			copies[i].Destination = filepath.Clean(c.Destination)
+		}
+
+		for j, src := range copies[i].Sources {
+			if src != "" {
+				copies[i].Sources[j] = filepath.Clean(src)
+			}
+		}
+	}
+	return nil
+}
+
+// resolvePackagePaths expands a leading tilde in the key paths of package
+// repositories, in the path of the local package repository and in the paths
+// to package files and cleans them.
+func resolvePackagePaths(packages spec.Packages) error {
+	repositories := packages.Repositories
+	for i, r := range repositories {
+		if r.Key == "" {
+			continue
+		}
+		if strings.HasPrefix(r.Key, "~") {
+			key, err := expandTilde(r.Key)
+			if err != nil {
+				return err
+			}
+			repositories[i].Key = key
+		} else {
+			repositories[i].Key = filepath.Clean(r.Key)
+		}
+	}
+
+	for i, f := range packages.InstallFiles {
+		if f == "" {
+			continue
+		}
+		if strings.HasPrefix(f, "~") {
+			p, err := expandTilde(f)
+			if err != nil {
+				return err
+			}
+			packages.InstallFiles[i] = p
+		} else {
+			packages.InstallFiles[i] = filepath.Clean(f)
+		}
+	}
+
+	if local := packages.Local; local != nil && local.Path != "" {
+		if strings.HasPrefix(local.Path, "~") {
+			p, err := expandTilde(local.Path)
+			if err != nil {
+				return err
+			}
+			local.Path = p
+		} else {
+			local.Path = filepath.Clean(local.Path)
+		}
+	}
+
+	return nil
+}
+
+// expandTilde replaces a path equal to "~" or starting with "~/" with the
+// corresponding path in the home directory of the user invoking the program.
+// Other paths are returned unchanged.
+func expandTilde(p string) (string, error) {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("discovering home directory on host: %w", err)
+	}
+	if p == "~" {
+		return home, nil
+	}
+	_, after, _ := strings.Cut(p, "/")
+	return filepath.Clean(filepath.Join(home, after)), nil
+}
+
+// anchorPackagePaths resolves the local key paths of the package
+// repositories, the local path of the local package repository and the local
+// paths to package files in the decoded spec `tree` and in its stages with
+// respect to the absolute path `dir`, substituting the build arguments in
+// `args`.
+func anchorPackagePaths(tree map[string]any, dir string, args map[string]string) {
+	if stages, ok := tree["stages"].(map[string]any); ok {
+		for _, st := range stages {
+			if t, ok := st.(map[string]any); ok {
+				anchorPackagePaths(t, dir, args)
+			}
+		}
+	}
+
+	packages, ok := tree["packages"].(map[string]any)
+	if !ok {
+		return
+	}
+
+	repositories, _ := packages["repositories"].([]any)
+	for _, r := range repositories {
+		t, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+		if key, ok := t["key"].(string); ok {
+			t["key"] = anchorPath(key, dir, args)
+		}
+	}
+
+	if t, ok := packages["local"].(map[string]any); ok {
+		if p, ok := t["path"].(string); ok {
+			t["path"] = anchorPath(p, dir, args)
+		}
+	}
+
+	files, _ := packages["install-files"].([]any)
+	for i, f := range files {
+		if p, ok := f.(string); ok {
+			files[i] = anchorPath(p, dir, args)
+		}
+	}
+}
+
+// anchorCopyBases resolves the blank and local bases of the copy tables in the
+// decoded spec `tree` and in its stages with respect to the absolute path
+// `dir`, substituting the build arguments in `args`. Copy tables that copy
+// files from a stage are left untouched.
+func anchorCopyBases(tree map[string]any, dir string, args map[string]string) {
+	if stages, ok := tree["stages"].(map[string]any); ok {
+		for _, st := range stages {
+			if t, ok := st.(map[string]any); ok {
+				anchorCopyBases(t, dir, args)
+			}
+		}
+	}
+
+	copies, ok := tree["copy"].([]any)
+	if !ok {
+		return
+	}
+
+	for _, c := range copies {
+		t, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, ok := t["from-stage"]; ok {
+			continue
+		}
+		base, _ := t["base"].(string)
+		if spec.ExpandReferences(base, args) == "" {
+			t["base"] = dir
+			continue
+		}
+		t["base"] = anchorPath(base, dir, args)
+	}
+}
+
+// anchorDestinations resolves the local paths of the destinations in the
+// decoded spec `tree` whose transport writes to the host's file system with
+// respect to the absolute path `dir`, substituting the build arguments in
+// `args`.
+func anchorDestinations(tree map[string]any, dir string, args map[string]string) {
+	this, ok := tree["this"].(map[string]any)
+	if !ok {
+		return
+	}
+
+	destinations, _ := this["destinations"].([]any)
+	for i, d := range destinations {
+		s, ok := d.(string)
+		if !ok {
+			continue
+		}
+		transport, p, ok := strings.Cut(spec.ExpandReferences(s, args), ":")
+		if !ok {
+			continue
+		}
+		switch transport {
+		case "dir", "docker-archive", "oci", "oci-archive":
+			if !strings.HasPrefix(p, "~") && filepath.IsLocal(p) {
+				destinations[i] = transport + ":" + filepath.Join(dir, p)
+			}
+		}
+	}
+}
+
+// anchorPath resolves the path `p` with respect to the absolute path `dir` if
+// it's local once the build arguments in `args` are substituted into it,
+// returning it unchanged otherwise.
+func anchorPath(p, dir string, args map[string]string) string {
+	expanded := spec.ExpandReferences(p, args)
+	if strings.HasPrefix(expanded, "~") || !filepath.IsLocal(expanded) {
+		return p
+	}
+	return filepath.Join(dir, expanded)
+}
+
+// effectiveArgs returns the build arguments that apply to a spec chain: those
+// declared in the specs, with each spec overriding those it extends, and then
+// those in `overrides`.
+func effectiveArgs(chain []specFile, overrides map[string]string) map[string]string {
+	args := map[string]string{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		declared, _ := chain[i].tree["args"].(map[string]any)
+		for k, v := range declared {
+			if s, ok := v.(string); ok {
+				args[k] = s
+			}
+		}
+	}
+	for k, v := range overrides {
+		args[k] = v
+	}
+	return args
+}
+
+// newExtendsError reports a problem with the parent of the last spec in
+// `chain`, which must not be empty.
+func newExtendsError(chain []specFile, message string) error {
+	f := chain[len(chain)-1]
+	e := specError{
+		File:    f.path,
+		Field:   "extends",
+		Message: message,
+	}
+	if _, line, column, ok := spec.Locate([][]byte{f.blob}, e.Field); ok {
+		e.Line = line
+		e.Column = column
+	}
+	return specErrors{e}
+}
+
+// newDecodeErrors converts an error returned by the TOML decoder for the spec
+// file at `p` into a specErrors, recovering the position of each problem.
+func newDecodeErrors(p string, err error) error {
+	var strictErr *toml.StrictMissingError
+	if errors.As(err, &strictErr) {
+		errs := make(specErrors, len(strictErr.Errors))
+		for i, de := range strictErr.Errors {
+			line, column := de.Position()
+			errs[i] = specError{
+				File:    p,
+				Line:    line,
+				Column:  column,
+				Field:   strings.Join(de.Key(), "."),
+				Message: "unknown field",
+			}
+		}
+		return errs
+	}
+
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		line, column := decodeErr.Position()
+		return specErrors{{
+			File:    p,
+			Line:    line,
+			Column:  column,
+			Message: decodeErr.Error(),
+		}}
+	}
+
+	return specErrors{{File: p, Message: err.Error()}}
+}
+
+// locateValidationErrors converts a spec.ValidationErrors into a specErrors,
+// locating each offending field in the chain of spec files when possible.
+//
+// Fields nested in arrays are located only when the chain consists of a
+// single file because arrays from several files may have been concatenated.
+func locateValidationErrors(err error, chain []specFile) error {
+	var validationErrs spec.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return fmt.Errorf("%w", err)
+	}
+
+	docs := make([][]byte, len(chain))
+	for i, f := range chain {
+		docs[i] = f.blob
+	}
+
+	errs := make(specErrors, len(validationErrs))
+	for i, ve := range validationErrs {
+		errs[i] = specError{
+			File:    chain[0].path,
+			Field:   ve.Field,
+			Value:   ve.Value,
+			Message: ve.Message,
+		}
+		if len(chain) > 1 && strings.Contains(ve.Field, "[") {
+			continue
+		}
+		if doc, line, column, ok := spec.Locate(docs, ve.Field); ok {
+			errs[i].File = chain[doc].path
+			errs[i].Line = line
+			errs[i].Column = column
+		}
+	}
+	return errs
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateSpecAnchorsExpandedPaths(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "example.toml")
+	contents := `[from]
+repository = "docker.io/library/alpine"
+tag = "3.18.3"
+distro = "alpine"
+
+[this]
+repository = "localhost/example"
+tag = "latest"
+destinations = ["oci:${OUT}/example"]
+
+[user]
+name = "example"
+
+[args]
+ROOT = "files"
+OUT = "out"
+
+[[copy]]
+base = "${ROOT}"
+dest = "/etc/example/"
+srcs = ["example.conf"]
+owner = "example"
+
+[packages]
+install-files = ["${ROOT}/example.apk"]
+`
+	if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing spec: %v", err)
+	}
+
+	cases := []struct {
+		args        map[string]string
+		base        string
+		file        string
+		destination string
+	}{
+		{
+			nil,
+			filepath.Join(dir, "files"),
+			filepath.Join(dir, "files", "example.apk"),
+			"oci:" + filepath.Join(dir, "out", "example"),
+		},
+		{
+			map[string]string{"ROOT": "/srv/files", "OUT": "/srv/out"},
+			"/srv/files",
+			"/srv/files/example.apk",
+			"oci:/srv/out/example",
+		},
+	}
+
+	for _, c := range cases {
+		s, _, err := createSpec(p, false, c.args)
+		if err != nil {
+			t.Fatalf("creating spec with arguments %v: %v", c.args, err)
+		}
+		if base := s.Copy[0].Base; base != c.base {
+			t.Errorf("expected copy base %q, found %q", c.base, base)
+		}
+		if file := s.Packages.InstallFiles[0]; file != c.file {
+			t.Errorf("expected package file %q, found %q", c.file, file)
+		}
+		if dest := s.This.Destinations[0]; dest != c.destination {
+			t.Errorf("expected destination %q, found %q", c.destination, dest)
+		}
+	}
+}
+
+func TestCreateSpecHashesArgs(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "example.toml")
+	contents := `[from]
+repository = "docker.io/library/alpine"
+tag = "${TAG}"
+distro = "alpine"
+
+[this]
+repository = "localhost/example"
+tag = "latest"
+
+[args]
+TAG = "3.18.3"
+`
+	if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing spec: %v", err)
+	}
+
+	digest := func(args map[string]string) string {
+		_, d, err := createSpec(p, true, args)
+		if err != nil {
+			t.Fatalf("creating spec with arguments %v: %v", args, err)
+		}
+		return d
+	}
+
+	if digest(nil) != digest(map[string]string{"TAG": "3.18.3"}) {
+		t.Errorf("expected overriding an argument with its default not to change the digest")
+	}
+	if digest(nil) == digest(map[string]string{"TAG": "3.17.3"}) {
+		t.Errorf("expected a different argument to change the digest")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newValidateCmd() *cli.Command {
+	return &cli.Command{
+		Name:                   "validate",
+		Aliases:                []string{"check"},
+		Usage:                  "Check one or more Turret specs for problems without building",
+		ArgsUsage:              "SPEC...",
+		HideHelpCommand:        true,
+		UseShortOptionHandling: true,
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "arg",
+				Aliases: []string{"a"},
+				Usage:   "Set the build argument KEY to VALUE, overriding SPEC (repeatable)",
+			},
+			&cli.StringFlag{
+				Name:    "format",
+				Aliases: []string{"o"},
+				Usage:   "Report problems as 'text' or 'json'",
+				Value:   "text",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			if !cCtx.Args().Present() {
+				if err := cli.ShowCommandHelp(cCtx, cCtx.Command.Name); err != nil {
+					return fmt.Errorf("displaying help: %w", err)
+				}
+				return nil
+			}
+
+			format := cCtx.String("format")
+			if format != "text" && format != "json" {
+				return fmt.Errorf("unsupported report format %q", format)
+			}
+
+			args, err := parseArgs(cCtx.StringSlice("arg"))
+			if err != nil {
+				return fmt.Errorf("parsing build arguments: %w", err)
+			}
+
+			reports := make([]validationReport, 0, cCtx.Args().Len())
+			valid := true
+			for _, a := range cCtx.Args().Slice() {
+				specPath, err := filepath.Abs(a)
+				if err != nil {
+					return fmt.Errorf("canonicalizing spec path: %w", err)
+				}
+
+				report := validateSpec(specPath, args)
+				if !report.Valid {
+					valid = false
+				}
+				reports = append(reports, report)
+			}
+
+			switch format {
+			case "json":
+				e := json.NewEncoder(os.Stdout)
+				e.SetIndent("", "  ")
+				if err := e.Encode(reports); err != nil {
+					return fmt.Errorf("encoding validation report: %w", err)
+				}
+			default:
+				for _, r := range reports {
+					for _, e := range r.Errors {
+						fmt.Fprintln(os.Stderr, e)
+					}
+				}
+			}
+
+			if !valid {
+				return cli.Exit("", 1)
+			}
+			return nil
+		},
+	}
+}
+
+// validationReport holds the outcome of validating a single spec.
+type validationReport struct {
+	// Absolute path to the spec
+	Spec string `json:"spec"`
+
+	// Whether the spec is free of problems
+	Valid bool `json:"valid"`
+
+	// Problems found in the spec
+	Errors specErrors `json:"errors"`
+}
+
+// validateSpec validates the spec at the absolute path `p` with the build
+// arguments `args`.
+//
+// Problems that prevent the spec from being read at all, e.g., a missing spec
+// file or parent spec, are reported as problems with the spec as a whole.
+func validateSpec(p string, args map[string]string) validationReport {
+	report := validationReport{
+		Spec:   p,
+		Valid:  true,
+		Errors: specErrors{},
+	}
+	if _, _, err := createSpec(p, false, args); err != nil {
+		var errs specErrors
+		if !errors.As(err, &errs) {
+			errs = specErrors{{File: p, Message: err.Error()}}
+		}
+		report.Valid = false
+		report.Errors = errs
+	}
+	return report
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateSpecReportsUnreadableSpecs(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "missing.toml")
+
+	report := validateSpec(p, nil)
+	if report.Valid {
+		t.Errorf("expected missing spec to be invalid")
+	}
+	if len(report.Errors) != 1 {
+		t.Fatalf("expected 1 problem, found %d", len(report.Errors))
+	}
+	if e := report.Errors[0]; e.File != p || e.Field != "" || e.Message == "" {
+		t.Errorf("expected a problem with the whole spec, found %+v", e)
+	}
+}
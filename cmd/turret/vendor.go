@@ -0,0 +1,118 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ok-ryoko/turret/internal/build"
+
+	"github.com/containers/storage/pkg/unshare"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+func newVendorCmd(logger *logrus.Logger) *cli.Command {
+	return &cli.Command{
+		Name:                   "vendor",
+		Usage:                  "Download the packages a Turret spec installs for building without network access",
+		ArgsUsage:              "SPEC DIR",
+		HideHelpCommand:        true,
+		UseShortOptionHandling: true,
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "arg",
+				Aliases: []string{"a"},
+				Usage:   "Set the build argument KEY to VALUE, overriding SPEC (repeatable)",
+			},
+			&cli.BoolFlag{
+				Name:    "locked",
+				Aliases: []string{"L"},
+				Usage:   "Download the package versions in the lockfile",
+				Value:   false,
+			},
+			&cli.BoolFlag{
+				Name:    "pull",
+				Aliases: []string{"p"},
+				Usage:   "Pull the base image from remote storage if it doesn't exist locally",
+				Value:   false,
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "Print nothing (overriding alias for --verbosity 0)",
+				Value:   false,
+			},
+			&cli.UintFlag{
+				Name:    "verbosity",
+				Aliases: []string{"v"},
+				Usage:   "Set the output level, from nothing (0) to everything (4)",
+				Value:   1,
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.Args().Len() != 2 {
+				if err := cli.ShowCommandHelp(cCtx, cCtx.Command.Name); err != nil {
+					return fmt.Errorf("displaying help: %w", err)
+				}
+				return nil
+			}
+
+			unshare.MaybeReexecUsingUserNamespace(true)
+			ctx := context.Background()
+
+			verbosity := cCtx.Uint("verbosity")
+			if cCtx.Bool("quiet") {
+				verbosity = 0
+			}
+			setLoggerLevel(logger, verbosity)
+
+			specPath, err := filepath.Abs(cCtx.Args().Get(0))
+			if err != nil {
+				return fmt.Errorf("canonicalizing spec path: %w", err)
+			}
+
+			dir, err := filepath.Abs(cCtx.Args().Get(1))
+			if err != nil {
+				return fmt.Errorf("canonicalizing vendor directory path: %w", err)
+			}
+
+			args, err := parseArgs(cCtx.StringSlice("arg"))
+			if err != nil {
+				return fmt.Errorf("parsing build arguments: %w", err)
+			}
+
+			spec, _, err := createSpec(specPath, false, args)
+			if err != nil {
+				logSpecErrors(logger, err)
+				return fmt.Errorf("creating in-memory representation of spec: %w", err)
+			}
+			logger.Debugln("created in-memory representation of spec")
+
+			options := build.VendorOptions{
+				Dir:         dir,
+				LogCommands: verbosity >= 4,
+				Pull:        cCtx.Bool("pull"),
+			}
+
+			if cCtx.Bool("locked") {
+				lock, err := readLock(specPath)
+				if err != nil {
+					return fmt.Errorf("reading lockfile: %w", err)
+				}
+				options.Lock = &lock
+				logger.Debugln("read lockfile")
+			}
+
+			if err := build.Vendor(ctx, spec, logger, options); err != nil {
+				return fmt.Errorf("vendoring packages according to given spec: %w", err)
+			}
+			fmt.Println(dir)
+
+			return nil
+		},
+	}
+}
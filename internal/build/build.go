@@ -5,209 +5,195 @@ package build
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/ok-ryoko/turret/internal/container"
 	"github.com/ok-ryoko/turret/internal/spec"
-	"github.com/ok-ryoko/turret/pkg/linux"
 	"github.com/ok-ryoko/turret/pkg/linux/find"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
 	"github.com/ok-ryoko/turret/pkg/linux/user"
 
 	"github.com/containers/buildah"
+	"github.com/containers/buildah/define"
 	is "github.com/containers/image/v5/storage"
+	"github.com/containers/image/v5/types"
 	"github.com/containers/storage"
-	"github.com/containers/storage/pkg/archive"
 	"github.com/sirupsen/logrus"
 )
 
-const (
-	digestKey    string = "com.github.ok-ryoko.turret.spec.digest"
-	manifestType string = "application/vnd.oci.image.manifest.v1+json"
-)
+const digestKey string = "com.github.ok-ryoko.turret.spec.digest"
 
 // Execute runs the build pipeline.
-func Execute(ctx context.Context, s spec.Spec, logger *logrus.Logger, options ExecuteOptions) (string, error) {
-	storeOptions, err := storage.DefaultStoreOptionsAutoDetectUID()
-	if err != nil {
-		storeOptions = storage.StoreOptions{}
-	}
-	store, err := storage.GetStore(storeOptions)
+func Execute(ctx context.Context, s spec.Spec, logger *logrus.Logger, options ExecuteOptions) (Result, error) {
+	store, err := openStore()
 	if err != nil {
-		return "", fmt.Errorf("creating store: %w", err)
-	}
-	defer func() {
-		layers, errShutdown := store.Shutdown(false)
-		if errShutdown != nil {
-			logger.Warnln("failed releasing driver resources")
-			logger.Infoln(
-				"the following layers may still be mounted:",
-				strings.Join(layers, ", "),
-			)
-		}
-	}()
-
-	if refThis := s.This.Reference(); store.Exists(refThis) && !options.Force {
-		return "", fmt.Errorf("image %s already exists", refThis)
+		return Result{}, err
 	}
+	defer shutDownStore(store, logger)
 
-	buildahOptions := buildah.BuilderOptions{
-		Capabilities: []string{},
-		FromImage:    s.From.Reference(),
-		Isolation:    buildah.IsolationOCIRootless,
-		PullPolicy:   buildah.PullNever,
-	}
-	if options.LogCommands {
-		buildahOptions.Logger = logger
-	}
-	if options.Pull {
-		buildahOptions.PullPolicy = buildah.PullIfMissing
+	if refThis := s.This.Reference(); store.Exists(refThis) && !options.Force && !options.LockOnly {
+		return Result{}, fmt.Errorf("image %s already exists", refThis)
 	}
 
-	buildahBuilder, err := buildah.NewBuilder(ctx, store, buildahOptions)
+	order, err := spec.StageOrder(s)
 	if err != nil {
-		return "", fmt.Errorf("creating Buildah builder: %w", err)
+		return Result{}, fmt.Errorf("ordering stages: %w", err)
 	}
-	logger.Debugf("created working container from image %s", buildahOptions.FromImage)
 
-	ctr := container.Container{
-		Builder: buildahBuilder,
-		Logger:  logger,
-	}
-	defer func() {
-		if !options.Keep {
-			if removeErr := ctr.Remove(); removeErr != nil {
-				logger.Warnln("failed deleting working container")
-				logger.Infoln("please remove the container manually: buildah rm", ctr.ContainerID())
+	if options.Lock != nil {
+		for _, name := range order {
+			if _, ok := options.Lock.Stages[name]; !ok {
+				return Result{}, fmt.Errorf("no packages locked for stage %s", name)
 			}
 		}
-	}()
-	logger.Debugf("created %s Linux working container", s.From.Distro)
-
-	if ctr.Builder.OS() != "linux" {
-		return "", fmt.Errorf("expected 'linux' image, got '%s' image", ctr.Builder.OS())
 	}
 
-	ctr.CommonOptions.LogCommands = options.LogCommands
-	if s.From.Distro.Distro == linux.Debian {
-		ctr.CommonOptions.Env = append(ctr.CommonOptions.Env, "DEBIAN_FRONTEND=noninteractive")
+	if options.Vendored != "" {
+		if err := checkVendoring(s); err != nil {
+			return Result{}, err
+		}
 	}
 
-	pckgFrontend, err := container.NewPackageFrontend(s.Backends.Package.Backend)
-	if err != nil {
-		return "", fmt.Errorf("creating package management interface: %w", err)
-	}
+	s = applyLock(s, options.Lock)
+	result := Result{}
 
-	userFrontend, err := container.NewUserFrontend(s.Backends.User.Backend)
-	if err != nil {
-		return "", fmt.Errorf("creating user management interface: %w", err)
+	stages := make(map[string]*container.Container, len(order))
+	for _, name := range order {
+		st := s.Stages[name].Spec()
+
+		vendored, err := readVendored(options.Vendored, name)
+		if err != nil {
+			return Result{}, fmt.Errorf("stage %s: reading vendored packages: %w", name, err)
+		}
+
+		stageCtr, err := newContainer(ctx, store, st, logger, options)
+		if err != nil {
+			return Result{}, fmt.Errorf("stage %s: %w", name, err)
+		}
+		defer removeContainer(stageCtr, logger, options.Keep)
+
+		cleanup, err := mountLocalRepository(stageCtr, st)
+		if err != nil {
+			return Result{}, fmt.Errorf("stage %s: mounting local package repository: %w", name, err)
+		}
+		defer cleanup()
+
+		// Stages are squashed into their working containers, so their steps are
+		// committed only to be cached
+		//
+		stageLayers := &layerCommitter{
+			ctx:     ctx,
+			store:   store,
+			logger:  logger,
+			options: options,
+			cache:   true,
+		}
+
+		logger.Debugf("building stage %s...", name)
+		if err := runSteps(stageCtr, st, stages, vendored, stageLayers, logger); err != nil {
+			return Result{}, fmt.Errorf("stage %s: %w", name, err)
+		}
+
+		packages, err := lockPackages(stageCtr, st, options.Lock, name)
+		if err != nil {
+			return Result{}, fmt.Errorf("stage %s: %w", name, err)
+		}
+		if result.Lock.Stages == nil {
+			result.Lock.Stages = make(map[string]LockedStage, len(order))
+		}
+		result.Lock.Stages[name] = LockedStage{Packages: packages}
+		logger.Debugf("finished building stage %s", name)
+
+		stages[name] = stageCtr
 	}
 
-	findCmdFactory, err := find.NewCommandFactory(s.Backends.Find.Backend)
+	vendored, err := readVendored(options.Vendored, "")
 	if err != nil {
-		return "", fmt.Errorf("creating find command factory: %w", err)
+		return Result{}, fmt.Errorf("reading vendored packages: %w", err)
 	}
 
-	if s.Packages.Upgrade {
-		logger.Debugln("upgrading packages in the working container...")
-		if err := upgradePackages(&ctr, pckgFrontend); err != nil {
-			return "", fmt.Errorf("upgrading packages: %w", err)
-		}
-		logger.Debugln("upgrade command ran successfully")
+	// The intermediate images must outlive the working container created
+	// from the last of them
+	//
+	layers := &layerCommitter{
+		ctx:         ctx,
+		store:       store,
+		logger:      logger,
+		options:     options,
+		keepHistory: s.This.KeepHistory,
+		perStep:     s.This.Layers.Layering == spec.PerStep,
+		cache:       true,
+	}
+	if !options.Keep {
+		defer layers.remove()
 	}
 
-	if len(s.Packages.Install) > 0 {
-		logger.Debugln("installing packages to the working container...")
-		if err := installPackages(&ctr, pckgFrontend, s.Packages.Install); err != nil {
-			return "", fmt.Errorf("installing packages: %w", err)
-		}
-		logger.Debugln("install command ran successfully")
+	ctr, err := newContainer(ctx, store, s, logger, options)
+	if err != nil {
+		return Result{}, err
 	}
+	defer removeContainer(ctr, logger, options.Keep)
 
-	if s.Packages.Clean {
-		if err := cleanPackageCaches(&ctr, pckgFrontend); err != nil {
-			return "", fmt.Errorf("cleaning package caches: %w", err)
-		}
-		logger.Debugln("clean command ran successfully")
+	cleanup, err := mountLocalRepository(ctr, s)
+	if err != nil {
+		return Result{}, fmt.Errorf("mounting local package repository: %w", err)
 	}
+	defer cleanup()
 
-	if s.User != nil {
-		createUserOptions := user.Options{
-			ID:         s.User.ID,
-			UserGroup:  s.User.UserGroup,
-			Groups:     s.User.Groups,
-			Comment:    s.User.Comment,
-			CreateHome: s.User.CreateHome,
-		}
-		if err := createUser(&ctr, userFrontend, s.User.Name, createUserOptions); err != nil {
-			return "", fmt.Errorf("creating nonroot user: %w", err)
-		}
-		logger.Debugf("created nonroot user")
-	}
-
-	if len(s.Copy) > 0 {
-		for _, cp := range s.Copy {
-			copyFilesOptions := copyFilesOptions{
-				excludes:      cp.Excludes,
-				mode:          cp.Mode,
-				owner:         cp.Owner,
-				removeSpecial: cp.RemoveS,
-			}
-			if err := copyFiles(&ctr, cp.Base, cp.Destination, cp.Sources, copyFilesOptions); err != nil {
-				return "", fmt.Errorf("copying files: %w", err)
-			}
-		}
-		logger.Debugln("file copy command(s) ran successfully")
+	preinstalled, err := listPackages(ctr, s)
+	if err != nil {
+		return Result{}, fmt.Errorf("listing pre-installed packages: %w", err)
 	}
 
-	if s.Security.SpecialFiles.RemoveS {
-		if err := unsetSpecialBits(&ctr, findCmdFactory, s.Security.SpecialFiles.Excludes); err != nil {
-			return "", fmt.Errorf("removing SUID and SGID bits from files: %w", err)
-		}
-		logger.Debugln("command to remove SUID and SGID bits from files ran successfully")
+	if err := runSteps(ctr, s, stages, vendored, layers, logger); err != nil {
+		return Result{}, err
 	}
 
-	if options.Digest != "" {
-		s.Config.Annotations[digestKey] = options.Digest
+	result.Lock.Packages, err = lockPackages(ctr, s, options.Lock, "")
+	if err != nil {
+		return Result{}, err
 	}
 
-	ports := make([]string, len(s.Config.Ports))
-	for i, p := range s.Config.Ports {
-		ports[i] = p.String()
+	result.PackageReport = diffPackages(preinstalled, result.Lock.Packages)
+	logPackageReport(logger, result.PackageReport)
+
+	if options.LockOnly {
+		return result, nil
 	}
 
-	configureOptions := configureOptions{
-		clearAnnotations: s.Config.Clear.Annotations,
-		annotations:      s.Config.Annotations,
-		clearAuthor:      s.Config.Clear.Author,
-		author:           s.Config.Author,
-		clearCommand:     s.Config.Clear.Command,
-		command:          s.Config.Command,
-		createdBy:        s.Config.CreatedBy,
-		clearEntrypoint:  s.Config.Clear.Entrypoint,
-		entrypoint:       s.Config.Entrypoint,
-		clearEnvironment: s.Config.Clear.Environment,
-		environment:      s.Config.Environment,
-		clearLabels:      s.Config.Clear.Labels,
-		labels:           s.Config.Labels,
-		clearPorts:       s.Config.Clear.Ports,
-		ports:            ports,
-		workDir:          s.Config.WorkDir,
+	configureOptions := newConfigureOptions(s, options)
+	if options.AnnotatePackageReport {
+		blob, err := json.Marshal(result.PackageReport)
+		if err != nil {
+			return Result{}, fmt.Errorf("encoding package report: %w", err)
+		}
+		configureOptions.annotations[packageReportKey] = string(blob)
 	}
-	if s.User != nil {
-		configureOptions.user = s.User.Name
+	if options.SBOM {
+		result.SBOM, err = newSBOM(s, result.Lock.Packages)
+		if err != nil {
+			return Result{}, fmt.Errorf("generating SBOM: %w", err)
+		}
+		configureOptions.annotations[sbomDigestKey] = sbomDigest(result.SBOM)
 	}
-	configure(&ctr, configureOptions)
+	configure(ctr, configureOptions)
 	logger.Debugln("configured image")
 
+	// When the last step's result was cached, the working container holds no
+	// changes for a layer of its own
+	//
+	commitOptions := newCommitOptions(s, options)
+	commitOptions.emptyLayer = layers.perStep && layers.clean
+
 	logger.Debugln("committing image...")
-	commitOptions := commitOptions{
-		keepHistory: s.This.KeepHistory,
-		latest:      options.Latest,
-	}
 	imageID, err := commit(
-		&ctr,
+		ctr,
 		ctx,
 		store,
 		s.This.Repository,
@@ -215,14 +201,50 @@ func Execute(ctx context.Context, s spec.Spec, logger *logrus.Logger, options Ex
 		commitOptions,
 	)
 	if err != nil {
-		return "", fmt.Errorf("committing image: %w", err)
+		return Result{}, fmt.Errorf("committing image: %w", err)
 	}
+	result.ImageID = imageID
 
-	return imageID, nil
+	if dests := destinations(s, options); len(dests) > 0 {
+		logger.Debugln("copying image to destinations...")
+		if err := push(ctx, store, imageID, dests, logger, newPushOptions(s, options)); err != nil {
+			return Result{}, fmt.Errorf("copying image to destinations: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// Result holds the outcome of the build pipeline.
+type Result struct {
+	// ID of the committed image; empty if no image was committed
+	ImageID string
+
+	// Packages installed in the working containers
+	Lock Lock
+
+	// Packages that the build added to, removed from or changed in the working
+	// container of the final image
+	PackageReport PackageReport
+
+	// CycloneDX SBOM of the image in JSON; nil unless ExecuteOptions.SBOM is
+	// set
+	SBOM []byte
 }
 
 // ExecuteOptions holds options for the build pipeline.
 type ExecuteOptions struct {
+	// Annotate the image with the package report in JSON
+	AnnotatePackageReport bool
+
+	// Path to the file holding the credentials for container registries; when
+	// empty, the default location is used
+	AuthFile string
+
+	// Locations to which to copy the image after committing it, in addition
+	// to the destinations in the spec, each given as TRANSPORT:REFERENCE
+	Destinations []string
+
 	// SHA256 digest of the spec file to apply as an annotation to the new image
 	Digest string
 
@@ -238,8 +260,296 @@ type ExecuteOptions struct {
 	// Log the standard output of container processes
 	LogCommands bool
 
+	// Install the versions of packages recorded in this lock and fail if the
+	// installed packages differ from it; when nil, packages are resolved freely
+	Lock *Lock
+
+	// Stop after listing the installed packages, neither configuring nor
+	// committing the image
+	LockOnly bool
+
+	// Run every step instead of reusing cached results; the results are still
+	// cached, replacing those from earlier builds
+	NoCache bool
+
 	// Retrieve the image only if it's not already in local storage
 	Pull bool
+
+	// Number of times to retry copying the image to a destination after a
+	// transient failure
+	Retries uint
+
+	// Time to wait between attempts to copy the image to a destination; when
+	// 0, the wait grows exponentially
+	RetryDelay time.Duration
+
+	// Generate a CycloneDX SBOM of the image and annotate the image with its
+	// SHA256 digest
+	SBOM bool
+
+	// Skip verifying the TLS certificates of container registries when copying
+	// the image to its destinations
+	SkipTLSVerify bool
+
+	// Path to a directory populated by Vendor from which to install packages
+	// with every process disconnected from the network; when empty, packages
+	// are installed from repositories
+	Vendored string
+}
+
+// openStore opens the default container storage for the invoking user.
+func openStore() (storage.Store, error) {
+	storeOptions, err := storage.DefaultStoreOptionsAutoDetectUID()
+	if err != nil {
+		storeOptions = storage.StoreOptions{}
+	}
+	store, err := storage.GetStore(storeOptions)
+	if err != nil {
+		return nil, fmt.Errorf("creating store: %w", err)
+	}
+	return store, nil
+}
+
+// shutDownStore releases the resources of the container storage, logging
+// rather than returning any error.
+func shutDownStore(store storage.Store, logger *logrus.Logger) {
+	layers, err := store.Shutdown(false)
+	if err != nil {
+		logger.Warnln("failed releasing driver resources")
+		logger.Infoln(
+			"the following layers may still be mounted:",
+			strings.Join(layers, ", "),
+		)
+	}
+}
+
+// newContainer creates a working container from the base image of a spec.
+func newContainer(
+	ctx context.Context,
+	store storage.Store,
+	s spec.Spec,
+	logger *logrus.Logger,
+	options ExecuteOptions,
+) (*container.Container, error) {
+	buildahBuilder, err := newBuilder(ctx, store, s.From.Reference(), logger, options)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := &container.Container{
+		Builder:       buildahBuilder,
+		Logger:        logger,
+		CommonOptions: commonOptions(s, options),
+	}
+	logger.Debugf("created %s Linux working container", s.From.Distro)
+
+	if ctr.Builder.OS() != "linux" {
+		removeContainer(ctr, logger, options.Keep)
+		return nil, fmt.Errorf("expected 'linux' image, got '%s' image", ctr.Builder.OS())
+	}
+
+	return ctr, nil
+}
+
+// newBuilder creates a Buildah builder for a working container created from
+// the image `image`.
+func newBuilder(
+	ctx context.Context,
+	store storage.Store,
+	image string,
+	logger *logrus.Logger,
+	options ExecuteOptions,
+) (*buildah.Builder, error) {
+	buildahOptions := buildah.BuilderOptions{
+		Capabilities: []string{},
+		FromImage:    image,
+		Isolation:    buildah.IsolationOCIRootless,
+		PullPolicy:   buildah.PullNever,
+	}
+	if options.LogCommands {
+		buildahOptions.Logger = logger
+	}
+	if options.Pull {
+		buildahOptions.PullPolicy = buildah.PullIfMissing
+	}
+
+	buildahBuilder, err := buildah.NewBuilder(ctx, store, buildahOptions)
+	if err != nil {
+		return nil, fmt.Errorf("creating Buildah builder: %w", err)
+	}
+	logger.Debugf("created working container from image %s", image)
+
+	return buildahBuilder, nil
+}
+
+// removeContainer removes a working container unless `keep` is true, logging
+// rather than returning any error.
+func removeContainer(c *container.Container, logger *logrus.Logger, keep bool) {
+	if keep {
+		return
+	}
+	id := c.ContainerID()
+	if err := c.Remove(); err != nil {
+		logger.Warnln("failed deleting working container")
+		logger.Infoln("please remove the container manually: buildah rm", id)
+	}
+}
+
+// runSteps alters a working container according to a spec, where `stages`
+// holds the working containers of the stages from which files may be copied
+// and `vendored`, if not nil, holds the package files to install in place of
+// the packages in the spec.
+//
+// If `layers` isn't nil and caches results, then the result of each step is
+// looked up in and recorded to the build cache until the first step that isn't
+// cacheable. If `layers` commits per step, then the changes made by each other
+// step but the last are committed as a layer of their own. The changes made by
+// the last step, if not cached, are left for the final commit.
+func runSteps(
+	c *container.Container,
+	s spec.Spec,
+	stages map[string]*container.Container,
+	vendored *vendoredPackages,
+	layers *layerCommitter,
+	logger *logrus.Logger,
+) error {
+	steps, err := newSteps(s, stages, vendored)
+	if err != nil {
+		return err
+	}
+
+	// The chain of cache keys starts from the base image and is broken by the
+	// first step that isn't cacheable. Packages in a local repository can
+	// change without changing the inputs of any step, so nothing is cached
+	//
+	var (
+		key      string
+		recorder *container.Container
+	)
+	if layers != nil && layers.cache && s.Packages.Local == nil {
+		key = initialCacheKey(c.Builder.FromImageID, layers.keepHistory)
+		recorder = &container.Container{
+			Logger:        logger,
+			CommonOptions: c.CommonOptions,
+			Recorder:      &container.Recorder{},
+		}
+	}
+
+	for i, st := range steps {
+		if key != "" {
+			if cacheable(st) {
+				key, err = stepCacheKey(key, st, recorder)
+				if err != nil {
+					return fmt.Errorf("%s: computing cache key: %w", st.description, err)
+				}
+			} else {
+				key = ""
+			}
+		}
+
+		if key != "" && !layers.options.NoCache {
+			reused, err := layers.reuse(c, cacheImageName(key))
+			if err != nil {
+				return fmt.Errorf("%s: %w", st.description, err)
+			}
+			if reused {
+				logger.Infof("using cached result of %s", st.description)
+				continue
+			}
+		}
+
+		logger.Debugf("%s...", st.description)
+		if err := st.run(c); err != nil {
+			return fmt.Errorf("%s: %w", st.description, err)
+		}
+		logger.Debugf("finished %s", st.description)
+
+		if layers == nil {
+			continue
+		}
+		layers.clean = false
+
+		switch {
+		case key != "":
+			err = layers.commit(c, historyEntry(st), cacheImageName(key))
+		case layers.perStep && i < len(steps)-1:
+			err = layers.commit(c, historyEntry(st), "")
+		case layers.perStep:
+			c.Builder.SetCreatedBy(historyEntry(st))
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", st.description, err)
+		}
+	}
+
+	return nil
+}
+
+// lockPackages lists the packages installed in the working container and, if
+// `lock` isn't nil, checks them against the packages that `lock` records for
+// the stage `stage` (or for the final image if `stage` is empty).
+func lockPackages(c *container.Container, s spec.Spec, lock *Lock, stage string) ([]pckg.Package, error) {
+	packages, err := listPackages(c, s)
+	if err != nil {
+		return nil, fmt.Errorf("listing installed packages: %w", err)
+	}
+
+	if lock != nil {
+		locked := lock.Packages
+		if stage != "" {
+			locked = lock.Stages[stage].Packages
+		}
+		if err := verifyPackages(packages, locked); err != nil {
+			return nil, err
+		}
+	}
+
+	return packages, nil
+}
+
+// addRepositories configures package repositories in the working container,
+// copying their keys from the host, and then updates the package index if
+// `updateIndex` is true.
+func addRepositories(
+	c *container.Container,
+	p container.PackageFrontendInterface,
+	f pckg.CommandFactory,
+	repositories []spec.Repository,
+	updateIndex bool,
+) error {
+	for _, r := range repositories {
+		repository := pckg.Repository{
+			Name:       r.Name,
+			URL:        r.URL,
+			Suite:      r.Suite,
+			Components: r.Components,
+			Priority:   r.Priority,
+			Enabled:    r.Enabled == nil || *r.Enabled,
+		}
+
+		if r.Key != "" {
+			blob, err := os.ReadFile(r.Key)
+			if err != nil {
+				return fmt.Errorf("reading key of repository %s: %w", r.Name, err)
+			}
+			repository.Key = f.KeyPath(r.Name, r.Key)
+			if err := c.WriteFile(repository.Key, string(blob), false); err != nil {
+				return fmt.Errorf("copying key of repository %s: %w", r.Name, err)
+			}
+		}
+
+		if err := p.AddRepository(c, repository); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	if updateIndex {
+		if err := p.UpdateIndex(c); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	return nil
 }
 
 // cleanPackageCaches cleans the package caches in the working container.
@@ -262,11 +572,16 @@ func commit(
 	options commitOptions,
 ) (string, error) {
 	co := buildah.CommitOptions{
-		PreferredManifestType: manifestType,
-		Compression:           archive.Gzip,
+		PreferredManifestType: manifestType(options.format),
+		Compression:           layerCompression(options.compression),
+		EmptyLayer:            options.emptyLayer,
 		HistoryTimestamp:      &time.Time{},
 		OmitHistory:           false,
-		Squash:                true,
+		Squash:                options.layers != spec.PerStep,
+		SystemContext: &types.SystemContext{
+			CompressionFormat: compressionAlgorithm(options.compression),
+			CompressionLevel:  options.compressionLevel,
+		},
 	}
 
 	if options.latest && tag != "latest" {
@@ -296,12 +611,69 @@ func commit(
 // commitOptions holds options for committing an image from the working
 // container to storage.
 type commitOptions struct {
+	// Algorithm with which to compress the layers of the image
+	compression spec.Compression
+
+	// Level at which to compress the layers of the image; when nil, the
+	// algorithm's default level is used
+	compressionLevel *int
+
+	// Add no layer for the working container's changes, e.g., because the
+	// changes are already in the layers of the image it was created from
+	emptyLayer bool
+
+	// Format of the image manifest and configuration
+	format spec.ImageFormat
+
 	// Preserve the image history and timestamps of the files in the working
 	// container's file system
 	keepHistory bool
 
 	// Ensure that the `latest` tag is created
 	latest bool
+
+	// Commit the changes made to the working container as a single layer or
+	// as one layer per step
+	layers spec.Layering
+}
+
+// newCommitOptions derives the options for committing an image from a spec
+// and the options for the build pipeline.
+func newCommitOptions(s spec.Spec, options ExecuteOptions) commitOptions {
+	return commitOptions{
+		compression:      s.This.Compression.Compression,
+		compressionLevel: s.This.CompressionLevel,
+		format:           s.This.Format.ImageFormat,
+		keepHistory:      s.This.KeepHistory,
+		latest:           options.Latest,
+		layers:           s.This.Layers.Layering,
+	}
+}
+
+// describe returns human-readable descriptions of the operations performed
+// when committing an image with these options.
+func (o commitOptions) describe(repository, tag string) []string {
+	d := []string{
+		fmt.Sprintf("commit %s image %s:%s to containers-storage", o.describeLayers(), repository, tag),
+		fmt.Sprintf("write %s image manifest and configuration", o.format),
+		describeCompression(o.compression, o.compressionLevel),
+	}
+	if o.latest && tag != "latest" {
+		d = append(d, fmt.Sprintf("tag image as %s:latest", repository))
+	}
+	if o.keepHistory {
+		d = append(d, "preserve image history and file timestamps")
+	}
+	return d
+}
+
+// describeLayers returns a human-readable description of the layers of the
+// committed image.
+func (o commitOptions) describeLayers() string {
+	if o.layers == spec.PerStep {
+		return "layered"
+	}
+	return "squashed"
 }
 
 // configure alters the metadata on and execution of the working container.
@@ -428,6 +800,113 @@ type configureOptions struct {
 	workDir string
 }
 
+// newConfigureOptions derives the configuration options for the working
+// container from a spec and the options for the build pipeline.
+func newConfigureOptions(s spec.Spec, options ExecuteOptions) configureOptions {
+	if options.Digest != "" {
+		s.Config.Annotations[digestKey] = options.Digest
+	}
+
+	ports := make([]string, len(s.Config.Ports))
+	for i, p := range s.Config.Ports {
+		ports[i] = p.String()
+	}
+
+	o := configureOptions{
+		clearAnnotations: s.Config.Clear.Annotations,
+		annotations:      s.Config.Annotations,
+		clearAuthor:      s.Config.Clear.Author,
+		author:           s.Config.Author,
+		clearCommand:     s.Config.Clear.Command,
+		command:          s.Config.Command,
+		createdBy:        s.Config.CreatedBy,
+		clearEntrypoint:  s.Config.Clear.Entrypoint,
+		entrypoint:       s.Config.Entrypoint,
+		clearEnvironment: s.Config.Clear.Environment,
+		environment:      s.Config.Environment,
+		clearLabels:      s.Config.Clear.Labels,
+		labels:           s.Config.Labels,
+		clearPorts:       s.Config.Clear.Ports,
+		ports:            ports,
+		workDir:          s.Config.WorkDir,
+	}
+	if s.User != nil {
+		o.user = s.User.Name
+	}
+	return o
+}
+
+// describe returns human-readable descriptions of the changes made by
+// configure with these options.
+func (o configureOptions) describe() []string {
+	var d []string
+
+	if o.clearAnnotations {
+		d = append(d, "clear annotations")
+	}
+	for _, a := range describeMap(o.annotations) {
+		d = append(d, fmt.Sprintf("set annotation %s", a))
+	}
+
+	if o.clearAuthor {
+		d = append(d, "clear author")
+	}
+	if o.author != "" {
+		d = append(d, fmt.Sprintf("set author to %q", o.author))
+	}
+
+	if o.clearCommand {
+		d = append(d, "clear command")
+	}
+	if len(o.command) > 0 {
+		d = append(d, fmt.Sprintf("set command to %q", o.command))
+	}
+
+	if o.createdBy != "" {
+		d = append(d, fmt.Sprintf("set created-by to %q", o.createdBy))
+	}
+
+	if o.clearEntrypoint {
+		d = append(d, "clear entrypoint")
+	}
+	if len(o.entrypoint) > 0 {
+		d = append(d, fmt.Sprintf("set entrypoint to %q", o.entrypoint))
+	}
+
+	if o.clearEnvironment {
+		d = append(d, "unset all environment variables")
+	}
+	for _, e := range describeMap(o.environment) {
+		d = append(d, fmt.Sprintf("set environment variable %s", e))
+	}
+
+	if o.clearLabels {
+		d = append(d, "clear labels")
+	}
+	for _, l := range describeMap(o.labels) {
+		d = append(d, fmt.Sprintf("set label %s", l))
+	}
+
+	d = append(d, "set OS to linux")
+
+	if o.clearPorts {
+		d = append(d, "close all ports")
+	}
+	for _, p := range o.ports {
+		d = append(d, fmt.Sprintf("expose port %s", p))
+	}
+
+	if o.workDir != "" {
+		d = append(d, fmt.Sprintf("set working directory to %s", o.workDir))
+	}
+
+	if o.user != "" {
+		d = append(d, fmt.Sprintf("set user to %s", o.user))
+	}
+
+	return d
+}
+
 // copyFiles copies one or more files on the host's file system to the working
 // container's file system, assuming `base` and `dest` are absolute file paths
 // and `srcs` is a nonempty slice of file paths.
@@ -456,8 +935,9 @@ func copyFiles(c *container.Container, base string, dest string, srcs []string,
 	}
 
 	aco := buildah.AddAndCopyOptions{
-		ContextDir: base,
-		Excludes:   excludes,
+		ContextDir:       base,
+		Excludes:         excludes,
+		IDMappingOptions: options.idMappingOptions,
 	}
 
 	if options.owner != "" {
@@ -480,6 +960,32 @@ func copyFiles(c *container.Container, base string, dest string, srcs []string,
 	return nil
 }
 
+// copyFilesFromContainer copies one or more files from the file system of the
+// working container `from` to that of the working container `c`, where `base`
+// is an absolute path to a directory in `from` and `dest` and `srcs` are as
+// described for copyFiles.
+func copyFilesFromContainer(
+	c *container.Container,
+	from *container.Container,
+	base string,
+	dest string,
+	srcs []string,
+	options copyFilesOptions,
+) error {
+	mountPoint, err := from.Builder.Mount(from.Builder.MountLabel)
+	if err != nil {
+		return fmt.Errorf("mounting container %s: %w", from.ContainerID(), err)
+	}
+	defer func() {
+		if err := from.Builder.Unmount(); err != nil {
+			c.Logger.Warnf("failed unmounting container %s", from.ContainerID())
+		}
+	}()
+
+	options.idMappingOptions = &from.Builder.IDMappingOptions
+	return copyFiles(c, filepath.Join(mountPoint, base), dest, srcs, options)
+}
+
 // copyFilesOptions holds options for copying files from the host's file system
 // to the working container's file system.
 type copyFilesOptions struct {
@@ -496,6 +1002,10 @@ type copyFilesOptions struct {
 	// Remove all SUID and SGID bits from the files copied to the working
 	// container
 	removeSpecial bool
+
+	// ID mappings of the container from which the files are copied; nil if
+	// the files are copied from the host
+	idMappingOptions *define.IDMappingOptions
 }
 
 // createUser creates the sole unprivileged user of the working container,
@@ -507,14 +1017,128 @@ func createUser(c *container.Container, u container.UserFrontendInterface, name
 	return nil
 }
 
-// installPackages installs one or more packages to the working container.
-func installPackages(c *container.Container, p container.PackageFrontendInterface, packages []string) error {
-	if err := p.Install(c, packages); err != nil {
+// installPackages installs zero or more packages and then zero or more package
+// groups to the working container.
+func installPackages(
+	c *container.Container,
+	p container.PackageFrontendInterface,
+	packages []string,
+	groups []string,
+	options pckg.InstallOptions,
+) error {
+	if len(packages) > 0 {
+		if err := p.Install(c, packages, options); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+	if len(groups) > 0 {
+		if err := p.InstallGroups(c, groups, options); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+	return nil
+}
+
+// installPackageFiles copies one or more package files from the host's file
+// system to a temporary directory in the working container, installs the
+// packages in them, reading no repository if `offline` is true, and removes the
+// directory.
+func installPackageFiles(
+	c *container.Container,
+	p container.PackageFrontendInterface,
+	files []string,
+	offline bool,
+	options pckg.InstallOptions,
+) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	if err := c.AddFiles(packageFilesDir, files); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = path.Join(packageFilesDir, filepath.Base(f))
+	}
+
+	errInstall := p.InstallFiles(c, paths, offline, options)
+
+	if err := c.RemoveAll(packageFilesDir); err != nil {
+		if errInstall != nil {
+			c.Logger.Warnf("failed removing %s", packageFilesDir)
+		} else {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	if errInstall != nil {
+		return fmt.Errorf("%w", errInstall)
+	}
+	return nil
+}
+
+// removePackages removes one or more packages from the working container.
+func removePackages(c *container.Container, p container.PackageFrontendInterface, packages []string) error {
+	if err := p.Remove(c, packages); err != nil {
 		return fmt.Errorf("%w", err)
 	}
 	return nil
 }
 
+// runCommand runs a command in the working container, assuming `cmd` is a
+// nonempty slice. The process is granted only the capabilities in `options`.
+func runCommand(c *container.Container, cmd []string, options runCommandOptions) error {
+	ro := c.DefaultRunOptions()
+	ro.AddCapabilities = options.capabilities
+	ro.Env = append(ro.Env, options.env...)
+	if options.network {
+		ro.ConfigureNetwork = buildah.NetworkEnabled
+	}
+	ro.User = options.user
+	ro.WorkingDir = options.workDir
+
+	outText, errText, err := c.Run(cmd, ro)
+	if err != nil {
+		errContext := fmt.Sprintf("running %q", cmd)
+		if errText != "" {
+			errContext = fmt.Sprintf("%s (%q)", errContext, errText)
+		}
+		return fmt.Errorf("%s: %w", errContext, err)
+	}
+	if c.CommonOptions.LogCommands {
+		if outText != "" {
+			c.Logger.Debug(outText)
+		}
+		if errText != "" {
+			c.Logger.Debug(errText)
+		}
+	}
+
+	return nil
+}
+
+// runCommandOptions holds options for running a command in the working
+// container.
+type runCommandOptions struct {
+	// Linux capabilities to grant to the process
+	capabilities []string
+
+	// Environment variables to set for the process, represented as a slice of
+	// "KEY=VALUE"s
+	env []string
+
+	// Connect the process to the network
+	network bool
+
+	// User (and, optionally, group) as whom to run the process
+	user string
+
+	// Directory in which to run the process
+	workDir string
+}
+
 // unsetSpecialBits removes the SUID and SGID bits from files in the working
 // container, assuming the availability of the chmod and find core utilities
 // and searching only real (non-device) file systems.
@@ -582,9 +1206,16 @@ func unsetSpecialBits(c *container.Container, f find.CommandFactory, excludes []
 	return nil
 }
 
-// upgradePackages upgrades the packages in the working container.
-func upgradePackages(c *container.Container, p container.PackageFrontendInterface) error {
-	if err := p.Upgrade(c); err != nil {
+// upgradePackages upgrades the packages in the working container, only those
+// with security fixes if `mode` is spec.UpgradeSecurity.
+func upgradePackages(c *container.Container, p container.PackageFrontendInterface, mode spec.UpgradeMode) error {
+	var err error
+	if mode == spec.UpgradeSecurity {
+		err = p.UpgradeSecurity(c)
+	} else {
+		err = p.Upgrade(c)
+	}
+	if err != nil {
 		return fmt.Errorf("%w", err)
 	}
 	return nil
@@ -0,0 +1,113 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ok-ryoko/turret/internal/container"
+	"github.com/ok-ryoko/turret/internal/spec"
+)
+
+// cacheRepository is the repository of the intermediate images that hold the
+// cached results of steps, each tagged with the key of its step.
+const cacheRepository string = "localhost/turret-cache"
+
+// cacheable reports whether the result of a step is determined by its inputs,
+// such that it can be cached.
+//
+// Steps that copy files from a stage aren't cacheable because the stage's
+// working container is built anew each time.
+func cacheable(st step) bool {
+	return st.recordable || st.hostInputs != nil
+}
+
+// initialCacheKey derives the key from which the chain of cache keys of a
+// working container's steps starts from the ID of the container's base image
+// and whether the timestamps of files in the container are preserved.
+func initialCacheKey(baseImageID string, keepHistory bool) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\nkeep history: %t", baseImageID, keepHistory)))
+	return hex.EncodeToString(sum[:])
+}
+
+// stepCacheKey derives the key of the cached result of a step from the key of
+// the previous step (or the initial key for the first step) and the
+// inputs of the step, i.e., the operations it performs, the processes it runs
+// and the contents of the files it reads from the host's file system.
+//
+// `recorder` is a container with a recorder whose common options match those
+// of the working container. The step must be cacheable.
+func stepCacheKey(prev string, st step, recorder *container.Container) (string, error) {
+	inputs := append([]string{prev, st.description}, st.details...)
+
+	if st.recordable {
+		if err := st.run(recorder); err != nil {
+			return "", fmt.Errorf("recording processes: %w", err)
+		}
+		for _, p := range recorder.Recorder.Flush() {
+			inputs = append(inputs, fmt.Sprintf("%+v", p))
+		}
+	}
+
+	if st.hostInputs != nil {
+		hostInputs, err := st.hostInputs()
+		if err != nil {
+			return "", fmt.Errorf("reading inputs: %w", err)
+		}
+		inputs = append(inputs, hostInputs...)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(inputs, "\n")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cacheImageName returns the name of the intermediate image holding the cached
+// result of the step with key `key`.
+func cacheImageName(key string) string {
+	return fmt.Sprintf("%s:%s", cacheRepository, key)
+}
+
+// digestInputs describes the contents of the files at the paths in `paths` by
+// their base names and SHA256 digests.
+func digestInputs(paths []string) ([]string, error) {
+	inputs := make([]string, 0, len(paths))
+	for _, p := range paths {
+		digest, err := digestFile(p)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, fmt.Sprintf("%s  %s", digest, filepath.Base(p)))
+	}
+	return inputs, nil
+}
+
+// copyInputs describes the files that a copy entry copies from the host's file
+// system by their paths relative to the copy's base directory, permission bits
+// and SHA256 digests.
+func copyInputs(cp spec.Copy) ([]string, error) {
+	var inputs []string
+	err := walkCopySources(cp, func(p, rel string) error {
+		info, err := os.Stat(p)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		digest, err := digestFile(p)
+		if err != nil {
+			return err
+		}
+
+		inputs = append(inputs, fmt.Sprintf("%s  %s  %s", digest, info.Mode().Perm(), rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
@@ -0,0 +1,94 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ok-ryoko/turret/internal/container"
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestStepCacheKey(t *testing.T) {
+	base := t.TempDir()
+	conf := filepath.Join(base, "example.conf")
+	if err := os.WriteFile(conf, []byte("greeting = hello"), 0o644); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	s := spec.Fill(spec.Spec{
+		From: spec.From{
+			Repository: "docker.io/library/alpine",
+			Tag:        "3.18.3",
+			Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+		},
+		This: spec.This{
+			Repository: "localhost/example",
+			Tag:        "latest",
+		},
+		Packages: spec.Packages{
+			Install: spec.PackageList{"curl"},
+		},
+		Copy: []spec.Copy{
+			{Base: base, Destination: "/etc/example/", Sources: []string{"example.conf"}},
+			{FromStage: "builder", Base: "/src", Destination: "/usr/local/bin/", Sources: []string{"example"}},
+		},
+	})
+
+	keys := func() []string {
+		steps, err := newSteps(s, nil, nil)
+		if err != nil {
+			t.Fatalf("creating steps: %v", err)
+		}
+		recorder := &container.Container{
+			Logger:        logrus.New(),
+			CommonOptions: commonOptions(s, ExecuteOptions{}),
+			Recorder:      &container.Recorder{},
+		}
+
+		var result []string
+		key := initialCacheKey("0123456789abcdef", false)
+		for _, st := range steps {
+			if !cacheable(st) {
+				break
+			}
+			key, err = stepCacheKey(key, st, recorder)
+			if err != nil {
+				t.Fatalf("%s: computing cache key: %v", st.description, err)
+			}
+			result = append(result, key)
+		}
+		return result
+	}
+
+	before := keys()
+	if len(before) < 2 {
+		t.Fatalf("expected every step but the copy from a stage to be cacheable, found %d keys", len(before))
+	}
+	if again := keys(); again[len(again)-1] != before[len(before)-1] {
+		t.Errorf("expected the same inputs to produce the same keys")
+	}
+
+	if err := os.WriteFile(conf, []byte("greeting = goodbye"), 0o644); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	after := keys()
+	for i := 0; i < len(before)-1; i++ {
+		if after[i] != before[i] {
+			t.Errorf("expected the key of step %d to be unaffected by the copied file", i)
+		}
+	}
+	if after[len(after)-1] == before[len(before)-1] {
+		t.Errorf("expected the key of the copy to change with the contents of the copied file")
+	}
+
+	if err := os.Chmod(conf, 0o600); err != nil {
+		t.Fatalf("changing file mode: %v", err)
+	}
+	if chmodded := keys(); chmodded[len(chmodded)-1] == after[len(after)-1] {
+		t.Errorf("expected the key of the copy to change with the mode of the copied file")
+	}
+}
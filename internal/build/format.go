@@ -0,0 +1,64 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"fmt"
+
+	"github.com/ok-ryoko/turret/internal/spec"
+
+	"github.com/containers/buildah/define"
+	"github.com/containers/image/v5/pkg/compression"
+	"github.com/containers/storage/pkg/archive"
+)
+
+// manifestType returns the MIME type of the manifest of an image in the
+// format `f`.
+func manifestType(f spec.ImageFormat) string {
+	if f == spec.Docker {
+		return define.Dockerv2ImageManifest
+	}
+	return define.OCIv1ImageManifest
+}
+
+// layerCompression returns the compression that buildah applies to the layers
+// of an image when committing it for the compression `c`.
+func layerCompression(c spec.Compression) archive.Compression {
+	switch c {
+	case spec.Zstd, spec.ZstdChunked:
+		return archive.Zstd
+	case spec.Uncompressed:
+		return archive.Uncompressed
+	default:
+		return archive.Gzip
+	}
+}
+
+// compressionAlgorithm returns the algorithm with which containers/image
+// compresses the layers of an image when copying it for the compression `c`,
+// or nil if the layers shouldn't be compressed.
+func compressionAlgorithm(c spec.Compression) *compression.Algorithm {
+	switch c {
+	case spec.Zstd:
+		return &compression.Zstd
+	case spec.ZstdChunked:
+		return &compression.ZstdChunked
+	case spec.Uncompressed:
+		return nil
+	default:
+		return &compression.Gzip
+	}
+}
+
+// describeCompression returns a human-readable description of the compression
+// of the layers of an image.
+func describeCompression(c spec.Compression, level *int) string {
+	if c == spec.Uncompressed {
+		return "leave layers uncompressed"
+	}
+	if level != nil {
+		return fmt.Sprintf("compress layers with %s at level %d", c, *level)
+	}
+	return fmt.Sprintf("compress layers with %s", c)
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ok-ryoko/turret/internal/container"
+
+	"github.com/containers/buildah"
+	"github.com/containers/buildah/define"
+	is "github.com/containers/image/v5/storage"
+	"github.com/containers/image/v5/types"
+	"github.com/containers/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// layerCommitter commits the changes that steps make to the working container
+// to intermediate images, replacing the working container each time with a new
+// one created from the image. It gives each step's changes an image layer of
+// their own and records the results of steps in the build cache.
+type layerCommitter struct {
+	ctx     context.Context
+	store   storage.Store
+	logger  *logrus.Logger
+	options ExecuteOptions
+
+	// Preserve the image history and timestamps of the files in the working
+	// container's file system
+	keepHistory bool
+
+	// Commit the changes made by every step but the last as a layer of their
+	// own
+	perStep bool
+
+	// Reuse and record the results of cacheable steps
+	cache bool
+
+	// Whether the working container is unchanged since it was created from
+	// the last intermediate image
+	clean bool
+
+	// IDs of the unnamed intermediate images committed so far, in order
+	images []string
+}
+
+// commit commits the changes made to the working container `c` since it was
+// created to an intermediate image, recording `createdBy` in the history of
+// the image, and replaces the working container with a new one created from
+// that image.
+//
+// If `name` isn't empty, then the image is given that name and kept after the
+// build. Any image that held the name before is removed if it's left unnamed.
+func (l *layerCommitter) commit(c *container.Container, createdBy string, name string) error {
+	co := buildah.CommitOptions{
+		PreferredManifestType: define.OCIv1ImageManifest,
+		HistoryTimestamp:      &time.Time{},
+	}
+	if l.keepHistory {
+		co.HistoryTimestamp = nil
+	}
+
+	var (
+		dest     types.ImageReference
+		previous *storage.Image
+	)
+	if name != "" {
+		ref, err := is.Transport.ParseStoreReference(l.store, name)
+		if err != nil {
+			return fmt.Errorf("parsing reference: %w", err)
+		}
+		dest = ref
+		if img, err := l.store.Image(name); err == nil {
+			previous = img
+		}
+	}
+
+	c.Builder.SetCreatedBy(createdBy)
+	imageID, _, _, err := c.Builder.Commit(l.ctx, dest, co)
+	if err != nil {
+		return fmt.Errorf("committing layer: %w", err)
+	}
+	if name == "" {
+		l.images = append(l.images, imageID)
+	}
+
+	if previous != nil && previous.ID != imageID {
+		if img, err := l.store.Image(previous.ID); err == nil && len(img.Names) == 0 {
+			if _, err := l.store.DeleteImage(previous.ID, true); err != nil {
+				l.logger.Warnf("failed removing replaced cache image %s: %v", previous.ID, err)
+			}
+		}
+	}
+
+	return l.restart(c, imageID)
+}
+
+// reuse replaces the working container `c` with a new one created from the
+// image named `name` if it exists, reporting whether it does.
+func (l *layerCommitter) reuse(c *container.Container, name string) (bool, error) {
+	img, err := l.store.Image(name)
+	if errors.Is(err, storage.ErrImageUnknown) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("looking up image %s: %w", name, err)
+	}
+	if err := l.restart(c, img.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// restart replaces the working container `c` with a new one created from the
+// image with ID `imageID`.
+func (l *layerCommitter) restart(c *container.Container, imageID string) error {
+	builder, err := newBuilder(l.ctx, l.store, imageID, l.logger, l.options)
+	if err != nil {
+		return err
+	}
+
+	id := c.ContainerID()
+	if err := c.Builder.Delete(); err != nil {
+		l.logger.Warnln("failed deleting working container")
+		l.logger.Infoln("please remove the container manually: buildah rm", id)
+	}
+	c.Builder = builder
+	l.clean = true
+
+	return nil
+}
+
+// remove removes the unnamed intermediate images, logging rather than
+// returning any error. The layers that the final image shares with them are
+// kept.
+func (l *layerCommitter) remove() {
+	for i := len(l.images) - 1; i >= 0; i-- {
+		if _, err := l.store.DeleteImage(l.images[i], true); err != nil {
+			l.logger.Warnf("failed removing intermediate image %s: %v", l.images[i], err)
+		}
+	}
+}
+
+// historyEntry returns the text recorded in the image history for the layer
+// holding the changes made by a step.
+func historyEntry(st step) string {
+	return strings.Join(append([]string{"turret: " + st.description}, st.details...), "; ")
+}
@@ -0,0 +1,60 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPlanPerStepLayers(t *testing.T) {
+	s := spec.Fill(spec.Spec{
+		From: spec.From{
+			Repository: "docker.io/library/alpine",
+			Tag:        "3.18.3",
+			Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+		},
+		This: spec.This{
+			Repository: "localhost/example",
+			Tag:        "latest",
+			Layers:     spec.LayeringWrapper{Layering: spec.PerStep},
+		},
+		Packages: spec.Packages{
+			Install: spec.PackageList{"curl"},
+		},
+		Copy: []spec.Copy{
+			{Base: "/src", Destination: "/etc/example/", Sources: []string{"example.conf"}},
+		},
+	})
+
+	plan, err := Plan(s, logrus.New(), ExecuteOptions{})
+	if err != nil {
+		t.Fatalf("planning build: %v", err)
+	}
+
+	layered := map[string]bool{}
+	var commit []string
+	for _, st := range plan {
+		for _, d := range st.Details {
+			if strings.HasPrefix(d, "commit the changes as a layer") {
+				layered[st.Description] = true
+			}
+		}
+		if st.Description == "committing image" {
+			commit = st.Details
+		}
+	}
+
+	if !layered["installing packages"] {
+		t.Errorf("expected the packages to be committed as a layer of their own")
+	}
+	if layered["copying files"] {
+		t.Errorf("expected the last step to be left for the final commit")
+	}
+	if len(commit) == 0 || !strings.HasPrefix(commit[0], "commit layered image") {
+		t.Errorf("expected a layered commit, found %v", commit)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ok-ryoko/turret/internal/container"
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+const (
+	// Absolute path at which the local package repository is mounted in the
+	// working container
+	localRepositoryPath string = "/run/turret/repository"
+
+	// Absolute path at which the package manager configuration for the local
+	// package repository is mounted in the working container
+	localConfigDir string = "/run/turret/config"
+)
+
+// newPackageCommandFactory creates a command factory for the package manager
+// of a spec that, if the spec has a local package repository, resolves
+// packages using only that repository.
+func newPackageCommandFactory(s spec.Spec) (pckg.CommandFactory, error) {
+	factory, err := pckg.NewCommandFactory(s.Backends.Package.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	if s.Packages.Local != nil {
+		factory = pckg.LocalCommandFactory{
+			CommandFactory: factory,
+			Repository:     localRepository(*s.Packages.Local),
+		}
+	}
+	return factory, nil
+}
+
+// localRepository describes a local package repository as seen from the
+// working container.
+func localRepository(r spec.LocalRepository) pckg.LocalRepository {
+	return pckg.LocalRepository{
+		Path:      localRepositoryPath,
+		ConfigDir: localConfigDir,
+		Trusted:   r.Trusted,
+	}
+}
+
+// bindMount returns a read-only bind mount of the host directory `src` at
+// `dest` in the working container.
+func bindMount(src, dest string) specs.Mount {
+	return specs.Mount{
+		Destination: dest,
+		Type:        "bind",
+		Source:      src,
+		Options:     []string{"bind", "ro"},
+	}
+}
+
+// mountLocalRepository writes the files that configure the package manager to
+// use the local package repository of a spec to a temporary directory on the
+// host and mounts that directory read-only in the working container. The
+// returned function removes the temporary directory.
+//
+// Nothing is done if the spec has no local package repository.
+func mountLocalRepository(c *container.Container, s spec.Spec) (func(), error) {
+	noop := func() {}
+	if s.Packages.Local == nil {
+		return noop, nil
+	}
+
+	if info, err := os.Stat(s.Packages.Local.Path); err != nil {
+		return noop, fmt.Errorf("%w", err)
+	} else if !info.IsDir() {
+		return noop, fmt.Errorf("local repository %s is not a directory", s.Packages.Local.Path)
+	}
+
+	factory, err := pckg.NewCommandFactory(s.Backends.Package.Backend)
+	if err != nil {
+		return noop, fmt.Errorf("creating package command factory: %w", err)
+	}
+
+	_, files := factory.LocalRepositoryOptions(localRepository(*s.Packages.Local))
+	if len(files) == 0 {
+		return noop, nil
+	}
+
+	dir, err := os.MkdirTemp("", "turret-")
+	if err != nil {
+		return noop, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			c.Logger.Warnf("failed removing temporary directory %s", dir)
+		}
+	}
+
+	for _, f := range files {
+		rel, ok := strings.CutPrefix(f.Path, localConfigDir+"/")
+		if !ok {
+			cleanup()
+			return noop, fmt.Errorf("file %s is outside %s", f.Path, localConfigDir)
+		}
+
+		p := filepath.Join(dir, filepath.FromSlash(path.Clean(rel)))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			cleanup()
+			return noop, fmt.Errorf("%w", err)
+		}
+		if err := os.WriteFile(p, []byte(f.Contents), 0o644); err != nil {
+			cleanup()
+			return noop, fmt.Errorf("%w", err)
+		}
+	}
+
+	c.CommonOptions.Mounts = append(c.CommonOptions.Mounts, bindMount(dir, localConfigDir))
+
+	return cleanup, nil
+}
+
+// describeLocalRepository returns a human-readable description of the mount
+// that makes the local package repository of a spec available in the working
+// container, assuming the spec has a local package repository.
+func describeLocalRepository(s spec.Spec) string {
+	return fmt.Sprintf("mount local package repository %s read-only at %s", s.Packages.Local.Path, localRepositoryPath)
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ok-ryoko/turret/internal/container"
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+)
+
+// Lock records the packages installed in the working containers of a build so
+// that later builds can install the same versions.
+type Lock struct {
+	// Base name of the spec file whose build the lock records
+	Spec string `toml:"spec"`
+
+	// Packages installed in the working container of the final image
+	Packages []pckg.Package `toml:"packages"`
+
+	// Packages installed in the working container of each stage, keyed by the
+	// name of the stage
+	Stages map[string]LockedStage `toml:"stages,omitempty"`
+}
+
+// LockedStage records the packages installed in the working container of a
+// stage.
+type LockedStage struct {
+	// Packages installed in the working container of the stage
+	Packages []pckg.Package `toml:"packages"`
+}
+
+// applyLock pins every package that a spec and its stages install without a
+// version to the version recorded in `lock`. The spec is returned unchanged if
+// `lock` is nil.
+func applyLock(s spec.Spec, lock *Lock) spec.Spec {
+	if lock == nil {
+		return s
+	}
+
+	s.Packages.Install = pinPackages(s.Packages.Install, lock.Packages)
+
+	stages := make(map[string]spec.Stage, len(s.Stages))
+	for name, st := range s.Stages {
+		st.Packages.Install = pinPackages(st.Packages.Install, lock.Stages[name].Packages)
+		stages[name] = st
+	}
+	s.Stages = stages
+
+	return s
+}
+
+// pinPackages returns a copy of a list of packages in which every package
+// without a version is pinned to its version in `locked`, if any.
+func pinPackages(l spec.PackageList, locked []pckg.Package) spec.PackageList {
+	versions := make(map[string]string, len(locked))
+	for _, p := range locked {
+		versions[p.Name] = p.Version
+	}
+
+	pinned := make(spec.PackageList, 0, len(l))
+	for _, p := range l.Packages() {
+		if v, ok := versions[p.Name]; ok && p.Version == "" {
+			p.Version = v
+		}
+		pinned = append(pinned, p)
+	}
+	return pinned
+}
+
+// listPackages lists the packages installed in the working container.
+func listPackages(c *container.Container, s spec.Spec) ([]pckg.Package, error) {
+	pckgCmdFactory, err := newPackageCommandFactory(s)
+	if err != nil {
+		return nil, fmt.Errorf("creating package command factory: %w", err)
+	}
+
+	pckgFrontend, err := container.NewPackageFrontend(pckgCmdFactory)
+	if err != nil {
+		return nil, fmt.Errorf("creating package management interface: %w", err)
+	}
+
+	packages, err := pckgFrontend.List(c)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Name != packages[j].Name {
+			return packages[i].Name < packages[j].Name
+		}
+		return packages[i].Architecture < packages[j].Architecture
+	})
+
+	return packages, nil
+}
+
+// verifyPackages returns an error describing every difference between the
+// packages installed in a working container and the packages in a lock.
+func verifyPackages(installed, locked []pckg.Package) error {
+	key := func(p pckg.Package) string {
+		if p.Architecture == "" {
+			return p.Name
+		}
+		return p.Name + ":" + p.Architecture
+	}
+
+	lockedVersions := make(map[string]string, len(locked))
+	for _, p := range locked {
+		lockedVersions[key(p)] = p.Version
+	}
+
+	var problems []string
+	for _, p := range installed {
+		k := key(p)
+		v, ok := lockedVersions[k]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s %s isn't locked", k, p.Version))
+		case v != p.Version:
+			problems = append(problems, fmt.Sprintf("%s is locked to %s but %s is installed", k, v, p.Version))
+		}
+		delete(lockedVersions, k)
+	}
+
+	missing := make([]string, 0, len(lockedVersions))
+	for k, v := range lockedVersions {
+		missing = append(missing, fmt.Sprintf("%s %s is locked but isn't installed", k, v))
+	}
+	sort.Strings(missing)
+	problems = append(problems, missing...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("installed packages differ from lock: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
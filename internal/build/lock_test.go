@@ -0,0 +1,68 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+)
+
+func TestPinPackages(t *testing.T) {
+	l := spec.PackageList{
+		"ca-certificates",
+		map[string]any{"name": "curl", "version": "8.2.1-r0"},
+		"git",
+	}
+	locked := []pckg.Package{
+		{Name: "ca-certificates", Version: "20230506-r0"},
+		{Name: "curl", Version: "8.1.2-r0"},
+	}
+
+	actual := pinPackages(l, locked).Packages()
+
+	expected := []pckg.Package{
+		{Name: "ca-certificates", Version: "20230506-r0"},
+		{Name: "curl", Version: "8.2.1-r0"},
+		{Name: "git"},
+	}
+
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d packages, found %d", len(expected), len(actual))
+	}
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("expected package %+v at position %d, found %+v", expected[i], i, actual[i])
+		}
+	}
+}
+
+func TestVerifyPackages(t *testing.T) {
+	locked := []pckg.Package{
+		{Name: "busybox", Version: "1.36.1-r0", Architecture: "x86_64"},
+		{Name: "curl", Version: "8.2.1-r0", Architecture: "x86_64"},
+		{Name: "musl", Version: "1.2.4-r0", Architecture: "x86_64"},
+	}
+
+	if err := verifyPackages(locked, locked); err != nil {
+		t.Errorf("expected no error, found %v", err)
+	}
+
+	installed := []pckg.Package{
+		{Name: "busybox", Version: "1.36.1-r0", Architecture: "x86_64"},
+		{Name: "curl", Version: "8.2.1-r1", Architecture: "x86_64"},
+		{Name: "git", Version: "2.40.1-r0", Architecture: "x86_64"},
+	}
+
+	err := verifyPackages(installed, locked)
+	if err == nil {
+		t.Fatalf("expected error for packages that differ from lock")
+	}
+
+	for _, s := range []string{"curl:x86_64 is locked to 8.2.1-r0", "git:x86_64 2.40.1-r0 isn't locked", "musl:x86_64 1.2.4-r0 is locked"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("expected error to contain %q, found %q", s, err)
+		}
+	}
+}
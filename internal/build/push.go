@@ -0,0 +1,172 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ok-ryoko/turret/internal/spec"
+
+	"github.com/containers/common/pkg/retry"
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/signature"
+	is "github.com/containers/image/v5/storage"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
+	"github.com/containers/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// pushOptions holds options for copying a committed image from local storage
+// to other locations.
+type pushOptions struct {
+	// Path to the file holding the credentials for container registries; when
+	// empty, the default location is used
+	authFile string
+
+	// Algorithm with which to compress the layers of the image
+	compression spec.Compression
+
+	// Level at which to compress the layers of the image; when nil, the
+	// algorithm's default level is used
+	compressionLevel *int
+
+	// Skip verifying the TLS certificates of container registries
+	skipTLSVerify bool
+
+	// Number of times to retry copying the image to a destination after a
+	// transient failure
+	retries uint
+
+	// Time to wait between attempts; when 0, the wait grows exponentially
+	retryDelay time.Duration
+}
+
+// newPushOptions derives the options for copying an image to its destinations
+// from a spec and the options for the build pipeline.
+func newPushOptions(s spec.Spec, options ExecuteOptions) pushOptions {
+	return pushOptions{
+		authFile:         options.AuthFile,
+		compression:      s.This.Compression.Compression,
+		compressionLevel: s.This.CompressionLevel,
+		skipTLSVerify:    options.SkipTLSVerify,
+		retries:          options.Retries,
+		retryDelay:       options.RetryDelay,
+	}
+}
+
+// destinations returns the locations to which to copy the image built from a
+// spec: those in the spec followed by those in the options for the build
+// pipeline.
+func destinations(s spec.Spec, options ExecuteOptions) []string {
+	result := make([]string, 0, len(s.This.Destinations)+len(options.Destinations))
+	result = append(result, s.This.Destinations...)
+	result = append(result, options.Destinations...)
+	return result
+}
+
+// push copies the image with ID `imageID` from local storage to every
+// location in `dests`, each given as TRANSPORT:REFERENCE, logging the digest
+// of the manifest written to each location.
+func push(
+	ctx context.Context,
+	store storage.Store,
+	imageID string,
+	dests []string,
+	logger *logrus.Logger,
+	options pushOptions,
+) error {
+	srcRef, err := is.Transport.NewStoreReference(store, nil, imageID)
+	if err != nil {
+		return fmt.Errorf("referencing image %s: %w", imageID, err)
+	}
+
+	// The source is the image we've just committed, so there are no
+	// signatures to verify
+	policy := &signature.Policy{Default: signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()}}
+	policyContext, err := signature.NewPolicyContext(policy)
+	if err != nil {
+		return fmt.Errorf("creating signature policy context: %w", err)
+	}
+	defer func() {
+		if err := policyContext.Destroy(); err != nil {
+			logger.Warnf("failed destroying signature policy context: %v", err)
+		}
+	}()
+
+	sys := &types.SystemContext{AuthFilePath: options.authFile}
+	if options.skipTLSVerify {
+		sys.DockerInsecureSkipTLSVerify = types.OptionalBoolTrue
+		sys.OCIInsecureSkipTLSVerify = true
+	}
+
+	// Registries always receive compressed layers, so leaving layers
+	// uncompressed only affects the transports that write to the file system
+	destSys := *sys
+	destSys.CompressionFormat = compressionAlgorithm(options.compression)
+	destSys.CompressionLevel = options.compressionLevel
+	if options.compression == spec.Uncompressed {
+		destSys.DirForceDecompress = true
+		destSys.OCIAcceptUncompressedLayers = true
+	}
+
+	var reportWriter io.Writer
+	if logger.IsLevelEnabled(logrus.DebugLevel) {
+		reportWriter = logger.Writer()
+		defer reportWriter.(io.Closer).Close()
+	}
+
+	for _, d := range dests {
+		destRef, err := alltransports.ParseImageName(d)
+		if err != nil {
+			return fmt.Errorf("parsing destination %s: %w", d, err)
+		}
+
+		logger.Debugf("copying image to %s...", d)
+		var manifestBytes []byte
+		err = retry.IfNecessary(ctx, func() error {
+			manifestBytes, err = copy.Image(ctx, policyContext, destRef, srcRef, &copy.Options{
+				ReportWriter:   reportWriter,
+				SourceCtx:      sys,
+				DestinationCtx: &destSys,
+			})
+			return err
+		}, &retry.Options{MaxRetry: int(options.retries), Delay: options.retryDelay})
+		if err != nil {
+			return fmt.Errorf("copying image to %s: %w", d, err)
+		}
+
+		digest, err := manifest.Digest(manifestBytes)
+		if err != nil {
+			return fmt.Errorf("computing digest of manifest written to %s: %w", d, err)
+		}
+		logger.Infof("copied image to %s (%s)", d, digest)
+	}
+
+	return nil
+}
+
+// describe returns human-readable descriptions of the operations performed
+// when copying an image to the locations in `dests` with these options.
+func (o pushOptions) describe(dests []string) []string {
+	d := make([]string, 0, len(dests)+2)
+	for _, dest := range dests {
+		d = append(d, fmt.Sprintf("copy image to %s", dest))
+	}
+	if o.skipTLSVerify {
+		d = append(d, "skip verifying the TLS certificates of container registries")
+	}
+	if o.retries > 0 {
+		if o.retryDelay > 0 {
+			d = append(d, fmt.Sprintf("retry up to %d times, waiting %s between attempts", o.retries, o.retryDelay))
+		} else {
+			d = append(d, fmt.Sprintf("retry up to %d times with exponential backoff", o.retries))
+		}
+	}
+	return d
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Key of the annotation that holds the package report of an image in JSON
+const packageReportKey string = "com.github.ok-ryoko.turret.packages.report"
+
+// PackageReport records how the packages installed in the working container
+// of the final image changed relative to its base image.
+type PackageReport struct {
+	// Packages installed by the build
+	Added []pckg.Package `json:"added"`
+
+	// Packages removed by the build
+	Removed []pckg.Package `json:"removed"`
+
+	// Packages whose version the build changed, e.g., by upgrading them
+	Changed []PackageChange `json:"changed"`
+}
+
+// PackageChange records the change in the version of an installed package.
+type PackageChange struct {
+	// Name of the package
+	Name string `json:"name"`
+
+	// Architecture for which the package was built; empty if unknown
+	Architecture string `json:"arch,omitempty"`
+
+	// Version installed in the base image
+	From string `json:"from"`
+
+	// Version installed by the build
+	To string `json:"to"`
+}
+
+// diffPackages compares the packages installed in a working container before
+// and after the build, each list sorted as by listPackages.
+func diffPackages(before, after []pckg.Package) PackageReport {
+	key := func(p pckg.Package) string {
+		return p.Name + ":" + p.Architecture
+	}
+
+	previous := make(map[string]pckg.Package, len(before))
+	for _, p := range before {
+		previous[key(p)] = p
+	}
+
+	report := PackageReport{
+		Added:   []pckg.Package{},
+		Removed: []pckg.Package{},
+		Changed: []PackageChange{},
+	}
+	for _, p := range after {
+		k := key(p)
+		old, ok := previous[k]
+		switch {
+		case !ok:
+			report.Added = append(report.Added, p)
+		case old.Version != p.Version:
+			report.Changed = append(report.Changed, PackageChange{
+				Name:         p.Name,
+				Architecture: p.Architecture,
+				From:         old.Version,
+				To:           p.Version,
+			})
+		}
+		delete(previous, k)
+	}
+
+	for _, p := range previous {
+		report.Removed = append(report.Removed, p)
+	}
+	sort.Slice(report.Removed, func(i, j int) bool {
+		return key(report.Removed[i]) < key(report.Removed[j])
+	})
+
+	return report
+}
+
+// Lines returns a human-readable line for every package in the report,
+// prefixed with "+" if added, "-" if removed and "~" if changed.
+func (r PackageReport) Lines() []string {
+	describe := func(name, arch string) string {
+		if arch == "" {
+			return name
+		}
+		return fmt.Sprintf("%s (%s)", name, arch)
+	}
+
+	lines := make([]string, 0, len(r.Added)+len(r.Removed)+len(r.Changed))
+	for _, p := range r.Added {
+		lines = append(lines, fmt.Sprintf("+ %s %s", describe(p.Name, p.Architecture), p.Version))
+	}
+	for _, p := range r.Removed {
+		lines = append(lines, fmt.Sprintf("- %s %s", describe(p.Name, p.Architecture), p.Version))
+	}
+	for _, c := range r.Changed {
+		lines = append(lines, fmt.Sprintf("~ %s %s -> %s", describe(c.Name, c.Architecture), c.From, c.To))
+	}
+	return lines
+}
+
+// logPackageReport writes a summary of a package report and a line for every
+// package in it to the log.
+func logPackageReport(logger *logrus.Logger, r PackageReport) {
+	logger.Infof(
+		"packages relative to the base image: %d added, %d removed, %d changed",
+		len(r.Added),
+		len(r.Removed),
+		len(r.Changed),
+	)
+	for _, l := range r.Lines() {
+		logger.Infoln(l)
+	}
+}
@@ -0,0 +1,43 @@
+package build
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+)
+
+func TestDiffPackages(t *testing.T) {
+	before := []pckg.Package{
+		{Name: "bash", Version: "5.2.15-2+b2", Architecture: "amd64"},
+		{Name: "libc6", Version: "2.36-9+deb12u1", Architecture: "amd64"},
+		{Name: "nano", Version: "7.2-1", Architecture: "amd64"},
+		{Name: "tzdata", Version: "2023c-5", Architecture: "all"},
+	}
+	after := []pckg.Package{
+		{Name: "bash", Version: "5.2.15-2+b2", Architecture: "amd64"},
+		{Name: "curl", Version: "7.88.1-10+deb12u1", Architecture: "amd64"},
+		{Name: "libc6", Version: "2.36-9+deb12u3", Architecture: "amd64"},
+		{Name: "tzdata", Version: "2023c-5", Architecture: "all"},
+	}
+
+	report := diffPackages(before, after)
+
+	expected := PackageReport{
+		Added:   []pckg.Package{{Name: "curl", Version: "7.88.1-10+deb12u1", Architecture: "amd64"}},
+		Removed: []pckg.Package{{Name: "nano", Version: "7.2-1", Architecture: "amd64"}},
+		Changed: []PackageChange{{Name: "libc6", Architecture: "amd64", From: "2.36-9+deb12u1", To: "2.36-9+deb12u3"}},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected report %+v, found %+v", expected, report)
+	}
+
+	expectedLines := []string{
+		"+ curl (amd64) 7.88.1-10+deb12u1",
+		"- nano (amd64) 7.2-1",
+		"~ libc6 (amd64) 2.36-9+deb12u1 -> 2.36-9+deb12u3",
+	}
+	if lines := report.Lines(); !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("expected lines %q, found %q", expectedLines, lines)
+	}
+}
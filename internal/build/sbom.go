@@ -0,0 +1,350 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+
+	"github.com/containers/storage/pkg/fileutils"
+)
+
+// Key of the annotation that holds the SHA256 digest of the SBOM of an image
+const sbomDigestKey string = "com.github.ok-ryoko.turret.sbom.digest"
+
+var reSPDXIdentifier = regexp.MustCompile(`^[0-9A-Za-z.-]+$`)
+
+// sbom is a software bill of materials in the CycloneDX 1.5 JSON format.
+//
+// The serial number and timestamp are omitted so that building the same image
+// twice yields the same SBOM and the same digest.
+type sbom struct {
+	BOMFormat   string          `json:"bomFormat"`
+	SpecVersion string          `json:"specVersion"`
+	Version     int             `json:"version"`
+	Metadata    sbomMetadata    `json:"metadata"`
+	Components  []sbomComponent `json:"components"`
+}
+
+// sbomMetadata describes the image that an SBOM is about and the tool that
+// produced it.
+type sbomMetadata struct {
+	Tools     sbomTools     `json:"tools"`
+	Component sbomComponent `json:"component"`
+}
+
+// sbomTools lists the tools that produced an SBOM.
+type sbomTools struct {
+	Components []sbomComponent `json:"components"`
+}
+
+// sbomComponent is a package, file or image listed in an SBOM.
+type sbomComponent struct {
+	Type       string         `json:"type"`
+	BOMRef     string         `json:"bom-ref,omitempty"`
+	Name       string         `json:"name"`
+	Version    string         `json:"version,omitempty"`
+	Licenses   []sbomLicense  `json:"licenses,omitempty"`
+	PURL       string         `json:"purl,omitempty"`
+	Hashes     []sbomHash     `json:"hashes,omitempty"`
+	Properties []sbomProperty `json:"properties,omitempty"`
+}
+
+// sbomLicense holds the license of a component as declared by its package,
+// either as a single license or as an SPDX license expression.
+type sbomLicense struct {
+	License    *sbomLicenseInfo `json:"license,omitempty"`
+	Expression string           `json:"expression,omitempty"`
+}
+
+// sbomLicenseInfo identifies a single license by its SPDX identifier or, if
+// it has none, by name.
+type sbomLicenseInfo struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// sbomHash holds a digest of the contents of a component.
+type sbomHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+// sbomProperty holds a name-value pair that CycloneDX has no field for.
+type sbomProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// newSBOM returns a CycloneDX SBOM in JSON for the image described by a spec,
+// listing the packages installed in its working container and the files
+// copied to it by the spec's copy entries.
+func newSBOM(s spec.Spec, packages []pckg.Package) ([]byte, error) {
+	doc := sbom{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: sbomMetadata{
+			Tools: sbomTools{
+				Components: []sbomComponent{{Type: "application", Name: "turret"}},
+			},
+			Component: sbomComponent{
+				Type:    "container",
+				Name:    s.This.Repository,
+				Version: s.This.Tag,
+			},
+		},
+		Components: make([]sbomComponent, 0, len(packages)),
+	}
+
+	namespace := strings.ToLower(s.From.Distro.String())
+	for _, p := range packages {
+		doc.Components = append(doc.Components, packageComponent(p, s.Backends.Package.Backend, namespace))
+	}
+
+	for _, cp := range s.Copy {
+		components, err := copyComponents(cp)
+		if err != nil {
+			return nil, fmt.Errorf("listing files copied to %s: %w", cp.Destination, err)
+		}
+		doc.Components = append(doc.Components, components...)
+	}
+
+	blob, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return append(blob, '\n'), nil
+}
+
+// sbomDigest returns the digest of an SBOM in the form sha256:HEX.
+func sbomDigest(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// packageComponent describes an installed package as an SBOM component,
+// identifying it with a package URL in the namespace `namespace` if the package
+// manager has a purl type.
+func packageComponent(p pckg.Package, b pckg.Backend, namespace string) sbomComponent {
+	c := sbomComponent{
+		Type:    "library",
+		Name:    p.Name,
+		Version: p.Version,
+		PURL:    packageURL(p, b, namespace),
+	}
+
+	c.BOMRef = c.PURL
+	if c.BOMRef == "" {
+		c.BOMRef = fmt.Sprintf("package:%s@%s", p.Name, p.Version)
+		if p.Architecture != "" {
+			c.BOMRef += "?arch=" + p.Architecture
+		}
+	}
+
+	if p.License != "" {
+		c.Licenses = []sbomLicense{packageLicense(p.License)}
+	}
+
+	if p.Source != "" {
+		c.Properties = []sbomProperty{{Name: "turret:package:source", Value: p.Source}}
+	}
+
+	return c
+}
+
+// packageLicense describes the license declared by a package as an SPDX
+// license identifier if it is one, as an SPDX license expression if it's a
+// valid compound expression, e.g., MIT AND GPL-2.0-or-later, and by name
+// otherwise.
+func packageLicense(license string) sbomLicense {
+	switch {
+	case spdxLicenseIDs[license]:
+		return sbomLicense{License: &sbomLicenseInfo{ID: license}}
+	case isSPDXExpression(license):
+		return sbomLicense{Expression: license}
+	default:
+		return sbomLicense{License: &sbomLicenseInfo{Name: license}}
+	}
+}
+
+// isSPDXExpression reports whether `s` is an SPDX license expression built
+// from SPDX license identifiers, custom license references, the operators
+// AND, OR and WITH, the or-later suffix and balanced parentheses.
+func isSPDXExpression(s string) bool {
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s))
+	if len(tokens) == 0 {
+		return false
+	}
+
+	depth := 0
+	expectOperand := true
+	afterWith := false
+	for _, t := range tokens {
+		switch {
+		case t == "(":
+			if !expectOperand {
+				return false
+			}
+			depth++
+		case t == ")":
+			if expectOperand || depth == 0 {
+				return false
+			}
+			depth--
+		case t == "AND" || t == "OR" || t == "WITH":
+			if expectOperand {
+				return false
+			}
+			expectOperand = true
+			afterWith = t == "WITH"
+		default:
+			if !expectOperand {
+				return false
+			}
+			switch {
+			case afterWith:
+				if !reSPDXIdentifier.MatchString(t) {
+					return false
+				}
+			case strings.HasPrefix(t, "LicenseRef-"):
+			case !spdxLicenseIDs[strings.TrimSuffix(t, "+")]:
+				return false
+			}
+			expectOperand = false
+			afterWith = false
+		}
+	}
+	return depth == 0 && !expectOperand
+}
+
+// packageURL returns the package URL of an installed package in the namespace
+// `namespace`, or an empty string if the package manager has no purl type.
+func packageURL(p pckg.Package, b pckg.Backend, namespace string) string {
+	var kind string
+	switch b {
+	case pckg.APK:
+		kind = "apk"
+	case pckg.APT:
+		kind = "deb"
+	case pckg.DNF, pckg.Zypper:
+		kind = "rpm"
+	case pckg.Pacman:
+		kind = "alpm"
+	default:
+		return ""
+	}
+
+	u := fmt.Sprintf("pkg:%s/%s/%s", kind, url.PathEscape(namespace), url.PathEscape(p.Name))
+	if p.Version != "" {
+		u += "@" + url.QueryEscape(p.Version)
+	}
+
+	var qualifiers []string
+	if p.Architecture != "" {
+		qualifiers = append(qualifiers, "arch="+url.QueryEscape(p.Architecture))
+	}
+	if p.Source != "" && p.Source != p.Name {
+		qualifiers = append(qualifiers, "upstream="+url.QueryEscape(p.Source))
+	}
+	if len(qualifiers) > 0 {
+		u += "?" + strings.Join(qualifiers, "&")
+	}
+
+	return u
+}
+
+// copyComponents describes the files that a copy entry adds to the working
+// container as SBOM components.
+//
+// Files copied from the host are listed with their SHA256 digests. Files
+// copied from a stage can't be inspected after the stage's working container
+// has been removed, so they're listed by source pattern without a digest.
+func copyComponents(cp spec.Copy) ([]sbomComponent, error) {
+	if cp.FromStage != "" {
+		components := make([]sbomComponent, 0, len(cp.Sources))
+		for _, src := range cp.Sources {
+			components = append(components, sbomComponent{
+				Type:       "file",
+				Name:       path.Join(cp.Destination, src),
+				Properties: []sbomProperty{{Name: "turret:copy:from-stage", Value: cp.FromStage}},
+			})
+		}
+		return components, nil
+	}
+
+	var components []sbomComponent
+	err := walkCopySources(cp, func(p, rel string) error {
+		digest, err := digestFile(p)
+		if err != nil {
+			return err
+		}
+
+		components = append(components, sbomComponent{
+			Type:   "file",
+			Name:   path.Join(cp.Destination, rel),
+			Hashes: []sbomHash{{Algorithm: "SHA-256", Content: digest}},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return components, nil
+}
+
+// walkCopySources calls `fn` for each regular file that a copy entry copies
+// from the host's file system, in lexical order, passing the file's path and
+// its path relative to the copy's base directory in slash-separated form.
+func walkCopySources(cp spec.Copy, fn func(p, rel string) error) error {
+	// Match the files in the same way as copyFiles
+	patterns := make([]string, 0, 1+len(cp.Sources)+len(cp.Excludes))
+	patterns = append(patterns, "*")
+	for _, src := range cp.Sources {
+		patterns = append(patterns, "!"+src)
+	}
+	patterns = append(patterns, cp.Excludes...)
+	pm, err := fileutils.NewPatternMatcher(patterns)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	err = filepath.WalkDir(cp.Base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(cp.Base, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if excluded, err := pm.IsMatch(rel); err != nil {
+			return err
+		} else if excluded {
+			return nil
+		}
+
+		return fn(p, rel)
+	})
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+)
+
+func TestPackageURL(t *testing.T) {
+	cases := []struct {
+		p         pckg.Package
+		b         pckg.Backend
+		namespace string
+		expected  string
+	}{
+		{
+			pckg.Package{Name: "libc6", Version: "2.36-9+deb12u3", Architecture: "amd64", Source: "glibc"},
+			pckg.APT,
+			"debian",
+			"pkg:deb/debian/libc6@2.36-9%2Bdeb12u3?arch=amd64&upstream=glibc",
+		},
+		{
+			pckg.Package{Name: "vim-minimal", Version: "2:9.0.1882-1.fc38", Architecture: "x86_64", Source: "vim"},
+			pckg.DNF,
+			"fedora",
+			"pkg:rpm/fedora/vim-minimal@2%3A9.0.1882-1.fc38?arch=x86_64&upstream=vim",
+		},
+		{
+			pckg.Package{Name: "busybox", Version: "1.36.1-r2", Architecture: "x86_64", Source: "busybox"},
+			pckg.APK,
+			"alpine",
+			"pkg:apk/alpine/busybox@1.36.1-r2?arch=x86_64",
+		},
+		{
+			pckg.Package{Name: "bash", Version: "5.2.015_1", Architecture: "x86_64"},
+			pckg.XBPS,
+			"void",
+			"",
+		},
+	}
+
+	for _, c := range cases {
+		if u := packageURL(c.p, c.b, c.namespace); u != c.expected {
+			t.Errorf("expected package URL %q, found %q", c.expected, u)
+		}
+	}
+}
+
+func TestCopyComponents(t *testing.T) {
+	base := t.TempDir()
+	files := map[string]string{
+		"bin/hello":      "hello",
+		"bin/hello.sig":  "signature",
+		"etc/hello.conf": "greeting = hello",
+		"README.md":      "readme",
+	}
+	for name, contents := range files {
+		p := filepath.Join(base, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("creating directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+			t.Fatalf("writing file: %v", err)
+		}
+	}
+
+	components, err := copyComponents(spec.Copy{
+		Base:        base,
+		Destination: "/opt/hello",
+		Sources:     []string{"bin", "etc/*.conf"},
+		Excludes:    []string{"**/*.sig"},
+	})
+	if err != nil {
+		t.Fatalf("listing copied files: %v", err)
+	}
+
+	expected := []sbomComponent{
+		{
+			Type:   "file",
+			Name:   "/opt/hello/bin/hello",
+			Hashes: []sbomHash{{Algorithm: "SHA-256", Content: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"}},
+		},
+		{
+			Type:   "file",
+			Name:   "/opt/hello/etc/hello.conf",
+			Hashes: []sbomHash{{Algorithm: "SHA-256", Content: "2b010db29388bcc7b3e2e23abfab19745945db6dfc1ab5b3db3c8defe7da865a"}},
+		},
+	}
+	if !reflect.DeepEqual(components, expected) {
+		t.Errorf("expected components %+v, found %+v", expected, components)
+	}
+}
+
+func TestPackageLicense(t *testing.T) {
+	cases := []struct {
+		license  string
+		expected sbomLicense
+	}{
+		{"MIT", sbomLicense{License: &sbomLicenseInfo{ID: "MIT"}}},
+		{"GPL-2.0-only", sbomLicense{License: &sbomLicenseInfo{ID: "GPL-2.0-only"}}},
+		{"MIT AND GPL-2.0-or-later", sbomLicense{Expression: "MIT AND GPL-2.0-or-later"}},
+		{"(MIT OR Apache-2.0) AND BSD-3-Clause", sbomLicense{Expression: "(MIT OR Apache-2.0) AND BSD-3-Clause"}},
+		{"GPL-2.0-or-later WITH Classpath-exception-2.0", sbomLicense{Expression: "GPL-2.0-or-later WITH Classpath-exception-2.0"}},
+		{"LGPL-2.1+", sbomLicense{Expression: "LGPL-2.1+"}},
+		{"GPL", sbomLicense{License: &sbomLicenseInfo{Name: "GPL"}}},
+		{"GPL AND custom", sbomLicense{License: &sbomLicenseInfo{Name: "GPL AND custom"}}},
+		{"GPLv2+ and LGPLv2+", sbomLicense{License: &sbomLicenseInfo{Name: "GPLv2+ and LGPLv2+"}}},
+		{"custom: public domain", sbomLicense{License: &sbomLicenseInfo{Name: "custom: public domain"}}},
+		{"(MIT AND", sbomLicense{License: &sbomLicenseInfo{Name: "(MIT AND"}}},
+	}
+
+	for _, c := range cases {
+		if l := packageLicense(c.license); !reflect.DeepEqual(l, c.expected) {
+			t.Errorf("%q: expected license %+v, found %+v", c.license, c.expected, l)
+		}
+	}
+}
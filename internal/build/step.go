@@ -0,0 +1,530 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ok-ryoko/turret/internal/container"
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux"
+	"github.com/ok-ryoko/turret/pkg/linux/find"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+	"github.com/ok-ryoko/turret/pkg/linux/user"
+
+	"github.com/sirupsen/logrus"
+)
+
+// step is a unit of work in the build pipeline that alters the working
+// container.
+type step struct {
+	// Gerund phrase describing the step, e.g., "upgrading packages"
+	description string
+
+	// Descriptions of the operations the step performs other than running
+	// processes in the working container
+	details []string
+
+	// Alter the working container
+	run func(c *container.Container) error
+
+	// Whether run does nothing but run processes in the working container and
+	// copy files through the Container, such that the processes can be
+	// recorded instead
+	recordable bool
+
+	// Describe the contents of the files on the host's file system that the
+	// step reads, e.g., by their digests; nil if the step reads no such files
+	hostInputs func() ([]string, error)
+}
+
+// Step describes a step of the build pipeline for display.
+type Step struct {
+	// Name of the stage whose working container the step alters; empty for
+	// the working container of the final image
+	Stage string
+
+	// Gerund phrase describing the step
+	Description string
+
+	// Processes the step runs in the working container, in order
+	Processes []container.Process
+
+	// Descriptions of the other operations the step performs
+	Details []string
+}
+
+// newSteps returns the steps of the build pipeline that alter the working
+// container in the order given by the spec, assuming the spec is valid.
+//
+// `stages` maps the name of each stage from which files may be copied to its
+// working container. The map is read only when the steps run, so it may be
+// populated afterwards.
+//
+// If `vendored` isn't nil, then its package files are installed in place of
+// the packages in the spec and the package index isn't updated.
+func newSteps(s spec.Spec, stages map[string]*container.Container, vendored *vendoredPackages) ([]step, error) {
+	pckgCmdFactory, err := newPackageCommandFactory(s)
+	if err != nil {
+		return nil, fmt.Errorf("creating package command factory: %w", err)
+	}
+
+	pckgFrontend, err := container.NewPackageFrontend(pckgCmdFactory)
+	if err != nil {
+		return nil, fmt.Errorf("creating package management interface: %w", err)
+	}
+
+	userFrontend, err := container.NewUserFrontend(s.Backends.User.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("creating user management interface: %w", err)
+	}
+
+	findCmdFactory, err := find.NewCommandFactory(s.Backends.Find.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("creating find command factory: %w", err)
+	}
+
+	installOptions := pckg.InstallOptions{
+		WeakDeps: s.Packages.WeakDeps,
+		Virtual:  s.Packages.Virtual,
+	}
+
+	var steps []step
+	for _, ref := range s.Steps {
+		switch a := ref.Action.Action; a {
+		case spec.ActionRepositories:
+			steps = append(steps, newRepositoriesStep(s.Packages.Repositories, pckgFrontend, pckgCmdFactory, vendored == nil))
+		case spec.ActionUpgrade:
+			mode := s.Packages.Upgrade.UpgradeMode
+			description := "upgrading packages"
+			if mode == spec.UpgradeSecurity {
+				description = "upgrading packages with security fixes"
+			}
+			steps = append(steps, step{
+				description: description,
+				run: func(c *container.Container) error {
+					return upgradePackages(c, pckgFrontend, mode)
+				},
+				recordable: true,
+			})
+		case spec.ActionInstall:
+			if vendored != nil {
+				steps = append(steps, newInstallFilesStep(vendored.files, pckgFrontend, true, installOptions))
+				continue
+			}
+			packages := pckg.Args(pckgCmdFactory, s.Packages.Install.Packages())
+			groups := s.Packages.Groups
+			steps = append(steps, step{
+				description: "installing packages",
+				run: func(c *container.Container) error {
+					return installPackages(c, pckgFrontend, packages, groups, installOptions)
+				},
+				recordable: true,
+			})
+		case spec.ActionInstallFiles:
+			// The virtual package depends only on the packages in
+			// packages.install
+			//
+			offline := vendored != nil || s.Packages.Local != nil
+			options := installOptions
+			options.Virtual = ""
+			steps = append(steps, newInstallFilesStep(s.Packages.InstallFiles, pckgFrontend, offline, options))
+		case spec.ActionRemove:
+			packages := s.Packages.Remove
+			steps = append(steps, step{
+				description: "removing packages",
+				run: func(c *container.Container) error {
+					return removePackages(c, pckgFrontend, packages)
+				},
+				recordable: true,
+			})
+		case spec.ActionClean:
+			steps = append(steps, step{
+				description: "cleaning package caches",
+				run: func(c *container.Container) error {
+					return cleanPackageCaches(c, pckgFrontend)
+				},
+				recordable: true,
+			})
+		case spec.ActionUser:
+			steps = append(steps, newUserStep(*s.User, userFrontend))
+		case spec.ActionCopy:
+			for _, i := range ref.Indices(len(s.Copy)) {
+				steps = append(steps, newCopyStep(s.Copy[i], stages))
+			}
+		case spec.ActionRun:
+			for _, i := range ref.Indices(len(s.Run)) {
+				steps = append(steps, newRunStep(s.Run[i]))
+			}
+		case spec.ActionRemoveS:
+			steps = append(steps, newRemoveSStep(s.Security.SpecialFiles.Excludes, findCmdFactory))
+		default:
+			return nil, fmt.Errorf("unsupported action %s", a)
+		}
+	}
+
+	return steps, nil
+}
+
+// newRepositoriesStep returns a step that configures package repositories in
+// the working container and then updates the package index if `updateIndex` is
+// true.
+func newRepositoriesStep(
+	repositories []spec.Repository,
+	pckgFrontend container.PackageFrontendInterface,
+	pckgCmdFactory pckg.CommandFactory,
+	updateIndex bool,
+) step {
+	var details []string
+	for _, r := range repositories {
+		if r.Key != "" {
+			details = append(details, fmt.Sprintf("copy key %s to %s", r.Key, pckgCmdFactory.KeyPath(r.Name, r.Key)))
+		}
+	}
+	return step{
+		description: "configuring package repositories",
+		details:     details,
+		run: func(c *container.Container) error {
+			return addRepositories(c, pckgFrontend, pckgCmdFactory, repositories, updateIndex)
+		},
+		recordable: true,
+	}
+}
+
+// newInstallFilesStep returns a step that installs the packages in one or more
+// package files on the host's file system to the working container, reading
+// no repository if `offline` is true.
+func newInstallFilesStep(
+	files []string,
+	pckgFrontend container.PackageFrontendInterface,
+	offline bool,
+	options pckg.InstallOptions,
+) step {
+	var details []string
+	if len(files) > 0 {
+		details = []string{fmt.Sprintf("copy %s to %s", strings.Join(files, ", "), packageFilesDir)}
+	}
+	return step{
+		description: "installing package files",
+		details:     details,
+		run: func(c *container.Container) error {
+			return installPackageFiles(c, pckgFrontend, files, offline, options)
+		},
+		recordable: true,
+		hostInputs: func() ([]string, error) {
+			return digestInputs(files)
+		},
+	}
+}
+
+// newUserStep returns a step that creates the sole unprivileged user of the
+// working container.
+func newUserStep(u spec.User, userFrontend container.UserFrontendInterface) step {
+	createUserOptions := user.Options{
+		ID:         u.ID,
+		UserGroup:  u.UserGroup,
+		Groups:     u.Groups,
+		Comment:    u.Comment,
+		CreateHome: u.CreateHome,
+	}
+	return step{
+		description: "creating nonroot user",
+		run: func(c *container.Container) error {
+			return createUser(c, userFrontend, u.Name, createUserOptions)
+		},
+		recordable: true,
+	}
+}
+
+// newCopyStep returns a step that copies files from the host's file system or
+// from the working container of a stage in `stages` to the working
+// container's file system.
+func newCopyStep(cp spec.Copy, stages map[string]*container.Container) step {
+	copyFilesOptions := copyFilesOptions{
+		excludes:      cp.Excludes,
+		mode:          cp.Mode,
+		owner:         cp.Owner,
+		removeSpecial: cp.RemoveS,
+	}
+
+	if cp.FromStage != "" {
+		name := cp.FromStage
+		return step{
+			description: fmt.Sprintf("copying files from stage %s", name),
+			details:     []string{describeCopy(fmt.Sprintf("%s in stage %s", cp.Base, name), cp.Destination, cp.Sources, copyFilesOptions)},
+			run: func(c *container.Container) error {
+				from, ok := stages[name]
+				if !ok {
+					return fmt.Errorf("stage %s hasn't been built", name)
+				}
+				return copyFilesFromContainer(c, from, cp.Base, cp.Destination, cp.Sources, copyFilesOptions)
+			},
+		}
+	}
+
+	return step{
+		description: "copying files",
+		details:     []string{describeCopy(cp.Base, cp.Destination, cp.Sources, copyFilesOptions)},
+		run: func(c *container.Container) error {
+			return copyFiles(c, cp.Base, cp.Destination, cp.Sources, copyFilesOptions)
+		},
+		hostInputs: func() ([]string, error) {
+			return copyInputs(cp)
+		},
+	}
+}
+
+// newRunStep returns a step that runs a command in the working container.
+func newRunStep(r spec.Run) step {
+	runCommandOptions := runCommandOptions{
+		capabilities: r.Capabilities,
+		env:          describeMap(r.Environment),
+		network:      r.Network,
+		user:         r.User,
+		workDir:      r.WorkDir,
+	}
+	return step{
+		description: fmt.Sprintf("running %s", r.Command[0]),
+		run: func(c *container.Container) error {
+			return runCommand(c, r.Command, runCommandOptions)
+		},
+		recordable: true,
+	}
+}
+
+// newRemoveSStep returns a step that removes the SUID and SGID bits from the
+// files in the working container that aren't in `excludes`.
+func newRemoveSStep(excludes []string, findCmdFactory find.CommandFactory) step {
+	details := []string{"unset the SUID and SGID bits on every file found"}
+	if len(excludes) > 0 {
+		details[0] += fmt.Sprintf(" except %s", strings.Join(excludes, ", "))
+	}
+	return step{
+		description: "removing SUID and SGID bits from files",
+		details:     details,
+		run: func(c *container.Container) error {
+			return unsetSpecialBits(c, findCmdFactory, excludes)
+		},
+		recordable: true,
+	}
+}
+
+// commonOptions returns the options for the execution of all processes in the
+// working container.
+func commonOptions(s spec.Spec, options ExecuteOptions) container.CommonOptions {
+	o := container.CommonOptions{LogCommands: options.LogCommands}
+	if s.From.Distro.Distro == linux.Debian {
+		o.Env = append(o.Env, "DEBIAN_FRONTEND=noninteractive")
+	}
+	if s.Packages.Local != nil {
+		o.Mounts = append(o.Mounts, bindMount(s.Packages.Local.Path, localRepositoryPath))
+		o.Offline = true
+	}
+	if options.Vendored != "" {
+		o.Offline = true
+	}
+	return o
+}
+
+// Plan walks the build pipeline without running anything, returning the steps
+// the pipeline would take. No container storage or runtime is needed.
+//
+// Processes whose arguments depend on the output of earlier processes, e.g.,
+// the chmod command that unsets special bits on the files found by a search,
+// are described in the details of their step instead.
+func Plan(s spec.Spec, logger *logrus.Logger, options ExecuteOptions) ([]Step, error) {
+	order, err := spec.StageOrder(s)
+	if err != nil {
+		return nil, fmt.Errorf("ordering stages: %w", err)
+	}
+
+	if options.Vendored != "" {
+		if err := checkVendoring(s); err != nil {
+			return nil, err
+		}
+	}
+
+	s = applyLock(s, options.Lock)
+
+	var plan []Step
+	for _, name := range order {
+		stagePlan, err := planSteps(s.Stages[name].Spec(), name, logger, options)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: %w", name, err)
+		}
+		plan = append(plan, stagePlan...)
+	}
+
+	finalPlan, err := planSteps(s, "", logger, options)
+	if err != nil {
+		return nil, err
+	}
+	plan = append(plan, finalPlan...)
+
+	if options.LockOnly {
+		return plan, nil
+	}
+
+	configureDetails := newConfigureOptions(s, options).describe()
+	if options.AnnotatePackageReport {
+		configureDetails = append(configureDetails, fmt.Sprintf("set annotation %s to the package report", packageReportKey))
+	}
+	if options.SBOM {
+		configureDetails = append(configureDetails, fmt.Sprintf("set annotation %s to the digest of the CycloneDX SBOM of the image", sbomDigestKey))
+	}
+	plan = append(plan, Step{
+		Description: "configuring image",
+		Details:     configureDetails,
+	})
+
+	plan = append(plan, Step{
+		Description: "committing image",
+		Details:     newCommitOptions(s, options).describe(s.This.Repository, s.This.Tag),
+	})
+
+	if dests := destinations(s, options); len(dests) > 0 {
+		plan = append(plan, Step{
+			Description: "copying image to destinations",
+			Details:     newPushOptions(s, options).describe(dests),
+		})
+	}
+
+	return plan, nil
+}
+
+// planSteps returns the steps that create and alter the working container
+// described by a spec, attributing them to the stage `stage`.
+func planSteps(s spec.Spec, stage string, logger *logrus.Logger, options ExecuteOptions) ([]Step, error) {
+	vendored, err := readVendored(options.Vendored, stage)
+	if err != nil {
+		return nil, fmt.Errorf("reading vendored packages: %w", err)
+	}
+
+	steps, err := newSteps(s, nil, vendored)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := container.Recorder{}
+	ctr := container.Container{
+		Logger:        logger,
+		CommonOptions: commonOptions(s, options),
+		Recorder:      &recorder,
+	}
+
+	details := []string{fmt.Sprintf("create %s Linux working container from image %s", s.From.Distro, s.From.Reference())}
+	if s.Packages.Local != nil {
+		details = append(details, describeLocalRepository(s))
+	}
+	if ctr.CommonOptions.Offline {
+		details = append(details, "disconnect every process from the network")
+	}
+
+	plan := make([]Step, 0, len(steps)+3)
+	plan = append(plan, Step{
+		Stage:       stage,
+		Description: "creating working container",
+		Details:     details,
+	})
+
+	// The packages of the final image are listed before and after the steps
+	// to report how the build changed them
+	//
+	if stage == "" {
+		if _, err := listPackages(&ctr, s); err != nil {
+			return nil, fmt.Errorf("listing pre-installed packages: %w", err)
+		}
+		plan = append(plan, Step{
+			Description: "listing pre-installed packages",
+			Processes:   recorder.Flush(),
+		})
+	}
+
+	layered := stage == "" && s.This.Layers.Layering == spec.PerStep
+	cached := s.Packages.Local == nil
+	for i, st := range steps {
+		planned := Step{
+			Stage:       stage,
+			Description: st.description,
+			Details:     st.details,
+		}
+		if st.recordable {
+			if err := st.run(&ctr); err != nil {
+				return nil, fmt.Errorf("%s: %w", st.description, err)
+			}
+			planned.Processes = recorder.Flush()
+		}
+		cached = cached && cacheable(st)
+		if cached {
+			planned.Details = append(planned.Details, describeCache(options.NoCache))
+		}
+		if layered && i < len(steps)-1 {
+			planned.Details = append(planned.Details, fmt.Sprintf("commit the changes as a layer created by %q", historyEntry(st)))
+		}
+		plan = append(plan, planned)
+	}
+
+	if _, err := listPackages(&ctr, s); err != nil {
+		return nil, fmt.Errorf("listing installed packages: %w", err)
+	}
+	listing := Step{
+		Stage:       stage,
+		Description: "listing installed packages",
+		Processes:   recorder.Flush(),
+	}
+	if options.Lock != nil {
+		listing.Details = append(listing.Details, "fail if the installed packages differ from the lock")
+	}
+	if stage == "" {
+		listing.Details = append(listing.Details, "report the packages added, removed and changed relative to the base image")
+	}
+	plan = append(plan, listing)
+
+	return plan, nil
+}
+
+// describeCache returns a human-readable description of the use of the build
+// cache for a cacheable step.
+func describeCache(noCache bool) string {
+	if noCache {
+		return fmt.Sprintf("cache the result of the step in %s", cacheRepository)
+	}
+	return fmt.Sprintf("reuse the result of the step cached in %s, or else cache it", cacheRepository)
+}
+
+// describeCopy returns a human-readable description of a copy operation.
+func describeCopy(base string, dest string, srcs []string, options copyFilesOptions) string {
+	d := fmt.Sprintf("copy %s from %s to %s", strings.Join(srcs, ", "), base, dest)
+	if len(options.excludes) > 0 {
+		d += fmt.Sprintf(" excluding %s", strings.Join(options.excludes, ", "))
+	}
+	if options.mode != 0 {
+		d += fmt.Sprintf(" with mode %o", options.mode)
+	}
+	if options.owner != "" {
+		d += fmt.Sprintf(" owned by %s", options.owner)
+	}
+	if options.removeSpecial {
+		d += " without SUID and SGID bits"
+	}
+	return d
+}
+
+// describeMap returns the entries of a map as "KEY=VALUE"s in lexicographic
+// order of keys.
+func describeMap(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, len(keys))
+	for i, k := range keys {
+		entries[i] = fmt.Sprintf("%s=%s", k, m[k])
+	}
+	return entries
+}
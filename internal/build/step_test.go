@@ -0,0 +1,55 @@
+package build
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ok-ryoko/turret/internal/container"
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPlanConditionalCapabilities(t *testing.T) {
+	s := spec.Fill(spec.Spec{
+		From: spec.From{
+			Repository: "registry.fedoraproject.org/fedora",
+			Tag:        "39",
+			Distro:     linux.DistroWrapper{Distro: linux.Fedora},
+		},
+		This: spec.This{
+			Repository: "localhost/example",
+			Tag:        "latest",
+		},
+		User: &spec.User{Name: "example"},
+	})
+
+	plan, err := Plan(s, logrus.New(), ExecuteOptions{})
+	if err != nil {
+		t.Fatalf("planning build: %v", err)
+	}
+
+	var processes []container.Process
+	for _, st := range plan {
+		if st.Description == "creating nonroot user" {
+			processes = st.Processes
+		}
+	}
+	if len(processes) != 2 {
+		t.Fatalf("expected a probe and useradd, found %+v", processes)
+	}
+
+	probe, useradd := processes[0], processes[1]
+	if !probe.Probe || !reflect.DeepEqual(probe.Cmd, []string{"command", "-v", "sss_cache"}) {
+		t.Errorf("expected a probe for sss_cache, found %+v", probe)
+	}
+	for _, c := range useradd.Capabilities {
+		if c == "CAP_SETGID" || c == "CAP_SETUID" {
+			t.Errorf("expected %s to be granted to useradd only if sss_cache is found", c)
+		}
+	}
+	if expected := []string{"CAP_SETGID", "CAP_SETUID"}; !reflect.DeepEqual(useradd.ConditionalCapabilities, expected) {
+		t.Errorf("expected conditional capabilities %v, found %v", expected, useradd.ConditionalCapabilities)
+	}
+}
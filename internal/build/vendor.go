@@ -0,0 +1,343 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package build
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ok-ryoko/turret/internal/container"
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+
+	"github.com/containers/storage"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Name of the file that records the SHA256 digest of every package file
+	// in a directory populated by Vendor, in the format of sha256sum
+	vendorManifestName string = "SHA256SUMS"
+
+	// Absolute path at which the directory to which Vendor downloads package
+	// files is mounted in the working container
+	vendorMountPath string = "/run/turret/vendor"
+
+	// Absolute path to the temporary directory in the working container to
+	// which package files are copied before installing them
+	packageFilesDir string = "/tmp/turret-packages"
+)
+
+// VendorOptions holds options for vendoring packages.
+type VendorOptions struct {
+	// Path to the directory on the host's file system to which to download
+	// package files; must be empty or not exist
+	Dir string
+
+	// Download the versions of packages recorded in this lock; when nil,
+	// packages are resolved freely
+	Lock *Lock
+
+	// Log the standard output of container processes
+	LogCommands bool
+
+	// Retrieve the base images only if they're not already in local storage
+	Pull bool
+}
+
+// vendoredPackages holds the package files that Vendor downloaded for a working
+// container.
+type vendoredPackages struct {
+	// Absolute paths to the package files on the host's file system
+	files []string
+}
+
+// Vendor resolves the packages that a spec and its stages install, including
+// their missing dependencies, in throwaway working containers and downloads
+// the package files to a directory on the host together with a manifest of
+// their SHA256 digests. A later build can install the packages from that
+// directory without network access by setting ExecuteOptions.Vendored.
+//
+// The package files of the final image are downloaded to the subdirectory this
+// and those of each stage to the subdirectory stages/NAME.
+func Vendor(ctx context.Context, s spec.Spec, logger *logrus.Logger, options VendorOptions) error {
+	if err := checkVendoring(s); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(options.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("%w", err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("directory %s isn't empty", options.Dir)
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer shutDownStore(store, logger)
+
+	order, err := spec.StageOrder(s)
+	if err != nil {
+		return fmt.Errorf("ordering stages: %w", err)
+	}
+
+	s = applyLock(s, options.Lock)
+
+	for _, name := range order {
+		logger.Debugf("vendoring packages of stage %s...", name)
+		dir := filepath.Join(options.Dir, vendorSubdir(name))
+		if err := vendorPackages(ctx, store, s.Stages[name].Spec(), dir, logger, options); err != nil {
+			return fmt.Errorf("stage %s: %w", name, err)
+		}
+	}
+
+	logger.Debugln("vendoring packages...")
+	return vendorPackages(ctx, store, s, filepath.Join(options.Dir, vendorSubdir("")), logger, options)
+}
+
+// vendorPackages downloads the files of the packages that a spec installs to
+// the directory `dir` on the host and records their digests in a manifest.
+func vendorPackages(
+	ctx context.Context,
+	store storage.Store,
+	s spec.Spec,
+	dir string,
+	logger *logrus.Logger,
+	options VendorOptions,
+) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if len(s.Packages.Install) > 0 {
+		ctr, err := newContainer(ctx, store, s, logger, ExecuteOptions{
+			LogCommands: options.LogCommands,
+			Pull:        options.Pull,
+		})
+		if err != nil {
+			return err
+		}
+		defer removeContainer(ctr, logger, false)
+
+		ctr.CommonOptions.Mounts = append(ctr.CommonOptions.Mounts, specs.Mount{
+			Destination: vendorMountPath,
+			Type:        "bind",
+			Source:      dir,
+			Options:     []string{"bind", "rw"},
+		})
+
+		pckgCmdFactory, err := newPackageCommandFactory(s)
+		if err != nil {
+			return fmt.Errorf("creating package command factory: %w", err)
+		}
+
+		pckgFrontend, err := container.NewPackageFrontend(pckgCmdFactory)
+		if err != nil {
+			return fmt.Errorf("creating package management interface: %w", err)
+		}
+
+		if len(s.Packages.Repositories) > 0 {
+			if err := addRepositories(ctr, pckgFrontend, pckgCmdFactory, s.Packages.Repositories, true); err != nil {
+				return fmt.Errorf("configuring package repositories: %w", err)
+			}
+		}
+
+		packages := pckg.Args(pckgCmdFactory, s.Packages.Install.Packages())
+		installOptions := pckg.InstallOptions{WeakDeps: s.Packages.WeakDeps}
+		if err := pckgFrontend.Download(ctr, packages, vendorMountPath, installOptions); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	if err := writeVendorManifest(dir, s.Backends.Package.Backend); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	return nil
+}
+
+// checkVendoring returns an error if a spec or one of its stages alters the
+// working container in a way that needs packages that Vendor doesn't download
+// or needs network access, which vendored builds disable for every process.
+func checkVendoring(s spec.Spec) error {
+	check := func(s spec.Spec) error {
+		p := s.Packages
+		if p.Upgrade.UpgradeMode != 0 {
+			return fmt.Errorf("upgrading packages needs network access")
+		}
+		if len(p.Groups) > 0 {
+			return fmt.Errorf("package groups can't be vendored")
+		}
+		if len(p.InstallFiles) > 0 {
+			return fmt.Errorf("the dependencies of package files can't be vendored")
+		}
+		if p.Local != nil {
+			return fmt.Errorf("packages can't be installed from both a local repository and vendored package files")
+		}
+		for i, r := range s.Run {
+			if r.Network {
+				return fmt.Errorf("run[%d]: network is unavailable when installing vendored packages", i)
+			}
+		}
+		return nil
+	}
+
+	if err := check(s); err != nil {
+		return fmt.Errorf("vendoring packages: %w", err)
+	}
+	for _, name := range sortedStageNames(s) {
+		if err := check(s.Stages[name].Spec()); err != nil {
+			return fmt.Errorf("vendoring packages of stage %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// sortedStageNames returns the names of the stages of a spec in lexicographic
+// order.
+func sortedStageNames(s spec.Spec) []string {
+	names := make([]string, 0, len(s.Stages))
+	for name := range s.Stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// vendorSubdir returns the path, relative to a directory populated by Vendor,
+// to the directory holding the package files of the stage `stage` (or of the
+// final image if `stage` is empty).
+func vendorSubdir(stage string) string {
+	if stage == "" {
+		return "this"
+	}
+	return filepath.Join("stages", stage)
+}
+
+// writeVendorManifest records the SHA256 digest of every package file under
+// the directory `dir` in a manifest in that directory, removing every other
+// file that the package manager left behind.
+func writeVendorManifest(dir string, b pckg.Backend) error {
+	var (
+		lines []string
+		dirs  []string
+	)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir {
+				dirs = append(dirs, p)
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !b.IsPackageFile(d.Name()) {
+			return os.Remove(p)
+		}
+
+		digest, err := digestFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s\n", digest, filepath.ToSlash(rel)))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	// Remove the directories that are now empty, deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if entries, err := os.ReadDir(dirs[i]); err == nil && len(entries) == 0 {
+			if err := os.Remove(dirs[i]); err != nil {
+				return fmt.Errorf("%w", err)
+			}
+		}
+	}
+
+	sort.Strings(lines)
+	p := filepath.Join(dir, vendorManifestName)
+	if err := os.WriteFile(p, []byte(strings.Join(lines, "")), 0o644); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// readVendored reads the manifest of the package files that Vendor downloaded
+// to the directory `dir` for the stage `stage` (or for the final image if
+// `stage` is empty), verifying the digest of every file. It returns nil if
+// `dir` is empty.
+func readVendored(dir, stage string) (*vendoredPackages, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	subdir := filepath.Join(dir, vendorSubdir(stage))
+	p := filepath.Join(subdir, vendorManifestName)
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer f.Close()
+
+	vendored := &vendoredPackages{files: []string{}}
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		digest, name, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok || !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("%s:%d: malformed line", p, n)
+		}
+
+		file := filepath.Join(subdir, filepath.FromSlash(name))
+		actual, err := digestFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		if actual != digest {
+			return nil, fmt.Errorf("%s has SHA256 digest %s, expected %s", file, actual, digest)
+		}
+
+		vendored.files = append(vendored.files, file)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", p, err)
+	}
+
+	return vendored, nil
+}
+
+// digestFile returns the hex-encoded SHA256 digest of the file at `p`.
+func digestFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("%w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("reading %s: %w", p, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
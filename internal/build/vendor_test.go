@@ -0,0 +1,103 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ok-ryoko/turret/internal/spec"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+)
+
+func TestVendorManifest(t *testing.T) {
+	dir := t.TempDir()
+	subdir := filepath.Join(dir, vendorSubdir(""))
+
+	files := map[string]string{
+		"curl_7.88.1-10_amd64.deb":     "curl",
+		"libcurl4_7.88.1-10_amd64.deb": "libcurl4",
+		"lock":                         "",
+		"partial/download":             "",
+	}
+	for name, contents := range files {
+		p := filepath.Join(subdir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("creating directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+			t.Fatalf("writing file: %v", err)
+		}
+	}
+
+	if err := writeVendorManifest(subdir, pckg.APT); err != nil {
+		t.Fatalf("writing manifest: %v", err)
+	}
+
+	for _, name := range []string{"lock", "partial"} {
+		if _, err := os.Stat(filepath.Join(subdir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", name)
+		}
+	}
+
+	vendored, err := readVendored(dir, "")
+	if err != nil {
+		t.Fatalf("reading manifest: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join(subdir, "curl_7.88.1-10_amd64.deb"),
+		filepath.Join(subdir, "libcurl4_7.88.1-10_amd64.deb"),
+	}
+	if strings.Join(vendored.files, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected files %v, found %v", expected, vendored.files)
+	}
+
+	if err := os.WriteFile(expected[0], []byte("tampered"), 0o644); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	if _, err := readVendored(dir, ""); err == nil {
+		t.Errorf("expected an error for a file whose digest differs from the manifest")
+	}
+
+	if vendored, err := readVendored("", ""); err != nil || vendored != nil {
+		t.Errorf("expected nothing for an empty directory path, found %v, %v", vendored, err)
+	}
+}
+
+func TestCheckVendoring(t *testing.T) {
+	cases := []struct {
+		description string
+		spec        spec.Spec
+		ok          bool
+	}{
+		{
+			"packages from repositories",
+			spec.Spec{Packages: spec.Packages{Install: spec.PackageList{"curl"}}},
+			true,
+		},
+		{
+			"package files",
+			spec.Spec{Packages: spec.Packages{InstallFiles: []string{"/srv/example.deb"}}},
+			false,
+		},
+		{
+			"process connected to the network",
+			spec.Spec{Run: []spec.Run{{Command: []string{"true"}, Network: true}}},
+			false,
+		},
+		{
+			"process of a stage connected to the network",
+			spec.Spec{Stages: map[string]spec.Stage{
+				"builder": {Run: []spec.Run{{Command: []string{"true"}, Network: true}}},
+			}},
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		if err := checkVendoring(c.spec); (err == nil) != c.ok {
+			t.Errorf("%s: expected success %t, found error %v", c.description, c.ok, err)
+		}
+	}
+}
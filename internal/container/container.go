@@ -6,9 +6,12 @@ package container
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"path"
 	"strings"
 
 	"github.com/containers/buildah"
+	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
 )
 
@@ -22,6 +25,76 @@ type Container struct {
 
 	// Common options for the execution of all container processes
 	CommonOptions CommonOptions
+
+	// Pointer to a recorder that, if not nil, collects the processes that
+	// would run in the working container in place of running them
+	Recorder *Recorder
+}
+
+// Recorder collects descriptions of container processes without running them.
+type Recorder struct {
+	// Processes recorded since the last flush, in order of execution
+	Processes []Process
+}
+
+// Flush returns the processes recorded since the last flush and forgets them.
+func (r *Recorder) Flush() []Process {
+	processes := r.Processes
+	r.Processes = nil
+	return processes
+}
+
+// markProbe marks the last recorded process as a probe.
+func (r *Recorder) markProbe() {
+	if n := len(r.Processes); n > 0 {
+		r.Processes[n-1].Probe = true
+	}
+}
+
+// markConditional records that the last recorded process is granted
+// `capabilities` only if `condition` holds.
+func (r *Recorder) markConditional(capabilities []string, condition string) {
+	if n := len(r.Processes); n > 0 {
+		r.Processes[n-1].ConditionalCapabilities = capabilities
+		r.Processes[n-1].Condition = condition
+	}
+}
+
+// Process describes a process that runs in the working container.
+type Process struct {
+	// Command line
+	Cmd []string
+
+	// Linux capabilities granted to the process
+	Capabilities []string
+
+	// Environment variables set for the process, represented as a slice of
+	// "KEY=VALUE"s
+	Env []string
+
+	// Connect the process to the network
+	Network bool
+
+	// User (and, optionally, group) as whom the process runs; root if empty
+	User string
+
+	// Directory in which the process runs; the default directory if empty
+	WorkDir string
+
+	// Data written to the standard input of the process
+	Stdin string
+
+	// Linux capabilities granted to the process in addition to Capabilities
+	// only if Condition holds
+	ConditionalCapabilities []string
+
+	// Condition, decided by the outcome of an earlier probe, under which the
+	// process is granted ConditionalCapabilities
+	Condition string
+
+	// Whether the process only probes the working container, its outcome
+	// deciding the Condition of a later process
+	Probe bool
 }
 
 // CommonOptions holds options for the execution of any container process.
@@ -32,6 +105,13 @@ type CommonOptions struct {
 
 	// Log the output and error streams of container processes
 	LogCommands bool
+
+	// Mounts to add to the working container when running a command
+	Mounts []specs.Mount
+
+	// Disconnect every process from the network, even processes whose run
+	// options enable the network
+	Offline bool
 }
 
 // ContainerID returns the ID of the working container.
@@ -51,6 +131,10 @@ func (c *Container) DefaultRunOptions() buildah.RunOptions {
 		ro.Env = append(ro.Env, c.CommonOptions.Env...)
 	}
 
+	if len(c.CommonOptions.Mounts) > 0 {
+		ro.Mounts = append(ro.Mounts, c.CommonOptions.Mounts...)
+	}
+
 	if c.CommonOptions.LogCommands {
 		ro.Logger = c.Logger
 		ro.Quiet = false
@@ -84,7 +168,37 @@ func (c *Container) ResolveExecutable(executable string) (string, error) {
 
 // Run executes a command in the working container, capturing standard output
 // and standard error streams as UTF-8-encoded strings.
+//
+// If the container has a recorder, then the process is recorded instead of
+// run and both streams are empty.
+//
+// If the container is offline, then the process is disconnected from the
+// network regardless of `options`.
 func (c *Container) Run(cmd []string, options buildah.RunOptions) (string, string, error) {
+	if c.CommonOptions.Offline {
+		options.ConfigureNetwork = buildah.NetworkDisabled
+	}
+
+	if c.Recorder != nil {
+		var stdin []byte
+		if options.Stdin != nil {
+			var err error
+			if stdin, err = io.ReadAll(options.Stdin); err != nil {
+				return "", "", fmt.Errorf("reading standard input: %w", err)
+			}
+		}
+		c.Recorder.Processes = append(c.Recorder.Processes, Process{
+			Cmd:          cmd,
+			Capabilities: options.AddCapabilities,
+			Env:          options.Env,
+			Network:      options.ConfigureNetwork == buildah.NetworkEnabled,
+			User:         options.User,
+			WorkDir:      options.WorkingDir,
+			Stdin:        string(stdin),
+		})
+		return "", "", nil
+	}
+
 	var (
 		stdoutBuf bytes.Buffer
 		stderrBuf bytes.Buffer
@@ -110,6 +224,63 @@ func (c *Container) Run(cmd []string, options buildah.RunOptions) (string, strin
 	return outText, errText, nil
 }
 
+// AddFiles copies one or more files from the host's file system to the
+// directory at the absolute path `dest` in the working container, creating the
+// directory as needed.
+//
+// If the container has a recorder, then nothing is copied.
+func (c *Container) AddFiles(dest string, files []string) error {
+	if c.Recorder != nil {
+		return nil
+	}
+	if err := c.Builder.Add(strings.TrimSuffix(dest, "/")+"/", false, buildah.AddAndCopyOptions{}, files...); err != nil {
+		return fmt.Errorf("copying files to %s: %w", dest, err)
+	}
+	return nil
+}
+
+// RemoveAll removes the file or directory at the absolute path `p` in the
+// working container together with its contents.
+func (c *Container) RemoveAll(p string) error {
+	ro := c.DefaultRunOptions()
+	ro.AddCapabilities = []string{"CAP_DAC_OVERRIDE"}
+	cmd := []string{"rm", "-rf", p}
+	if err := c.runWithLogging(cmd, ro, fmt.Sprintf("removing %s", p)); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
+
+// WriteFile writes `contents` to the file at the absolute path `p` in the
+// working container, creating its parent directories as needed, and appends
+// to the file instead of replacing it if `appending` is true.
+func (c *Container) WriteFile(p, contents string, appending bool) error {
+	ro := c.DefaultRunOptions()
+	ro.AddCapabilities = []string{"CAP_DAC_OVERRIDE"}
+
+	cmd := []string{"mkdir", "-p", path.Dir(p)}
+	if err := c.runWithLogging(cmd, ro, fmt.Sprintf("creating parent directory of %s", p)); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	cmd = []string{"tee"}
+	if appending {
+		cmd = append(cmd, "-a")
+	}
+	cmd = append(cmd, p)
+
+	ro.Stdin = strings.NewReader(contents)
+	if _, errText, err := c.Run(cmd, ro); err != nil {
+		errContext := fmt.Sprintf("writing %s", p)
+		if errText != "" {
+			errContext = fmt.Sprintf("%s (%q)", errContext, errText)
+		}
+		return fmt.Errorf("%s: %w", errContext, err)
+	}
+
+	return nil
+}
+
 // runWithLogging wraps Run, logging standard output and standard error.
 func (c *Container) runWithLogging(cmd []string, options buildah.RunOptions, errContext string) error {
 	outText, errText, err := c.Run(cmd, options)
@@ -14,17 +14,44 @@ import (
 // PackageFrontendInterface is the interface implemented by a PackageFrontend
 // for a particular package manager.
 type PackageFrontendInterface interface {
+	// AddRepository configures a package repository in the working container.
+	AddRepository(c *Container, r pckg.Repository) error
+
 	// CleanCaches cleans the package caches in the working container.
 	CleanCaches(c *Container) error
 
+	// Download downloads to the directory `dir` in the working container the
+	// files of the packages that installing one or more packages would
+	// install.
+	Download(c *Container, packages []string, dir string, options pckg.InstallOptions) error
+
 	// Install installs one or more packages to the working container.
-	Install(c *Container, packages []string) error
+	Install(c *Container, packages []string, options pckg.InstallOptions) error
+
+	// InstallFiles installs the packages in one or more package files in the
+	// working container, reading no repository if `offline` is true.
+	InstallFiles(c *Container, files []string, offline bool, options pckg.InstallOptions) error
+
+	// InstallGroups installs one or more package groups to the working
+	// container.
+	InstallGroups(c *Container, groups []string, options pckg.InstallOptions) error
 
 	// List lists the packages installed in the working container.
-	List(c *Container) ([]string, error)
+	List(c *Container) ([]pckg.Package, error)
+
+	// Remove removes one or more packages from the working container together
+	// with the dependencies that no other package needs.
+	Remove(c *Container, packages []string) error
+
+	// UpdateIndex updates the package index in the working container.
+	UpdateIndex(c *Container) error
 
 	// Upgrade upgrades the packages in the working container.
 	Upgrade(c *Container) error
+
+	// UpgradeSecurity upgrades only the packages in the working container
+	// that have security fixes.
+	UpgradeSecurity(c *Container) error
 }
 
 // PackageFrontend provides a high-level frontend for Buildah for managing
@@ -33,6 +60,28 @@ type PackageFrontend struct {
 	pckg.CommandFactory
 }
 
+// AddRepository configures a package repository in the working container.
+func (f *PackageFrontend) AddRepository(c *Container, r pckg.Repository) error {
+	files, cmds, capabilities := f.NewAddRepositoryCmds(r)
+
+	for _, file := range files {
+		if err := c.WriteFile(file.Path, file.Contents, file.Append); err != nil {
+			return fmt.Errorf("configuring %s repository %s: %w", f.Backend(), r.Name, err)
+		}
+	}
+
+	for _, cmd := range cmds {
+		ro := c.DefaultRunOptions()
+		ro.AddCapabilities = capabilities
+		errContext := fmt.Sprintf("configuring %s repository %s", f.Backend(), r.Name)
+		if err := c.runWithLogging(cmd, ro, errContext); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	return nil
+}
+
 // CleanCaches cleans the package caches in the working container.
 func (f *PackageFrontend) CleanCaches(c *Container) error {
 	cmd, capabilities := f.NewCleanCacheCmd()
@@ -45,9 +94,25 @@ func (f *PackageFrontend) CleanCaches(c *Container) error {
 	return nil
 }
 
+// Download downloads to the directory `dir` in the working container the files
+// of the packages that installing one or more packages would install.
+func (f *PackageFrontend) Download(c *Container, packages []string, dir string, options pckg.InstallOptions) error {
+	cmds, capabilities := f.NewDownloadCmds(packages, dir, options)
+	for _, cmd := range cmds {
+		ro := c.DefaultRunOptions()
+		ro.AddCapabilities = capabilities
+		ro.ConfigureNetwork = buildah.NetworkEnabled
+		errContext := fmt.Sprintf("downloading %s packages", f.Backend())
+		if err := c.runWithLogging(cmd, ro, errContext); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+	return nil
+}
+
 // Install installs one or more packages to the working container.
-func (f *PackageFrontend) Install(c *Container, packages []string) error {
-	cmd, capabilities := f.NewInstallCmd(packages)
+func (f *PackageFrontend) Install(c *Container, packages []string, options pckg.InstallOptions) error {
+	cmd, capabilities := f.NewInstallCmd(packages, options)
 	ro := c.DefaultRunOptions()
 	ro.AddCapabilities = capabilities
 	ro.ConfigureNetwork = buildah.NetworkEnabled
@@ -58,8 +123,43 @@ func (f *PackageFrontend) Install(c *Container, packages []string) error {
 	return nil
 }
 
+// InstallFiles installs the packages in one or more package files in the
+// working container, reading no repository if `offline` is true.
+func (f *PackageFrontend) InstallFiles(c *Container, files []string, offline bool, options pckg.InstallOptions) error {
+	cmds, capabilities := f.NewInstallFilesCmds(files, offline, options)
+	for _, cmd := range cmds {
+		ro := c.DefaultRunOptions()
+		ro.AddCapabilities = capabilities
+		if !offline {
+			ro.ConfigureNetwork = buildah.NetworkEnabled
+		}
+		errContext := fmt.Sprintf("installing %s package files", f.Backend())
+		if err := c.runWithLogging(cmd, ro, errContext); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+	return nil
+}
+
+// InstallGroups installs one or more package groups to the working container.
+// It does nothing if the package manager has no package groups.
+func (f *PackageFrontend) InstallGroups(c *Container, groups []string, options pckg.InstallOptions) error {
+	cmd, capabilities := f.NewInstallGroupsCmd(groups, options)
+	if len(cmd) == 0 {
+		return nil
+	}
+	ro := c.DefaultRunOptions()
+	ro.AddCapabilities = capabilities
+	ro.ConfigureNetwork = buildah.NetworkEnabled
+	errContext := fmt.Sprintf("installing %s package groups", f.Backend())
+	if err := c.runWithLogging(cmd, ro, errContext); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
+
 // List lists the packages installed in the working container.
-func (f *PackageFrontend) List(c *Container) ([]string, error) {
+func (f *PackageFrontend) List(c *Container) ([]pckg.Package, error) {
 	cmd, capabilities, parse := f.NewListInstalledPackagesCmd()
 
 	ro := c.DefaultRunOptions()
@@ -74,7 +174,12 @@ func (f *PackageFrontend) List(c *Container) ([]string, error) {
 		return nil, fmt.Errorf("%s: %w", errContext, err)
 	}
 
-	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(outText), "\r\n", "\n"), "\n")
+	outText = strings.TrimSpace(outText)
+	if outText == "" {
+		return []pckg.Package{}, nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(outText, "\r\n", "\n"), "\n")
 	packages, err := parse(lines)
 	if err != nil {
 		return nil, fmt.Errorf("parsing installed packages: %w", err)
@@ -83,6 +188,37 @@ func (f *PackageFrontend) List(c *Container) ([]string, error) {
 	return packages, nil
 }
 
+// Remove removes one or more packages from the working container together
+// with the dependencies that no other package needs.
+func (f *PackageFrontend) Remove(c *Container, packages []string) error {
+	cmd, capabilities := f.NewRemoveCmd(packages)
+	ro := c.DefaultRunOptions()
+	ro.AddCapabilities = capabilities
+	errContext := fmt.Sprintf("removing %s packages", f.Backend())
+	if err := c.runWithLogging(cmd, ro, errContext); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
+
+// UpdateIndex updates the package index in the working container. It does
+// nothing if the package manager updates its index whenever it installs or
+// upgrades packages.
+func (f *PackageFrontend) UpdateIndex(c *Container) error {
+	cmd, capabilities := f.NewUpdateIndexCmd()
+	if len(cmd) == 0 {
+		return nil
+	}
+	ro := c.DefaultRunOptions()
+	ro.AddCapabilities = capabilities
+	ro.ConfigureNetwork = buildah.NetworkEnabled
+	errContext := fmt.Sprintf("updating %s package index", f.Backend())
+	if err := c.runWithLogging(cmd, ro, errContext); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
+
 // Upgrade upgrades the packages in the working container.
 func (f *PackageFrontend) Upgrade(c *Container) error {
 	cmd, capabilities := f.NewUpgradeCmd()
@@ -96,15 +232,53 @@ func (f *PackageFrontend) Upgrade(c *Container) error {
 	return nil
 }
 
-// NewPackageFrontend creates a frontend for a particular package manager.
-func NewPackageFrontend(backend pckg.Backend) (PackageFrontendInterface, error) {
-	factory, err := pckg.NewCommandFactory(backend)
-	if err != nil {
-		return nil, fmt.Errorf("%w", err)
+// UpgradeSecurity upgrades only the packages in the working container that have
+// security fixes. It returns an error if the package manager has no notion of
+// security fixes.
+func (f *PackageFrontend) UpgradeSecurity(c *Container) error {
+	files, cmds, capabilities := f.NewSecurityUpgradeCmds()
+	if len(cmds) == 0 {
+		return fmt.Errorf("%s doesn't support security-only upgrades", f.Backend())
+	}
+
+	for _, file := range files {
+		if err := c.WriteFile(file.Path, file.Contents, file.Append); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	var errUpgrade error
+	for _, cmd := range cmds {
+		ro := c.DefaultRunOptions()
+		ro.AddCapabilities = capabilities
+		ro.ConfigureNetwork = buildah.NetworkEnabled
+		errContext := fmt.Sprintf("upgrading pre-installed %s packages with security fixes", f.Backend())
+		if errUpgrade = c.runWithLogging(cmd, ro, errContext); errUpgrade != nil {
+			break
+		}
+	}
+
+	for _, file := range files {
+		if err := c.RemoveAll(file.Path); err != nil {
+			if errUpgrade != nil {
+				c.Logger.Warnf("failed removing %s", file.Path)
+			} else {
+				return fmt.Errorf("%w", err)
+			}
+		}
 	}
 
+	if errUpgrade != nil {
+		return fmt.Errorf("%w", errUpgrade)
+	}
+	return nil
+}
+
+// NewPackageFrontend creates a frontend for the package manager whose commands
+// `factory` creates.
+func NewPackageFrontend(factory pckg.CommandFactory) (PackageFrontendInterface, error) {
 	var result PackageFrontendInterface
-	switch backend {
+	switch backend := factory.Backend(); backend {
 	case pckg.APT:
 		result = &APTPackageFrontend{PackageFrontend{factory}}
 	case
@@ -7,13 +7,14 @@ import (
 	"fmt"
 
 	"github.com/containers/buildah"
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
 )
 
 type APTPackageFrontend struct {
 	PackageFrontend
 }
 
-func (f *APTPackageFrontend) Install(c *Container, packages []string) error {
+func (f *APTPackageFrontend) Install(c *Container, packages []string, options pckg.InstallOptions) error {
 	{
 		cmd, capabilities := f.NewUpdateIndexCmd()
 		ro := c.DefaultRunOptions()
@@ -26,7 +27,7 @@ func (f *APTPackageFrontend) Install(c *Container, packages []string) error {
 	}
 
 	{
-		cmd, capabilities := f.NewInstallCmd(packages)
+		cmd, capabilities := f.NewInstallCmd(packages, options)
 		ro := c.DefaultRunOptions()
 		ro.AddCapabilities = capabilities
 		ro.ConfigureNetwork = buildah.NetworkEnabled
@@ -39,6 +40,32 @@ func (f *APTPackageFrontend) Install(c *Container, packages []string) error {
 	return nil
 }
 
+func (f *APTPackageFrontend) InstallGroups(c *Container, groups []string, options pckg.InstallOptions) error {
+	{
+		cmd, capabilities := f.NewUpdateIndexCmd()
+		ro := c.DefaultRunOptions()
+		ro.AddCapabilities = capabilities
+		ro.ConfigureNetwork = buildah.NetworkEnabled
+		errContext := fmt.Sprintf("updating %s package index", f.Backend())
+		if err := c.runWithLogging(cmd, ro, errContext); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	{
+		cmd, capabilities := f.NewInstallGroupsCmd(groups, options)
+		ro := c.DefaultRunOptions()
+		ro.AddCapabilities = capabilities
+		ro.ConfigureNetwork = buildah.NetworkEnabled
+		errContext := fmt.Sprintf("installing %s package groups", f.Backend())
+		if err := c.runWithLogging(cmd, ro, errContext); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	return nil
+}
+
 func (f *APTPackageFrontend) Upgrade(c *Container) error {
 	{
 		cmd, capabilities := f.NewUpdateIndexCmd()
@@ -64,3 +91,22 @@ func (f *APTPackageFrontend) Upgrade(c *Container) error {
 
 	return nil
 }
+
+func (f *APTPackageFrontend) UpgradeSecurity(c *Container) error {
+	cmd, capabilities := f.NewUpdateIndexCmd()
+	ro := c.DefaultRunOptions()
+	ro.AddCapabilities = capabilities
+	ro.ConfigureNetwork = buildah.NetworkEnabled
+	errContext := fmt.Sprintf("updating %s package index", f.Backend())
+	if err := c.runWithLogging(cmd, ro, errContext); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return f.PackageFrontend.UpgradeSecurity(c)
+}
+
+// UpdateIndex does nothing because the frontend updates the package index
+// whenever it installs or upgrades packages.
+func (f *APTPackageFrontend) UpdateIndex(c *Container) error {
+	return nil
+}
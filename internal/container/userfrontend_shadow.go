@@ -22,26 +22,35 @@ func (f *ShadowUserFrontend) CreateUser(c *Container, name string, options user.
 	// sss_cache to invalidate the System Security Services Daemon cache,
 	// an operation that requires additional capabilities.
 	//
+	// When recording, the outcome of the probe isn't known, so the
+	// additional capabilities are recorded as conditional instead.
+	//
+	sssCacheCapabilities := []string{
+		"CAP_SETGID",
+		//
+		// Set the effective GID to 0 (root)
+
+		"CAP_SETUID",
+		//
+		// Set the effective UID to 0 (root)
+	}
 	_, err := c.ResolveExecutable("sss_cache")
-	if err != nil {
+	switch {
+	case c.Recorder != nil:
+		c.Recorder.markProbe()
+	case err != nil:
 		c.Logger.Debugln("sss_cache not found; skipping cache invalidation")
-	} else {
-		ro.AddCapabilities = append(
-			ro.AddCapabilities,
-			"CAP_SETGID",
-			//
-			// Set the effective GID to 0 (root)
-
-			"CAP_SETUID",
-			//
-			// Set the effective UID to 0 (root)
-		)
+	default:
+		ro.AddCapabilities = append(ro.AddCapabilities, sssCacheCapabilities...)
 	}
 
 	errContext := fmt.Sprintf("creating user using %s", f.Backend())
 	if err := c.runWithLogging(cmd, ro, errContext); err != nil {
 		return fmt.Errorf("%w", err)
 	}
+	if c.Recorder != nil {
+		c.Recorder.markConditional(sssCacheCapabilities, "sss_cache is found")
+	}
 
 	return nil
 }
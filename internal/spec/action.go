@@ -0,0 +1,136 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ActionRepositories Action = 1 << iota
+	ActionUpgrade
+	ActionInstall
+	ActionInstallFiles
+	ActionRemove
+	ActionClean
+	ActionUser
+	ActionCopy
+	ActionRun
+	ActionRemoveS
+)
+
+// Action is a unique identifier for a kind of alteration that a spec can make
+// to the working container. The zero value represents an unknown action.
+type Action uint
+
+// String returns a string containing the stylized name of the action.
+func (a Action) String() string {
+	var s string
+	switch a {
+	case ActionRepositories:
+		s = "repositories"
+	case ActionUpgrade:
+		s = "upgrade"
+	case ActionInstall:
+		s = "install"
+	case ActionInstallFiles:
+		s = "install-files"
+	case ActionRemove:
+		s = "remove"
+	case ActionClean:
+		s = "clean"
+	case ActionUser:
+		s = "user"
+	case ActionCopy:
+		s = "copy"
+	case ActionRun:
+		s = "run"
+	case ActionRemoveS:
+		s = "remove-s"
+	default:
+		s = "unknown"
+	}
+	return s
+}
+
+// Field returns the path to the field of a spec that enables the action.
+func (a Action) Field() string {
+	var s string
+	switch a {
+	case ActionRepositories:
+		s = "packages.repositories"
+	case ActionUpgrade:
+		s = "packages.upgrade"
+	case ActionInstall:
+		s = "packages.install"
+	case ActionInstallFiles:
+		s = "packages.install-files"
+	case ActionRemove:
+		s = "packages.remove"
+	case ActionClean:
+		s = "packages.clean"
+	case ActionUser:
+		s = "user"
+	case ActionCopy:
+		s = "copy"
+	case ActionRun:
+		s = "run"
+	case ActionRemoveS:
+		s = "security.special-files.remove-s"
+	}
+	return s
+}
+
+// Indexed returns true if the action refers to the elements of an array of
+// tables in a spec.
+func (a Action) Indexed() bool {
+	return a == ActionCopy || a == ActionRun
+}
+
+// ActionWrapper wraps Action to facilitate its parsing from serialized data.
+type ActionWrapper struct {
+	Action
+}
+
+// UnmarshalText decodes the action from a UTF-8-encoded string.
+func (w *ActionWrapper) UnmarshalText(text []byte) error {
+	var err error
+	w.Action, err = parseActionString(string(text))
+	return err
+}
+
+// Enum returns the identifiers from which the action can be decoded.
+func (w ActionWrapper) Enum() []string {
+	return []string{"repositories", "upgrade", "install", "install-files", "remove", "clean", "user", "copy", "run", "remove-s"}
+}
+
+func parseActionString(s string) (Action, error) {
+	var a Action
+	switch strings.ToLower(s) {
+	case "repositories":
+		a = ActionRepositories
+	case "upgrade":
+		a = ActionUpgrade
+	case "install":
+		a = ActionInstall
+	case "install-files":
+		a = ActionInstallFiles
+	case "remove":
+		a = ActionRemove
+	case "clean":
+		a = ActionClean
+	case "user":
+		a = ActionUser
+	case "copy":
+		a = ActionCopy
+	case "run":
+		a = ActionRun
+	case "remove-s":
+		a = ActionRemoveS
+	default:
+		return 0, fmt.Errorf("unsupported action %q", s)
+	}
+	return a, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	reArgName      = regexp.MustCompile(`^[A-Za-z_][0-9A-Za-z_]*$`)
+	reArgReference = regexp.MustCompile(`\$\{([^}]*)\}`)
+)
+
+// Expand replaces each reference of the form ${KEY} in the string fields of a
+// spec with the value of the build argument KEY, returning a ValidationErrors
+// naming every field with a malformed reference or a reference to an argument
+// that isn't defined in `s.Args`.
+func Expand(s Spec) (Spec, error) {
+	e := expander{args: s.Args}
+
+	for _, k := range sortedKeys(s.Args) {
+		if !reArgName.MatchString(k) {
+			e.errs.add("args."+fieldKey(k), k, "invalid build argument name %q", k)
+		}
+	}
+
+	s = e.expandStage(s)
+
+	s.This.Repository = e.expand("this.repository", s.This.Repository)
+	s.This.Tag = e.expand("this.tag", s.This.Tag)
+	s.This.Destinations = e.expandSlice("this.destinations", s.This.Destinations)
+
+	s.Config.Annotations = e.expandMap("config.annotations", s.Config.Annotations)
+	s.Config.Author = e.expand("config.author", s.Config.Author)
+	s.Config.Command = e.expandSlice("config.cmd", s.Config.Command)
+	s.Config.CreatedBy = e.expand("config.created-by", s.Config.CreatedBy)
+	s.Config.Entrypoint = e.expandSlice("config.ep", s.Config.Entrypoint)
+	s.Config.Environment = e.expandMap("config.env", s.Config.Environment)
+	s.Config.Labels = e.expandMap("config.labels", s.Config.Labels)
+	s.Config.WorkDir = e.expand("config.work-dir", s.Config.WorkDir)
+
+	for _, name := range sortedKeys(s.Stages) {
+		se := expander{args: s.Args, prefix: "stages." + fieldKey(name) + "."}
+		s.Stages[name] = stageOf(se.expandStage(s.Stages[name].Spec()))
+		e.errs = append(e.errs, se.errs...)
+	}
+
+	if err := e.errs.err(); err != nil {
+		return Spec{}, err
+	}
+	return s, nil
+}
+
+// ExpandReferences replaces each well-formed reference of the form ${KEY} in
+// `text` with the value of KEY in `args`, leaving malformed references and
+// references to undefined arguments in place for Expand to report.
+func ExpandReferences(text string, args map[string]string) string {
+	return reArgReference.ReplaceAllStringFunc(text, func(ref string) string {
+		name := reArgReference.FindStringSubmatch(ref)[1]
+		if v, ok := args[name]; ok && reArgName.MatchString(name) {
+			return v
+		}
+		return ref
+	})
+}
+
+// expander substitutes build arguments into strings, collecting a validation
+// error for each reference it can't resolve.
+type expander struct {
+	args map[string]string
+	errs ValidationErrors
+
+	// Prefix for the paths of the fields in which references are found
+	prefix string
+}
+
+// expandStage expands the references in the fields of a spec that describe
+// the alteration of a working container.
+func (e *expander) expandStage(s Spec) Spec {
+	s.From.Repository = e.expand("from.repository", s.From.Repository)
+	s.From.Tag = e.expand("from.tag", s.From.Tag)
+	s.From.Digest = e.expand("from.digest", s.From.Digest)
+
+	for i, r := range s.Packages.Repositories {
+		prefix := fmt.Sprintf("packages.repositories[%d]", i)
+		s.Packages.Repositories[i].URL = e.expand(prefix+".url", r.URL)
+		s.Packages.Repositories[i].Suite = e.expand(prefix+".suite", r.Suite)
+		s.Packages.Repositories[i].Components = e.expandSlice(prefix+".components", r.Components)
+		s.Packages.Repositories[i].Key = e.expand(prefix+".key", r.Key)
+	}
+
+	if s.Packages.Local != nil {
+		local := *s.Packages.Local
+		local.Path = e.expand("packages.local.path", local.Path)
+		s.Packages.Local = &local
+	}
+
+	s.Packages.Install = e.expandPackages("packages.install", s.Packages.Install)
+	s.Packages.Groups = e.expandSlice("packages.groups", s.Packages.Groups)
+	s.Packages.Virtual = e.expand("packages.virtual", s.Packages.Virtual)
+	s.Packages.InstallFiles = e.expandSlice("packages.install-files", s.Packages.InstallFiles)
+	s.Packages.Remove = e.expandSlice("packages.remove", s.Packages.Remove)
+
+	for i, c := range s.Copy {
+		prefix := fmt.Sprintf("copy[%d]", i)
+		s.Copy[i].Base = e.expand(prefix+".base", c.Base)
+		s.Copy[i].Destination = e.expand(prefix+".dest", c.Destination)
+		s.Copy[i].Sources = e.expandSlice(prefix+".srcs", c.Sources)
+		s.Copy[i].Excludes = e.expandSlice(prefix+".excludes", c.Excludes)
+		s.Copy[i].Owner = e.expand(prefix+".owner", c.Owner)
+	}
+
+	for i, r := range s.Run {
+		prefix := fmt.Sprintf("run[%d]", i)
+		s.Run[i].Command = e.expandSlice(prefix+".cmd", r.Command)
+		s.Run[i].Environment = e.expandMap(prefix+".env", r.Environment)
+		s.Run[i].User = e.expand(prefix+".user", r.User)
+		s.Run[i].WorkDir = e.expand(prefix+".work-dir", r.WorkDir)
+	}
+
+	return s
+}
+
+func (e *expander) expand(field, text string) string {
+	return reArgReference.ReplaceAllStringFunc(text, func(ref string) string {
+		name := reArgReference.FindStringSubmatch(ref)[1]
+		if !reArgName.MatchString(name) {
+			e.errs.add(e.prefix+field, text, "invalid build argument reference %q", ref)
+			return ref
+		}
+		v, ok := e.args[name]
+		if !ok {
+			e.errs.add(e.prefix+field, text, "undefined build argument %q", name)
+			return ref
+		}
+		return v
+	})
+}
+
+func (e *expander) expandSlice(field string, texts []string) []string {
+	for i, t := range texts {
+		texts[i] = e.expand(fmt.Sprintf("%s[%d]", field, i), t)
+	}
+	return texts
+}
+
+func (e *expander) expandPackages(field string, l PackageList) PackageList {
+	for i, v := range l {
+		prefix := fmt.Sprintf("%s[%d]", field, i)
+		switch t := v.(type) {
+		case string:
+			l[i] = e.expand(prefix, t)
+		case map[string]any:
+			for _, k := range sortedKeys(t) {
+				if text, ok := t[k].(string); ok {
+					t[k] = e.expand(prefix+"."+fieldKey(k), text)
+				}
+			}
+		}
+	}
+	return l
+}
+
+func (e *expander) expandMap(field string, m map[string]string) map[string]string {
+	for _, k := range sortedKeys(m) {
+		m[k] = e.expand(field+"."+fieldKey(k), m[k])
+	}
+	return m
+}
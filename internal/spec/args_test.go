@@ -0,0 +1,61 @@
+package spec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	s := Spec{
+		Args: map[string]string{
+			"VERSION":  "1.2.3",
+			"REVISION": "0123abc",
+		},
+		This: This{
+			Repository: "localhost/example",
+			Tag:        "${VERSION}",
+		},
+		Packages: Packages{
+			Install: PackageList{"example-${VERSION}"},
+		},
+		Config: Configuration{
+			Labels: map[string]string{
+				"org.opencontainers.image.revision": "${REVISION}",
+			},
+		},
+	}
+
+	actual, err := Expand(s)
+	if err != nil {
+		t.Fatalf("expanding build arguments: %v", err)
+	}
+
+	if actual.This.Tag != "1.2.3" {
+		t.Errorf("expected tag 1.2.3, found %s", actual.This.Tag)
+	}
+
+	if actual.Packages.Install[0] != "example-1.2.3" {
+		t.Errorf("expected package example-1.2.3, found %s", actual.Packages.Install[0])
+	}
+
+	if v := actual.Config.Labels["org.opencontainers.image.revision"]; v != "0123abc" {
+		t.Errorf("expected revision label 0123abc, found %s", v)
+	}
+}
+
+func TestExpandUndefined(t *testing.T) {
+	s := Spec{
+		Copy: []Copy{
+			{Destination: "/opt/${PREFIX}"},
+		},
+	}
+
+	_, err := Expand(s)
+	if err == nil {
+		t.Fatalf("expected error for undefined build argument")
+	}
+
+	if !strings.Contains(err.Error(), "copy[0].dest") {
+		t.Errorf("expected error to name field copy[0].dest, found %q", err)
+	}
+}
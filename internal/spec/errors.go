@@ -0,0 +1,69 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var reBareKey = regexp.MustCompile(`^[-0-9A-Z_a-z]+$`)
+
+// ValidationError describes a field in a spec whose value doesn't satisfy a
+// domain-specific constraint.
+type ValidationError struct {
+	// Path to the field using TOML key notation, e.g., copy[2].owner
+	Field string
+
+	// Offending value
+	Value any
+
+	// Description of the violated constraint
+	Message string
+}
+
+// Error returns a string representation of the validation error.
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors holds every validation error found in a spec.
+type ValidationErrors []ValidationError
+
+// Error returns a string representation of all validation errors.
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, v := range e {
+		messages[i] = v.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add appends a validation error for the field at `path`.
+func (e *ValidationErrors) add(path string, value any, format string, a ...any) {
+	*e = append(*e, ValidationError{
+		Field:   path,
+		Value:   value,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// err returns nil if there are no validation errors and the validation errors
+// otherwise.
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// fieldKey returns a table key as it would appear in a field path, quoting it
+// if it's not a bare key.
+func fieldKey(k string) string {
+	if reBareKey.MatchString(k) {
+		return k
+	}
+	return fmt.Sprintf("%q", k)
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	Gzip Compression = 1 << iota
+	Zstd
+	ZstdChunked
+	Uncompressed
+)
+
+// Compression is a unique identifier for a way of compressing the layers of
+// an image. The zero value represents the default, gzip.
+type Compression uint
+
+// String returns a string containing the name of the compression algorithm.
+func (c Compression) String() string {
+	var s string
+	switch c {
+	case 0, Gzip:
+		s = "gzip"
+	case Zstd:
+		s = "zstd"
+	case ZstdChunked:
+		s = "zstd:chunked"
+	case Uncompressed:
+		s = "uncompressed"
+	default:
+		s = "unknown"
+	}
+	return s
+}
+
+// LevelRange returns the smallest and largest compression levels supported by
+// the compression algorithm; both are 0 if the layers aren't compressed.
+func (c Compression) LevelRange() (min, max int) {
+	switch c {
+	case 0, Gzip:
+		min, max = 1, 9
+	case Zstd, ZstdChunked:
+		min, max = 1, 22
+	}
+	return min, max
+}
+
+// CompressionWrapper wraps Compression to facilitate its parsing from
+// serialized data.
+type CompressionWrapper struct {
+	Compression
+}
+
+// UnmarshalText decodes the compression algorithm from a UTF-8-encoded string.
+func (w *CompressionWrapper) UnmarshalText(text []byte) error {
+	var err error
+	w.Compression, err = parseCompressionString(string(text))
+	return err
+}
+
+// Enum returns the identifiers from which the compression algorithm can be
+// decoded.
+func (w CompressionWrapper) Enum() []string {
+	return []string{"gzip", "uncompressed", "zstd", "zstd:chunked"}
+}
+
+func parseCompressionString(s string) (Compression, error) {
+	var c Compression
+	switch strings.ToLower(s) {
+	case "gzip":
+		c = Gzip
+	case "uncompressed":
+		c = Uncompressed
+	case "zstd":
+		c = Zstd
+	case "zstd:chunked":
+		c = ZstdChunked
+	default:
+		return 0, fmt.Errorf("unsupported compression %q", s)
+	}
+	return c, nil
+}
+
+const (
+	OCI ImageFormat = 1 << iota
+	Docker
+)
+
+// ImageFormat is a unique identifier for the format of the manifest and
+// configuration of an image. The zero value represents the default, OCI.
+type ImageFormat uint
+
+// String returns a string containing the stylized name of the image format.
+func (f ImageFormat) String() string {
+	var s string
+	switch f {
+	case 0, OCI:
+		s = "OCI"
+	case Docker:
+		s = "Docker"
+	default:
+		s = "unknown"
+	}
+	return s
+}
+
+// ImageFormatWrapper wraps ImageFormat to facilitate its parsing from
+// serialized data.
+type ImageFormatWrapper struct {
+	ImageFormat
+}
+
+// UnmarshalText decodes the image format from a UTF-8-encoded string.
+func (w *ImageFormatWrapper) UnmarshalText(text []byte) error {
+	var err error
+	w.ImageFormat, err = parseImageFormatString(string(text))
+	return err
+}
+
+// Enum returns the identifiers from which the image format can be decoded.
+func (w ImageFormatWrapper) Enum() []string {
+	return []string{"docker", "oci"}
+}
+
+func parseImageFormatString(s string) (ImageFormat, error) {
+	var f ImageFormat
+	switch strings.ToLower(s) {
+	case "docker":
+		f = Docker
+	case "oci":
+		f = OCI
+	default:
+		return 0, fmt.Errorf("unsupported image format %q", s)
+	}
+	return f, nil
+}
+
+const (
+	Squash Layering = 1 << iota
+	PerStep
+)
+
+// Layering is a unique identifier for a way of dividing the changes made to
+// the working container into image layers. The zero value represents the
+// default, squashing.
+type Layering uint
+
+// String returns a string containing the name of the layering.
+func (l Layering) String() string {
+	var s string
+	switch l {
+	case 0, Squash:
+		s = "squash"
+	case PerStep:
+		s = "per-step"
+	default:
+		s = "unknown"
+	}
+	return s
+}
+
+// LayeringWrapper wraps Layering to facilitate its parsing from serialized
+// data.
+type LayeringWrapper struct {
+	Layering
+}
+
+// UnmarshalText decodes the layering from a UTF-8-encoded string.
+func (w *LayeringWrapper) UnmarshalText(text []byte) error {
+	var err error
+	w.Layering, err = parseLayeringString(string(text))
+	return err
+}
+
+// Enum returns the identifiers from which the layering can be decoded.
+func (w LayeringWrapper) Enum() []string {
+	return []string{"per-step", "squash"}
+}
+
+func parseLayeringString(s string) (Layering, error) {
+	var l Layering
+	switch strings.ToLower(s) {
+	case "per-step":
+		l = PerStep
+	case "squash":
+		l = Squash
+	default:
+		return 0, fmt.Errorf("unsupported layering %q; expected \"squash\" or \"per-step\"", s)
+	}
+	return l, nil
+}
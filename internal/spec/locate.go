@@ -0,0 +1,159 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// Locate returns the index of the first TOML document in `docs` that declares
+// the field at `path` together with the 1-indexed line and column of the
+// declaration, where `path` uses the notation of ValidationError.Field. If no
+// document declares the field, then Locate falls back to the closest enclosing
+// field that is declared. The last return value is false if no document that
+// can be parsed declares the field or any enclosing field.
+func Locate(docs [][]byte, path string) (doc, line, column int, ok bool) {
+	positions := make([]map[string]unstable.Position, len(docs))
+	for i, d := range docs {
+		positions[i] = locateFields(d)
+	}
+
+	for path != "" {
+		for i, m := range positions {
+			if p, found := m[path]; found {
+				return i, p.Line, p.Column, true
+			}
+		}
+		path = parentField(path)
+	}
+	return 0, 0, 0, false
+}
+
+// locateFields returns the positions of the fields declared in the TOML
+// document `doc`, or nil if the document can't be parsed.
+func locateFields(doc []byte) map[string]unstable.Position {
+	l := locator{positions: map[string]unstable.Position{}}
+	l.parser.Reset(doc)
+
+	counts := map[string]int{}
+	table := ""
+	for l.parser.NextExpression() {
+		e := l.parser.Expression()
+		switch e.Kind {
+		case unstable.Table:
+			table = joinKey("", e.Key())
+			l.record(table, e.Child())
+		case unstable.ArrayTable:
+			k := joinKey("", e.Key())
+			table = fmt.Sprintf("%s[%d]", k, counts[k])
+			counts[k]++
+			l.record(k, e.Child())
+			l.record(table, e.Child())
+		case unstable.KeyValue:
+			l.walkKeyValue(table, e)
+		}
+	}
+	if l.parser.Error() != nil {
+		return nil
+	}
+
+	return l.positions
+}
+
+// locator records the positions of the fields declared in a TOML document.
+type locator struct {
+	parser    unstable.Parser
+	positions map[string]unstable.Position
+}
+
+// record stores the position of the node `n` for the field at `path` if the
+// node refers to a range of the document.
+func (l *locator) record(path string, n *unstable.Node) {
+	if n == nil || n.Raw.Length == 0 {
+		return
+	}
+	if _, found := l.positions[path]; !found {
+		l.positions[path] = l.parser.Shape(n.Raw).Start
+	}
+}
+
+// walkKeyValue records the position of the key-value node `kv` in the table
+// at `table` and of any values nested within it.
+func (l *locator) walkKeyValue(table string, kv *unstable.Node) {
+	keys := kv.Key()
+	path := joinKey(table, keys)
+
+	// Record every table implied by a dotted key
+	//
+	prefix := table
+	it := kv.Key()
+	for it.Next() {
+		prefix = appendKey(prefix, string(it.Node().Data))
+		l.record(prefix, it.Node())
+	}
+
+	l.walkValue(path, kv.Value())
+}
+
+// walkValue records the positions of the elements of arrays and the key-value
+// pairs of inline tables nested in the value node `v`.
+func (l *locator) walkValue(path string, v *unstable.Node) {
+	switch v.Kind {
+	case unstable.Array:
+		i := 0
+		it := v.Children()
+		for it.Next() {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			l.record(p, it.Node())
+			l.walkValue(p, it.Node())
+			i++
+		}
+	case unstable.InlineTable:
+		it := v.Children()
+		for it.Next() {
+			l.walkKeyValue(path, it.Node())
+		}
+	}
+}
+
+// joinKey appends the components of a dotted key to the field path `prefix`.
+func joinKey(prefix string, keys unstable.Iterator) string {
+	for keys.Next() {
+		prefix = appendKey(prefix, string(keys.Node().Data))
+	}
+	return prefix
+}
+
+// appendKey appends a single key to the field path `prefix`.
+func appendKey(prefix, k string) string {
+	if prefix == "" {
+		return fieldKey(k)
+	}
+	return strings.Join([]string{prefix, fieldKey(k)}, ".")
+}
+
+// parentField returns the path to the field enclosing the field at `path`,
+// or an empty string if there's no such field.
+func parentField(path string) string {
+	inQuotes := false
+	cut := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case '"':
+			inQuotes = !inQuotes
+		case '.', '[':
+			if !inQuotes {
+				cut = i
+			}
+		}
+	}
+	return path[:cut]
+}
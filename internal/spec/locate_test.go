@@ -0,0 +1,56 @@
+package spec
+
+import "testing"
+
+func TestLocate(t *testing.T) {
+	parent := []byte(`[from]
+repository = "docker.io/library/alpine"
+tag = "3.18.2"
+`)
+
+	child := []byte(`extends = "parent.toml"
+
+[this]
+repository = "localhost/example"
+
+[[copy]]
+dest = "/etc"
+srcs = ["a", "b"]
+
+[config.labels]
+"org.example.name" = "example"
+`)
+
+	tests := []struct {
+		field  string
+		doc    int
+		line   int
+		column int
+	}{
+		{"this.repository", 0, 4, 1},
+		{"this.tag", 0, 3, 2},
+		{"copy[0].srcs[1]", 0, 8, 14},
+		{"copy[0].owner", 0, 6, 3},
+		{`config.labels."org.example.name"`, 0, 11, 1},
+		{"from.tag", 1, 3, 1},
+	}
+
+	docs := [][]byte{child, parent}
+	for _, tt := range tests {
+		doc, line, column, ok := Locate(docs, tt.field)
+		if !ok {
+			t.Errorf("expected to locate field %s", tt.field)
+			continue
+		}
+		if doc != tt.doc || line != tt.line || column != tt.column {
+			t.Errorf(
+				"expected field %s at %d:%d:%d, found %d:%d:%d",
+				tt.field, tt.doc, tt.line, tt.column, doc, line, column,
+			)
+		}
+	}
+
+	if _, _, _, ok := Locate(docs, "user.name"); ok {
+		t.Errorf("expected not to locate undeclared field user.name")
+	}
+}
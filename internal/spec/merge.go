@@ -0,0 +1,71 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import "strings"
+
+// appendedLists holds the dotted paths to the arrays in a spec that a child
+// spec extends rather than replaces. All other arrays are replaced.
+var appendedLists = map[string]bool{
+	"config.ports":                    true,
+	"copy":                            true,
+	"packages.groups":                 true,
+	"packages.install":                true,
+	"packages.install-files":          true,
+	"packages.remove":                 true,
+	"packages.repositories":           true,
+	"run":                             true,
+	"security.special-files.excludes": true,
+	"user.groups":                     true,
+}
+
+// Merge deep-merges the tables of the decoded child spec `child` over those of
+// the decoded parent spec `parent` and returns the result. Neither argument is
+// modified.
+//
+// Tables are merged key by key. Arrays are appended to the parent's arrays if
+// their path is in appendedLists and replace them otherwise. All other values
+// in the child replace the corresponding values in the parent.
+func Merge(parent, child map[string]any) map[string]any {
+	return mergeTables(parent, child, "")
+}
+
+func mergeTables(parent, child map[string]any, prefix string) map[string]any {
+	result := make(map[string]any, len(parent)+len(child))
+	for k, v := range parent {
+		result[k] = v
+	}
+
+	for k, v := range child {
+		path := k
+		if prefix != "" {
+			path = strings.Join([]string{prefix, k}, ".")
+		}
+
+		// The arrays of a stage are merged like those of a spec
+		//
+		if prefix == "stages" {
+			path = ""
+		}
+
+		switch cv := v.(type) {
+		case map[string]any:
+			if pv, ok := result[k].(map[string]any); ok {
+				result[k] = mergeTables(pv, cv, path)
+				continue
+			}
+		case []any:
+			if pv, ok := result[k].([]any); ok && appendedLists[path] {
+				merged := make([]any, 0, len(pv)+len(cv))
+				merged = append(merged, pv...)
+				merged = append(merged, cv...)
+				result[k] = merged
+				continue
+			}
+		}
+		result[k] = v
+	}
+
+	return result
+}
@@ -0,0 +1,100 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	parent := map[string]any{
+		"from": map[string]any{
+			"repository": "docker.io/library/alpine",
+			"tag":        "3.18.2",
+			"distro":     "alpine",
+		},
+		"packages": map[string]any{
+			"upgrade": true,
+			"install": []any{"curl"},
+		},
+		"config": map[string]any{
+			"cmd":    []any{"/bin/sh"},
+			"labels": map[string]any{"org.example.team": "a"},
+		},
+	}
+
+	child := map[string]any{
+		"this": map[string]any{
+			"repository": "localhost/child",
+		},
+		"packages": map[string]any{
+			"upgrade": false,
+			"install": []any{"git"},
+		},
+		"config": map[string]any{
+			"cmd":    []any{"/bin/ash"},
+			"labels": map[string]any{"org.example.image": "b"},
+		},
+	}
+
+	expected := map[string]any{
+		"from": map[string]any{
+			"repository": "docker.io/library/alpine",
+			"tag":        "3.18.2",
+			"distro":     "alpine",
+		},
+		"this": map[string]any{
+			"repository": "localhost/child",
+		},
+		"packages": map[string]any{
+			"upgrade": false,
+			"install": []any{"curl", "git"},
+		},
+		"config": map[string]any{
+			"cmd": []any{"/bin/ash"},
+			"labels": map[string]any{
+				"org.example.team":  "a",
+				"org.example.image": "b",
+			},
+		},
+	}
+
+	actual := Merge(parent, child)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, found %v", expected, actual)
+	}
+
+	if install := parent["packages"].(map[string]any)["install"].([]any); len(install) != 1 {
+		t.Errorf("expected parent to be unmodified, found install = %v", install)
+	}
+}
+
+func TestMergeStages(t *testing.T) {
+	parent := map[string]any{
+		"stages": map[string]any{
+			"builder": map[string]any{
+				"packages": map[string]any{"install": []any{"gcc"}},
+			},
+		},
+	}
+
+	child := map[string]any{
+		"stages": map[string]any{
+			"builder": map[string]any{
+				"packages": map[string]any{"install": []any{"make"}},
+			},
+		},
+	}
+
+	expected := map[string]any{
+		"stages": map[string]any{
+			"builder": map[string]any{
+				"packages": map[string]any{"install": []any{"gcc", "make"}},
+			},
+		},
+	}
+
+	actual := Merge(parent, child)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, found %v", expected, actual)
+	}
+}
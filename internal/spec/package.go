@@ -0,0 +1,76 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import (
+	"fmt"
+
+	"github.com/ok-ryoko/turret/pkg/linux/pckg"
+)
+
+// PackageList holds packages as decoded from TOML, where each element is
+// either a string naming a package or a table with the keys name and version,
+// the latter pinning the package to a version.
+type PackageList []any
+
+// Packages returns the packages in the list, skipping malformed elements.
+func (l PackageList) Packages() []pckg.Package {
+	packages := make([]pckg.Package, 0, len(l))
+	for _, v := range l {
+		if p, err := parsePackage(v); err == nil {
+			packages = append(packages, p)
+		}
+	}
+	return packages
+}
+
+// Schema returns the JSON Schema for the list.
+func (l PackageList) Schema() map[string]any {
+	return map[string]any{
+		"type": "array",
+		"items": map[string]any{
+			"anyOf": []any{
+				map[string]any{"type": "string"},
+				map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"name":    map[string]any{"type": "string"},
+						"version": map[string]any{"type": "string"},
+					},
+					"required":             []string{"name"},
+					"additionalProperties": false,
+				},
+			},
+		},
+	}
+}
+
+// parsePackage converts an element of a PackageList into a package.
+func parsePackage(v any) (pckg.Package, error) {
+	switch t := v.(type) {
+	case string:
+		return pckg.Package{Name: t}, nil
+	case pckg.Package:
+		return t, nil
+	case map[string]any:
+		p := pckg.Package{}
+		for _, k := range sortedKeys(t) {
+			s, ok := t[k].(string)
+			if !ok {
+				return pckg.Package{}, fmt.Errorf("expected string for key %q, found %T", k, t[k])
+			}
+			switch k {
+			case "name":
+				p.Name = s
+			case "version":
+				p.Version = s
+			default:
+				return pckg.Package{}, fmt.Errorf("unknown key %q", k)
+			}
+		}
+		return p, nil
+	default:
+		return pckg.Package{}, fmt.Errorf("expected string or table, found %T", v)
+	}
+}
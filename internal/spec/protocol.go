@@ -43,6 +43,11 @@ func (w *ProtocolWrapper) UnmarshalText(text []byte) error {
 	return err
 }
 
+// Enum returns the identifiers from which the protocol can be decoded.
+func (w ProtocolWrapper) Enum() []string {
+	return []string{"tcp", "udp"}
+}
+
 func parseProtocolString(s string) (Protocol, error) {
 	var p Protocol
 	switch strings.ToLower(s) {
@@ -0,0 +1,206 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const schemaDialect string = "https://json-schema.org/draft/2020-12/schema"
+
+// enumerator is the interface implemented by types that can be decoded from
+// one of a fixed set of identifiers.
+type enumerator interface {
+	Enum() []string
+}
+
+// schemer is the interface implemented by types that describe their own JSON
+// Schema.
+type schemer interface {
+	Schema() map[string]any
+}
+
+// schemaConstraints holds the constraints enforced by Validate that can be
+// expressed in JSON Schema, keyed by field path. The constraints also apply to
+// the corresponding fields of stages.
+var schemaConstraints = map[string]map[string]any{
+	"config.ports[].number": {
+		"minimum": 1,
+	},
+	"packages.repositories[].name": {
+		"pattern": reRepositoryName.String(),
+	},
+	"run[].capabilities[]": {
+		"pattern": `^CAP_[0-9A-Z_]+$`,
+	},
+	"run[].cmd": {
+		"minItems": 1,
+	},
+	"run[].user": {
+		"pattern": reUserGroup.String(),
+	},
+	"steps[].index": {
+		"minimum": 0,
+	},
+	"user.comment": {
+		"maxLength": maxCommentLength,
+	},
+	"user.groups[]": {
+		"maxLength": maxNameLength,
+		"minLength": 1,
+		"pattern":   `^[-.0-9A-Z_a-z]+$`,
+	},
+	"user.id": {
+		"anyOf": []any{
+			map[string]any{"const": 0},
+			map[string]any{"minimum": minUID, "maximum": maxUID},
+		},
+	},
+	"user.name": {
+		"maxLength": maxNameLength,
+		"minLength": 1,
+		"pattern":   `^[-.0-9A-Z_a-z]+$`,
+	},
+}
+
+// Schema returns a JSON Schema (draft 2020-12) describing the structure of
+// spec files, generated from the Spec type.
+//
+// The schema describes individual spec files rather than resolved specs, so
+// it marks no field as required; a spec may inherit required fields from the
+// spec it extends.
+func Schema() (map[string]any, error) {
+	s, err := schemaOf(reflect.TypeOf(Spec{}), "")
+	if err != nil {
+		return nil, err
+	}
+	s["$schema"] = schemaDialect
+	s["title"] = "Turret spec"
+	return s, nil
+}
+
+// schemaOf returns the JSON Schema for values of type `t` found at the field
+// path `path`.
+func schemaOf(t reflect.Type, path string) (map[string]any, error) {
+	var s map[string]any
+
+	if sc, ok := reflect.New(t).Elem().Interface().(schemer); ok {
+		s = sc.Schema()
+	} else if e, ok := reflect.New(t).Interface().(enumerator); ok {
+		if _, ok := e.(encoding.TextUnmarshaler); ok {
+			s = map[string]any{
+				"type": "string",
+				"anyOf": []any{
+					map[string]any{"enum": e.Enum()},
+					map[string]any{"pattern": enumPattern(e.Enum())},
+				},
+			}
+		}
+	}
+
+	if s == nil {
+		switch t.Kind() {
+		case reflect.Pointer:
+			return schemaOf(t.Elem(), path)
+		case reflect.Bool:
+			s = map[string]any{"type": "boolean"}
+		case reflect.String:
+			s = map[string]any{"type": "string"}
+		case reflect.Int:
+			s = map[string]any{"type": "integer"}
+		case reflect.Uint16:
+			s = map[string]any{"type": "integer", "minimum": 0, "maximum": math.MaxUint16}
+		case reflect.Uint32:
+			s = map[string]any{"type": "integer", "minimum": 0, "maximum": math.MaxUint32}
+		case reflect.Slice:
+			items, err := schemaOf(t.Elem(), path+"[]")
+			if err != nil {
+				return nil, err
+			}
+			s = map[string]any{
+				"type":  "array",
+				"items": items,
+			}
+		case reflect.Map:
+			values, err := schemaOf(t.Elem(), path+".*")
+			if err != nil {
+				return nil, err
+			}
+			s = map[string]any{
+				"type":                 "object",
+				"additionalProperties": values,
+			}
+		case reflect.Struct:
+			properties := map[string]any{}
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				if !f.IsExported() {
+					continue
+				}
+				k := fieldName(f)
+				p := k
+				if path != "" {
+					p = path + "." + k
+				}
+				property, err := schemaOf(f.Type, p)
+				if err != nil {
+					return nil, err
+				}
+				properties[k] = property
+			}
+			s = map[string]any{
+				"type":                 "object",
+				"properties":           properties,
+				"additionalProperties": false,
+			}
+		default:
+			return nil, fmt.Errorf("no JSON Schema for type %s of field %q", t, path)
+		}
+	}
+
+	for k, v := range schemaConstraints[strings.TrimPrefix(path, "stages.*.")] {
+		s[k] = v
+	}
+
+	return s, nil
+}
+
+// enumPattern returns a regular expression matching any of the identifiers in
+// `values` regardless of case. Identifiers are decoded case-insensitively, so
+// the schema accepts them in any case besides listing them in an enum.
+//
+// JSON Schema patterns follow ECMA-262, which has no inline flags, so each
+// letter is matched by a class of its lowercase and uppercase forms.
+func enumPattern(values []string) string {
+	alternatives := make([]string, 0, len(values))
+	for _, v := range values {
+		var b strings.Builder
+		for _, r := range v {
+			if lower, upper := unicode.ToLower(r), unicode.ToUpper(r); lower != upper {
+				fmt.Fprintf(&b, "[%c%c]", lower, upper)
+			} else {
+				b.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		alternatives = append(alternatives, b.String())
+	}
+	return fmt.Sprintf("^(?:%s)$", strings.Join(alternatives, "|"))
+}
+
+// fieldName returns the key under which a struct field is encoded in TOML.
+func fieldName(f reflect.StructField) string {
+	if tag, ok := f.Tag.Lookup("toml"); ok {
+		name, _, _ := strings.Cut(tag, ",")
+		if name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(f.Name)
+}
@@ -0,0 +1,59 @@
+package spec
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatalf("generating JSON Schema: %v", err)
+	}
+
+	properties := func(s map[string]any) map[string]any {
+		return s["properties"].(map[string]any)
+	}
+
+	from := properties(schema)["from"].(map[string]any)
+	distro := properties(from)["distro"].(map[string]any)
+	if distro["type"] != "string" {
+		t.Errorf("expected distro of type string, found %v", distro["type"])
+	}
+	expectedDistros := []string{"alpine", "arch", "chimera", "debian", "fedora", "opensuse", "void"}
+	alternatives := distro["anyOf"].([]any)
+	enum := alternatives[0].(map[string]any)["enum"]
+	if !reflect.DeepEqual(enum, expectedDistros) {
+		t.Errorf("expected distros %v, found %v", expectedDistros, enum)
+	}
+	re, err := regexp.Compile(alternatives[1].(map[string]any)["pattern"].(string))
+	if err != nil {
+		t.Fatalf("compiling distro pattern: %v", err)
+	}
+	for _, d := range []string{"alpine", "Alpine", "OPENSUSE", "void"} {
+		if !re.MatchString(d) {
+			t.Errorf("expected distro pattern to match %q", d)
+		}
+	}
+	for _, d := range []string{"ubuntu", "alpine ", "xalpine", "arch|void"} {
+		if re.MatchString(d) {
+			t.Errorf("expected distro pattern not to match %q", d)
+		}
+	}
+
+	user := properties(schema)["user"].(map[string]any)
+	name := properties(user)["name"].(map[string]any)
+	if name["maxLength"] != maxNameLength {
+		t.Errorf("expected user name maxLength %d, found %v", maxNameLength, name["maxLength"])
+	}
+
+	copies := properties(schema)["copy"].(map[string]any)
+	item := copies["items"].(map[string]any)
+	if _, ok := properties(item)["dest"]; !ok {
+		t.Errorf("expected copy items to have property dest")
+	}
+	if item["additionalProperties"] != false {
+		t.Errorf("expected copy items to disallow additional properties")
+	}
+}
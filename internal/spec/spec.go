@@ -5,15 +5,20 @@ package spec
 
 import (
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/ok-ryoko/turret/pkg/linux"
 	"github.com/ok-ryoko/turret/pkg/linux/find"
 	"github.com/ok-ryoko/turret/pkg/linux/pckg"
 	"github.com/ok-ryoko/turret/pkg/linux/user"
 
+	"github.com/containers/common/pkg/capabilities"
 	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/transports/alltransports"
 )
 
 const (
@@ -28,11 +33,23 @@ var (
 	reNotPOSIXPortableCharacter = regexp.MustCompile(`[^-.0-9A-Z_a-z]`)
 	reReverseUnlimitedFQDN      = regexp.MustCompile(`^\.?([0-9A-Za-z]|[0-9A-Za-z][-0-9A-Za-z]*[0-9A-Za-z]\.)*[0-9A-Za-z]$`)
 	reSpecialPrefixOrSuffix     = regexp.MustCompile(`^[-._]|[-._]$`)
+	reRepositoryName            = regexp.MustCompile(`^[0-9A-Za-z][-.0-9A-Z_a-z]*$`)
+	reStageName                 = regexp.MustCompile(`^[0-9A-Za-z][-.0-9A-Z_a-z]*$`)
+	reUserGroup                 = regexp.MustCompile(`^[-.0-9A-Z_a-z]+(:[-.0-9A-Z_a-z]+)?$`)
 	reURLScheme                 = regexp.MustCompile(`^[^:/?#]+:`) // IETF RFC 3986 Appendix B
+	reVirtualPackageName        = regexp.MustCompile(`^\.?[0-9a-z][+\-.0-9_a-z]*[0-9a-z]$`)
 )
 
 // Spec holds the options for the build and defines the structure of spec files.
 type Spec struct {
+	// Path to a parent spec over which to merge this spec; if a relative path,
+	// then it's resolved with respect to the directory containing this spec
+	Extends string
+
+	// Default values of the build arguments that may be referenced as ${KEY}
+	// in string fields
+	Args map[string]string
+
 	// Information about the base image
 	From From
 
@@ -50,14 +67,114 @@ type Spec struct {
 	// file system to the working container's file system
 	Copy []Copy
 
+	// Instructions for running one or more commands in the working container
+	Run []Run
+
 	// Security options for the working container
 	Security Security
 
+	// Order in which to alter the working container; defaults to configuring
+	// package repositories, upgrading, installing, removing and cleaning
+	// packages, creating the user, copying files, running commands and
+	// removing SUID and SGID bits, in that order
+	Steps []Step
+
 	// Configuration for the working container
 	Config Configuration
 
 	// Choices of implementations of operations in the working container
 	Backends Backends
+
+	// Intermediate working containers from which files may be copied, keyed
+	// by name
+	Stages map[string]Stage
+}
+
+// Stage holds instructions for building an intermediate working container
+// from which files may be copied. Its fields have the same meanings as the
+// corresponding fields of Spec.
+type Stage struct {
+	From     From
+	Packages Packages
+	User     *User
+	Copy     []Copy
+	Run      []Run
+	Security Security
+	Steps    []Step
+	Backends Backends
+}
+
+// Spec returns a spec describing the alteration of the stage's working
+// container.
+func (st Stage) Spec() Spec {
+	return Spec{
+		From:     st.From,
+		Packages: st.Packages,
+		User:     st.User,
+		Copy:     st.Copy,
+		Run:      st.Run,
+		Security: st.Security,
+		Steps:    st.Steps,
+		Backends: st.Backends,
+	}
+}
+
+// stageOf returns the stage described by the parts of a spec that alter the
+// working container.
+func stageOf(s Spec) Stage {
+	return Stage{
+		From:     s.From,
+		Packages: s.Packages,
+		User:     s.User,
+		Copy:     s.Copy,
+		Run:      s.Run,
+		Security: s.Security,
+		Steps:    s.Steps,
+		Backends: s.Backends,
+	}
+}
+
+// StageOrder returns the names of the stages of a spec in an order in which
+// every stage follows the stages from which it copies files, breaking ties in
+// lexicographic order. It returns an error if a stage transitively copies
+// files from itself.
+func StageOrder(s Spec) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	order := make([]string, 0, len(s.Stages))
+	state := make(map[string]int, len(s.Stages))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("stage %q copies files from itself", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, c := range s.Stages[name].Copy {
+			if _, ok := s.Stages[c.FromStage]; ok {
+				if err := visit(c.FromStage); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range sortedKeys(s.Stages) {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
 }
 
 // From holds information about the base image.
@@ -99,6 +216,25 @@ type This struct {
 	// Preserve the image history and timestamps of the files in the working
 	// container's file system
 	KeepHistory bool `toml:"keep-history"`
+
+	// Algorithm with which to compress the layers of the image
+	Compression CompressionWrapper
+
+	// Level at which to compress the layers of the image; when nil, the
+	// algorithm's default level is used
+	CompressionLevel *int `toml:"compression-level"`
+
+	// Format of the image manifest and configuration
+	Format ImageFormatWrapper
+
+	// Commit the changes made to the working container as a single layer or
+	// as one layer per step
+	Layers LayeringWrapper
+
+	// Locations to which to copy the image after committing it to local
+	// storage, each given as TRANSPORT:REFERENCE, e.g.,
+	// docker://registry.example.com/app:1.0 or oci-archive:app.tar
+	Destinations []string
 }
 
 // Reference returns a string representation of the image's tagged reference.
@@ -112,14 +248,85 @@ func (t This) Reference() string {
 
 // Packages contains instructions for the package management backend.
 type Packages struct {
-	// Upgrade pre-installed packages
-	Upgrade bool
+	// Package repositories to configure before upgrading, installing or
+	// removing packages
+	Repositories []Repository
+
+	// Upgrade pre-installed packages, either all of them or only those with
+	// security fixes
+	Upgrade UpgradeModeWrapper
+
+	// Install one or more packages, each of which may be pinned to a version
+	Install PackageList
+
+	// Install one or more package groups after the packages in Install; only
+	// supported by APT (tasks), DNF (groups and module streams), pacman
+	// (groups) and zypper (patterns)
+	Groups []string
 
-	// Install one or more packages
-	Install []string
+	// Name of a virtual package to create that depends on the packages in
+	// Install so that they can be removed together; only supported by apk
+	Virtual string
+
+	// Paths to one or more package files on the host's file system whose
+	// packages to install
+	InstallFiles []string `toml:"install-files"`
+
+	// Install the weak dependencies (recommended packages) of the packages
+	// being installed; only supported by APT, DNF and zypper
+	WeakDeps bool `toml:"weak-deps"`
+
+	// Remove one or more packages together with the dependencies that no
+	// other package needs
+	Remove []string
 
 	// Clean package caches after upgrading or installing packages
 	Clean bool
+
+	// Package repository on the host's file system from which to resolve
+	// packages instead of the configured repositories; if set, then every
+	// process in the working container is disconnected from the network
+	Local *LocalRepository
+}
+
+// LocalRepository holds information about a package repository on the host's
+// file system, e.g., a directory containing .deb files and a Packages index,
+// an RPM repository with repodata or an APK index.
+type LocalRepository struct {
+	// Path to the directory containing the repository on the host's file
+	// system
+	Path string
+
+	// Don't verify the signatures of the repository and its packages
+	Trusted bool
+}
+
+// Repository holds information about a package repository to configure in
+// addition to the repositories configured in the base image.
+type Repository struct {
+	// Unique human-readable identifier
+	Name string
+
+	// URL of the repository
+	URL string `toml:"url"`
+
+	// Distribution suite, e.g., bookworm; required for and only supported by
+	// APT
+	Suite string
+
+	// Archive areas, e.g., main; only supported by APT
+	Components []string
+
+	// Path to the public key with which the repository is signed on the
+	// host's file system
+	Key string
+
+	// Priority of the repository in the package manager's own terms; 0 for
+	// the default priority
+	Priority int
+
+	// Use the repository; defaults to true
+	Enabled *bool
 }
 
 // User holds information about the sole unprivileged Linux user to be created
@@ -177,6 +384,57 @@ type Copy struct {
 
 	// Remove all SUID and SGID bits from the files copied to the working container
 	RemoveS bool `toml:"remove-s"`
+
+	// Name of the stage from whose working container to copy the files; if
+	// set, then the base and sources refer to that container's file system
+	FromStage string `toml:"from-stage"`
+}
+
+// Run holds instructions for running a command in the working container.
+type Run struct {
+	// Command line, beginning with the executable
+	Command []string `toml:"cmd"`
+
+	// Linux capabilities to grant to the process, e.g., CAP_CHOWN; the
+	// process runs with no capabilities by default
+	Capabilities []string
+
+	// Connect the process to the network
+	Network bool
+
+	// Set one or more environment variables for the process
+	Environment map[string]string `toml:"env"`
+
+	// User (and, optionally, group) as whom to run the process, in the form
+	// USER[:GROUP]; the process runs as root by default
+	User string
+
+	// Absolute path to the directory in which to run the process
+	WorkDir string `toml:"work-dir"`
+}
+
+// Step refers to one or more alterations of the working container that are
+// described elsewhere in the spec.
+type Step struct {
+	// Kind of alteration
+	Action ActionWrapper
+
+	// Position of the copy or run table to which the step refers; if nil,
+	// then the step refers to all such tables, in order of declaration
+	Index *int
+}
+
+// Indices returns the positions of the tables to which the step refers,
+// assuming the step's action is indexed and `n` tables are declared.
+func (s Step) Indices(n int) []int {
+	if s.Index != nil {
+		return []int{*s.Index}
+	}
+	indices := make([]int, n)
+	for i := range indices {
+		indices[i] = i
+	}
+	return indices
 }
 
 // Security holds security-related options for the working container.
@@ -317,139 +575,519 @@ func Fill(s Spec) Spec {
 		}
 	}
 
+	if len(s.Steps) == 0 {
+		for _, a := range defaultOrder {
+			if count(s, a) > 0 {
+				s.Steps = append(s.Steps, Step{Action: ActionWrapper{a}})
+			}
+		}
+	}
+
+	for i, r := range s.Packages.Repositories {
+		if r.Enabled == nil {
+			enabled := true
+			s.Packages.Repositories[i].Enabled = &enabled
+		}
+	}
+
+	for i, c := range s.Copy {
+		if c.FromStage != "" && c.Base == "" {
+			s.Copy[i].Base = "/"
+		}
+	}
+
+	for name, st := range s.Stages {
+		s.Stages[name] = stageOf(Fill(st.Spec()))
+	}
+
 	return s
 }
 
+// defaultOrder holds the order in which to alter the working container when a
+// spec doesn't declare any steps.
+var defaultOrder = []Action{
+	ActionRepositories,
+	ActionUpgrade,
+	ActionInstall,
+	ActionInstallFiles,
+	ActionRemove,
+	ActionClean,
+	ActionUser,
+	ActionCopy,
+	ActionRun,
+	ActionRemoveS,
+}
+
+// count returns the number of alterations of the given kind that a spec
+// describes.
+func count(s Spec, a Action) int {
+	var n int
+	switch a {
+	case ActionRepositories:
+		if len(s.Packages.Repositories) > 0 {
+			n = 1
+		}
+	case ActionUpgrade:
+		if s.Packages.Upgrade.UpgradeMode != 0 {
+			n = 1
+		}
+	case ActionInstall:
+		if len(s.Packages.Install) > 0 || len(s.Packages.Groups) > 0 {
+			n = 1
+		}
+	case ActionInstallFiles:
+		if len(s.Packages.InstallFiles) > 0 {
+			n = 1
+		}
+	case ActionRemove:
+		if len(s.Packages.Remove) > 0 {
+			n = 1
+		}
+	case ActionClean:
+		if s.Packages.Clean {
+			n = 1
+		}
+	case ActionUser:
+		if s.User != nil {
+			n = 1
+		}
+	case ActionCopy:
+		n = len(s.Copy)
+	case ActionRun:
+		n = len(s.Run)
+	case ActionRemoveS:
+		if s.Security.SpecialFiles.RemoveS {
+			n = 1
+		}
+	}
+	return n
+}
+
 // Validate asserts that a spec is complete and satisfies domain-specific
-// constraints.
+// constraints, returning a ValidationErrors describing every violation.
 func Validate(s Spec) error {
+	errs := validateStage(s, s.Stages)
+
+	if s.This.Repository == "" {
+		errs.add("this.repository", "", "missing image repository (name)")
+	} else if _, err := reference.Parse(s.This.Reference()); err != nil {
+		errs.add("this.repository", s.This.Reference(), "parsing image reference: %v", err)
+	}
+
+	if s.This.Format.ImageFormat == Docker {
+		switch s.This.Compression.Compression {
+		case Zstd, ZstdChunked:
+			errs.add("this.compression", s.This.Compression.String(), "%s image format doesn't support %s compression", s.This.Format, s.This.Compression)
+		}
+	}
+	if l := s.This.CompressionLevel; l != nil {
+		if min, max := s.This.Compression.LevelRange(); min == 0 && max == 0 {
+			errs.add("this.compression-level", *l, "%s layers have no compression level", s.This.Compression)
+		} else if *l < min || *l > max {
+			errs.add("this.compression-level", *l, "%s compression level must be between %d and %d", s.This.Compression, min, max)
+		}
+	}
+
+	// Only registry references are parsed because parsing a reference with a
+	// transport that writes to the host's file system inspects that path
+	for i, d := range s.This.Destinations {
+		field := fmt.Sprintf("this.destinations[%d]", i)
+		if t := alltransports.TransportFromImageName(d); t == nil {
+			errs.add(field, d, "unknown transport in destination %q", d)
+		} else if t.Name() == "docker" {
+			if _, err := alltransports.ParseImageName(d); err != nil {
+				errs.add(field, d, "parsing destination: %v", err)
+			}
+		}
+	}
+
+	for _, k := range sortedKeys(s.Config.Annotations) {
+		if !reReverseUnlimitedFQDN.MatchString(k) {
+			errs.add("config.annotations."+fieldKey(k), k, "annotation key %q is not in reverse domain notation", k)
+		}
+	}
+
+	for _, k := range sortedKeys(s.Config.Labels) {
+		if !reReverseUnlimitedFQDN.MatchString(k) {
+			errs.add("config.labels."+fieldKey(k), k, "label key %q is not in reverse domain notation", k)
+		}
+	}
+
+	for i, p := range s.Config.Ports {
+		prefix := fmt.Sprintf("config.ports[%d]", i)
+		if p.Number == 0 {
+			errs.add(prefix+".number", p.Number, "the zero port is reserved")
+		}
+		if p.Protocol.Protocol == 0 {
+			errs.add(prefix+".protocol", "", "unknown network protocol for port %d", p.Number)
+		}
+	}
+
+	if s.Config.WorkDir != "" {
+		if !filepath.IsAbs(s.Config.WorkDir) {
+			errs.add("config.work-dir", s.Config.WorkDir, "working directory %q is not an absolute path", s.Config.WorkDir)
+		}
+	}
+
+	if _, err := StageOrder(s); err != nil {
+		errs.add("stages", "", "%v", err)
+	}
+
+	for _, name := range sortedKeys(s.Stages) {
+		prefix := "stages." + fieldKey(name)
+		if !reStageName.MatchString(name) {
+			errs.add(prefix, name, "invalid stage name %q", name)
+		}
+		for _, e := range validateStage(s.Stages[name].Spec(), s.Stages) {
+			e.Field = prefix + "." + e.Field
+			errs = append(errs, e)
+		}
+	}
+
+	return errs.err()
+}
+
+// validateStage asserts that the parts of a spec that describe the alteration
+// of a working container are complete and satisfy domain-specific
+// constraints, where `stages` holds the stages from which files may be copied.
+func validateStage(s Spec, stages map[string]Stage) ValidationErrors {
+	var errs ValidationErrors
+
 	if s.From.Distro.Distro == 0 {
-		return fmt.Errorf("missing distro")
+		errs.add("from.distro", "", "missing distro")
 	}
 
 	if s.Backends.Package.Backend == 0 {
-		return fmt.Errorf("missing package management backend")
+		errs.add("backends.package", "", "missing package management backend")
 	}
 
 	if s.Backends.User.Backend == 0 {
-		return fmt.Errorf("missing user management backend")
+		errs.add("backends.user", "", "missing user management backend")
 	}
 
 	if s.Backends.Find.Backend == 0 {
-		return fmt.Errorf("missing find implementation")
+		errs.add("backends.find", "", "missing find implementation")
 	}
 
-	if s.This.Repository == "" {
-		return fmt.Errorf("missing image repository (name)")
+	if s.From.Repository == "" {
+		errs.add("from.repository", "", "missing base image repository (name)")
+	} else if s.From.Tag == "" && s.From.Digest == "" {
+		errs.add("from.tag", "", "expected tag or digest for base image, found neither")
+	} else if _, err := reference.Parse(s.From.Reference()); err != nil {
+		errs.add("from.repository", s.From.Reference(), "parsing base image reference: %v", err)
 	}
 
-	if _, err := reference.Parse(s.This.Reference()); err != nil {
-		return fmt.Errorf("parsing image reference: %w", err)
+	if len(s.Packages.Install) > 0 || len(s.Packages.Remove) > 0 {
+		re := regexp.MustCompile(s.Backends.Package.RePackageName())
+		reVersion := regexp.MustCompile(s.Backends.Package.RePackageVersion())
+		for i, v := range s.Packages.Install {
+			path := fmt.Sprintf("packages.install[%d]", i)
+			p, err := parsePackage(v)
+			if err != nil {
+				errs.add(path, v, "invalid package: %v", err)
+				continue
+			}
+			if _, ok := v.(string); !ok {
+				path += ".name"
+			}
+			if !re.MatchString(p.Name) {
+				errs.add(path, p.Name, "invalid package name %q", p.Name)
+			}
+			if p.Version != "" && !reVersion.MatchString(p.Version) {
+				errs.add(fmt.Sprintf("packages.install[%d].version", i), p.Version, "invalid %s version %q for package %q", s.Backends.Package, p.Version, p.Name)
+			}
+		}
+		for i, p := range s.Packages.Remove {
+			if p != s.Packages.Virtual && !re.MatchString(p) {
+				errs.add(fmt.Sprintf("packages.remove[%d]", i), p, "invalid package name %q", p)
+			}
+		}
 	}
 
-	if s.From.Repository == "" {
-		return fmt.Errorf("missing base image repository (name)")
+	if len(s.Packages.Groups) > 0 {
+		if r := s.Backends.Package.ReGroupName(); r == "" {
+			errs.add("packages.groups", s.Packages.Groups, "%s doesn't support package groups", s.Backends.Package)
+		} else {
+			re := regexp.MustCompile(r)
+			for i, g := range s.Packages.Groups {
+				if !re.MatchString(g) {
+					errs.add(fmt.Sprintf("packages.groups[%d]", i), g, "invalid package group name %q", g)
+				}
+			}
+		}
 	}
 
-	if s.From.Tag == "" && s.From.Digest == "" {
-		return fmt.Errorf("expected tag or digest for base image, found neither")
+	if s.Packages.Virtual != "" {
+		if s.Backends.Package.Backend != pckg.APK {
+			errs.add("packages.virtual", s.Packages.Virtual, "%s doesn't support virtual packages", s.Backends.Package)
+		} else if !reVirtualPackageName.MatchString(s.Packages.Virtual) {
+			errs.add("packages.virtual", s.Packages.Virtual, "invalid virtual package name %q", s.Packages.Virtual)
+		} else if len(s.Packages.Install) == 0 {
+			errs.add("packages.virtual", s.Packages.Virtual, "virtual package %q depends on no packages", s.Packages.Virtual)
+		}
 	}
 
-	if _, err := reference.Parse(s.From.Reference()); err != nil {
-		return fmt.Errorf("parsing base image reference: %w", err)
+	names := map[string]bool{}
+	for i, f := range s.Packages.InstallFiles {
+		path := fmt.Sprintf("packages.install-files[%d]", i)
+		name := filepath.Base(f)
+		if f == "" {
+			errs.add(path, f, "empty package file path")
+		} else if !filepath.IsAbs(f) {
+			errs.add(path, f, "package file %q is not an absolute path", f)
+		} else if !s.Backends.Package.IsPackageFile(name) {
+			errs.add(path, f, "%q isn't a %s package file", f, s.Backends.Package)
+		} else if names[name] {
+			errs.add(path, f, "duplicate package file name %q", name)
+		}
+		names[name] = true
 	}
 
-	if len(s.Packages.Install) > 0 {
-		re := regexp.MustCompile(s.Backends.Package.RePackageName())
-		for _, p := range s.Packages.Install {
-			if !re.MatchString(p) {
-				return fmt.Errorf("invalid package name %q", p)
-			}
+	if s.Packages.Upgrade.UpgradeMode == UpgradeSecurity && !s.Backends.Package.HasSecurityUpgrades() {
+		errs.add("packages.upgrade", "security", "%s doesn't support security-only upgrades", s.Backends.Package)
+	}
+
+	if s.Packages.WeakDeps && !s.Backends.Package.HasWeakDeps() {
+		errs.add("packages.weak-deps", true, "%s doesn't support weak dependencies", s.Backends.Package)
+	}
+
+	errs = append(errs, validateRepositories(s.Packages.Repositories, s.Backends.Package.Backend)...)
+
+	if s.Packages.Local != nil {
+		if s.Packages.Local.Path == "" {
+			errs.add("packages.local.path", "", "missing path to local repository")
+		} else if !filepath.IsAbs(s.Packages.Local.Path) {
+			errs.add("packages.local.path", s.Packages.Local.Path, "local repository %q is not an absolute path", s.Packages.Local.Path)
 		}
 	}
 
 	if s.User != nil {
 		if err := validateName(s.User.Name); err != nil {
-			return fmt.Errorf("invalid user name %q: %w", s.User.Name, err)
+			errs.add("user.name", s.User.Name, "invalid user name %q: %v", s.User.Name, err)
 		}
 
 		if s.User.ID != 0 && (s.User.ID < minUID || s.User.ID > maxUID) {
-			return fmt.Errorf("UID %d outside allowed range [%d-%d]", s.User.ID, minUID, maxUID)
+			errs.add("user.id", s.User.ID, "UID %d outside allowed range [%d-%d]", s.User.ID, minUID, maxUID)
 		}
 
-		for _, g := range s.User.Groups {
+		for i, g := range s.User.Groups {
 			if err := validateName(g); err != nil {
-				return fmt.Errorf("invalid group name %q: %w", g, err)
+				errs.add(fmt.Sprintf("user.groups[%d]", i), g, "invalid group name %q: %v", g, err)
 			}
 		}
 
 		if s.User.Comment != nil {
 			if len(*s.User.Comment) > maxCommentLength {
-				return fmt.Errorf("comment is longer than %d characters", maxCommentLength)
+				errs.add("user.comment", *s.User.Comment, "comment is longer than %d characters", maxCommentLength)
 			}
 		}
 	}
 
-	for _, c := range s.Copy {
-		if c.Base == "" {
-			return fmt.Errorf("missing base")
+	for i, c := range s.Copy {
+		prefix := fmt.Sprintf("copy[%d]", i)
+
+		if c.FromStage != "" {
+			if _, ok := stages[c.FromStage]; !ok {
+				errs.add(prefix+".from-stage", c.FromStage, "undefined stage %q", c.FromStage)
+			}
 		}
-		if !filepath.IsAbs(c.Base) {
-			return fmt.Errorf("base %q is not an absolute path", c.Base)
+
+		if c.Base == "" {
+			errs.add(prefix+".base", "", "missing base")
+		} else if !filepath.IsAbs(c.Base) {
+			errs.add(prefix+".base", c.Base, "base %q is not an absolute path", c.Base)
 		}
 
 		if c.Destination == "" {
-			return fmt.Errorf("missing destination")
-		}
-		if !filepath.IsAbs(c.Destination) {
-			return fmt.Errorf("destination %q is not an absolute path", c.Destination)
+			errs.add(prefix+".dest", "", "missing destination")
+		} else if !filepath.IsAbs(c.Destination) {
+			errs.add(prefix+".dest", c.Destination, "destination %q is not an absolute path", c.Destination)
 		}
 
 		if len(c.Sources) == 0 {
-			return fmt.Errorf("missing sources for base %q and destination %q", c.Base, c.Destination)
+			errs.add(prefix+".srcs", c.Sources, "missing sources for base %q and destination %q", c.Base, c.Destination)
 		}
-		for i, src := range c.Sources {
+		for j, src := range c.Sources {
+			path := fmt.Sprintf("%s.srcs[%d]", prefix, j)
 			if src == "" {
-				return fmt.Errorf("empty source at index %d for base %q and destination %q", i, c.Base, c.Destination)
-			}
-			if reURLScheme.MatchString(src) {
-				return fmt.Errorf("source %q has a scheme", src)
+				errs.add(path, src, "empty source for base %q and destination %q", c.Base, c.Destination)
+			} else if reURLScheme.MatchString(src) {
+				errs.add(path, src, "source %q has a scheme", src)
 			}
 		}
 
 		if err := validateName(c.Owner); err != nil {
-			return fmt.Errorf("invalid owner %q for destination %q", c.Owner, c.Destination)
+			errs.add(prefix+".owner", c.Owner, "invalid owner %q for destination %q: %v", c.Owner, c.Destination, err)
 		}
 	}
 
-	for k := range s.Config.Annotations {
-		if !reReverseUnlimitedFQDN.MatchString(k) {
-			return fmt.Errorf("annotation key %q is not in reverse domain notation", k)
+	for i, r := range s.Run {
+		prefix := fmt.Sprintf("run[%d]", i)
+
+		if len(r.Command) == 0 {
+			errs.add(prefix+".cmd", r.Command, "missing command")
+		} else if r.Command[0] == "" {
+			errs.add(prefix+".cmd[0]", "", "blank executable")
 		}
-	}
 
-	for k := range s.Config.Labels {
-		if !reReverseUnlimitedFQDN.MatchString(k) {
-			return fmt.Errorf("label key %q is not in reverse domain notation", k)
+		for j, c := range r.Capabilities {
+			if err := capabilities.ValidateCapabilities([]string{c}); err != nil {
+				errs.add(fmt.Sprintf("%s.capabilities[%d]", prefix, j), c, "invalid capability %q", c)
+			}
+		}
+
+		for _, k := range sortedKeys(r.Environment) {
+			if !reArgName.MatchString(k) {
+				errs.add(prefix+".env."+fieldKey(k), k, "invalid environment variable name %q", k)
+			}
+		}
+
+		if r.User != "" && !reUserGroup.MatchString(r.User) {
+			errs.add(prefix+".user", r.User, "expected format 'USER[:GROUP]' for user %q", r.User)
+		}
+
+		if r.WorkDir != "" && !filepath.IsAbs(r.WorkDir) {
+			errs.add(prefix+".work-dir", r.WorkDir, "working directory %q is not an absolute path", r.WorkDir)
+		}
+
+		if r.Network && s.Packages.Local != nil {
+			errs.add(prefix+".network", r.Network, "network is unavailable when installing packages from a local repository")
 		}
 	}
 
-	for _, p := range s.Config.Ports {
-		if p.Number == 0 {
-			return fmt.Errorf("the zero port is reserved")
+	validateSteps(s, &errs)
+
+	return errs
+}
+
+// validateSteps asserts that the steps of a spec refer to every alteration of
+// the working container described by the spec exactly once.
+func validateSteps(s Spec, errs *ValidationErrors) {
+	referenced := map[Action][]bool{}
+	for _, a := range defaultOrder {
+		referenced[a] = make([]bool, count(s, a))
+	}
+
+	for i, st := range s.Steps {
+		prefix := fmt.Sprintf("steps[%d]", i)
+		a := st.Action.Action
+
+		if a == 0 {
+			errs.add(prefix+".action", "", "missing action")
+			continue
 		}
-		if p.Protocol.Protocol == 0 {
-			return fmt.Errorf("unknown network protocol for port %d", p.Number)
+
+		n := len(referenced[a])
+		if n == 0 {
+			errs.add(prefix+".action", a.String(), "action %q refers to nothing; check %s", a, a.Field())
+			continue
+		}
+
+		if st.Index != nil {
+			if !a.Indexed() {
+				errs.add(prefix+".index", *st.Index, "action %q doesn't take an index", a)
+				continue
+			}
+			if *st.Index < 0 || *st.Index >= n {
+				errs.add(prefix+".index", *st.Index, "index %d outside range of %s [0-%d]", *st.Index, a.Field(), n-1)
+				continue
+			}
+		}
+
+		for _, j := range st.Indices(n) {
+			if referenced[a][j] {
+				if a.Indexed() {
+					errs.add(prefix, a.String(), "%s[%d] is referred to by more than one step", a.Field(), j)
+				} else {
+					errs.add(prefix, a.String(), "%s is referred to by more than one step", a.Field())
+				}
+			}
+			referenced[a][j] = true
 		}
 	}
 
-	if s.Config.WorkDir != "" {
-		if !filepath.IsAbs(s.Config.WorkDir) {
-			return fmt.Errorf("working directory %q is not an absolute path", s.Config.WorkDir)
+	for _, a := range defaultOrder {
+		for j, ok := range referenced[a] {
+			if ok {
+				continue
+			}
+			if a.Indexed() {
+				errs.add("steps", a.String(), "%s[%d] isn't referred to by any step", a.Field(), j)
+			} else {
+				errs.add("steps", a.String(), "%s isn't referred to by any step", a.Field())
+			}
 		}
 	}
+}
 
-	return nil
+// sortedKeys returns the keys of a map in lexicographic order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateRepositories asserts that the package repositories to configure with
+// the package manager `b` are complete and satisfy domain-specific constraints.
+func validateRepositories(repositories []Repository, b pckg.Backend) ValidationErrors {
+	var errs ValidationErrors
+
+	seen := map[string]bool{}
+	for i, r := range repositories {
+		prefix := fmt.Sprintf("packages.repositories[%d]", i)
+
+		if r.Name == "" {
+			errs.add(prefix+".name", "", "missing repository name")
+		} else if !reRepositoryName.MatchString(r.Name) {
+			errs.add(prefix+".name", r.Name, "invalid repository name %q", r.Name)
+		} else if seen[r.Name] {
+			errs.add(prefix+".name", r.Name, "duplicate repository name %q", r.Name)
+		}
+		seen[r.Name] = true
+
+		if r.URL == "" {
+			errs.add(prefix+".url", "", "missing URL for repository %q", r.Name)
+		} else if u, err := url.Parse(r.URL); err != nil || u.Scheme == "" {
+			errs.add(prefix+".url", r.URL, "invalid URL %q for repository %q", r.URL, r.Name)
+		}
+
+		if b == pckg.APT {
+			if r.Suite == "" {
+				errs.add(prefix+".suite", "", "missing suite for %s repository %q", b, r.Name)
+			} else if strings.HasSuffix(r.Suite, "/") {
+				if len(r.Components) > 0 {
+					errs.add(prefix+".components", r.Components, "flat repository %q can't have components", r.Name)
+				}
+			} else if len(r.Components) == 0 {
+				errs.add(prefix+".components", r.Components, "missing components for %s repository %q", b, r.Name)
+			}
+		} else {
+			if r.Suite != "" {
+				errs.add(prefix+".suite", r.Suite, "%s doesn't support repository suites", b)
+			}
+			if len(r.Components) > 0 {
+				errs.add(prefix+".components", r.Components, "%s doesn't support repository components", b)
+			}
+		}
+
+		if r.Key != "" && !filepath.IsAbs(r.Key) {
+			errs.add(prefix+".key", r.Key, "key %q is not an absolute path", r.Key)
+		}
+
+		if r.Priority != 0 {
+			min, max := b.RepositoryPriorityRange()
+			if min == 0 && max == 0 {
+				errs.add(prefix+".priority", r.Priority, "%s doesn't support repository priorities", b)
+			} else if r.Priority < min || r.Priority > max {
+				errs.add(prefix+".priority", r.Priority, "%s repository priority %d outside allowed range [%d-%d]", b, r.Priority, min, max)
+			}
+		}
+	}
+
+	return errs
 }
 
 // validateName asserts that a name is a valid Linux user or group name
@@ -0,0 +1,675 @@
+package spec
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ok-ryoko/turret/pkg/linux"
+)
+
+func TestValidateCollectsAllErrors(t *testing.T) {
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/alpine",
+			Tag:        "3.18.2",
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		User: &User{
+			Name: "root",
+			ID:   7,
+		},
+		Copy: []Copy{
+			{
+				Base:        "/src",
+				Destination: "/dst",
+				Sources:     []string{"a", ""},
+				Owner:       "user",
+			},
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	expected := []string{
+		"from.distro",
+		"backends.package",
+		"backends.user",
+		"backends.find",
+		"user.name",
+		"user.id",
+		"copy[0].srcs[1]",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, found %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i := range expected {
+		if errs[i].Field != expected[i] {
+			t.Errorf("expected field %s at position %d, found %s", expected[i], i, errs[i].Field)
+		}
+	}
+}
+
+func TestValidateRun(t *testing.T) {
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/alpine",
+			Tag:        "3.18.2",
+			Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		Run: []Run{
+			{
+				Command:      []string{"update-ca-certificates"},
+				Capabilities: []string{"CAP_DAC_OVERRIDE"},
+				Environment:  map[string]string{"LANG": "C.UTF-8"},
+				User:         "nobody:nogroup",
+				WorkDir:      "/etc",
+			},
+			{
+				Capabilities: []string{"ALL", "CAP_NOT_A_CAPABILITY"},
+				Environment:  map[string]string{"NOT-A-NAME": ""},
+				User:         "a:b:c",
+				WorkDir:      "etc",
+			},
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	expected := []string{
+		"run[1].cmd",
+		"run[1].capabilities[0]",
+		"run[1].capabilities[1]",
+		"run[1].env.NOT-A-NAME",
+		"run[1].user",
+		"run[1].work-dir",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, found %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i := range expected {
+		if errs[i].Field != expected[i] {
+			t.Errorf("expected field %s at position %d, found %s", expected[i], i, errs[i].Field)
+		}
+	}
+}
+
+func TestValidatePackageVersions(t *testing.T) {
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/alpine",
+			Tag:        "3.18.2",
+			Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		Packages: Packages{
+			Install: PackageList{
+				"ca-certificates",
+				map[string]any{"name": "curl", "version": "8.2.1-r0"},
+				map[string]any{"name": "git", "version": "2.40.1-1"},
+				map[string]any{"name": "-jq", "version": "1.6-r3"},
+				map[string]any{"name": "tar", "release": "1.34-r3"},
+				42,
+			},
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	expected := []string{
+		"packages.install[2].version",
+		"packages.install[3].name",
+		"packages.install[4]",
+		"packages.install[5]",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, found %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i := range expected {
+		if errs[i].Field != expected[i] {
+			t.Errorf("expected field %s at position %d, found %s", expected[i], i, errs[i].Field)
+		}
+	}
+}
+
+func TestValidateRepositories(t *testing.T) {
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/debian",
+			Tag:        "12.1-slim",
+			Distro:     linux.DistroWrapper{Distro: linux.Debian},
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		Packages: Packages{
+			Repositories: []Repository{
+				{
+					Name:       "example",
+					URL:        "https://packages.example.com/debian",
+					Suite:      "bookworm",
+					Components: []string{"main"},
+					Key:        "/keys/example.asc",
+					Priority:   900,
+				},
+				{
+					Name:  "flat",
+					URL:   "file:///srv/repo",
+					Suite: "./",
+				},
+				{
+					Name:       "example",
+					URL:        "packages.example.com",
+					Suite:      "./",
+					Components: []string{"main"},
+					Key:        "keys/example.asc",
+					Priority:   40000,
+				},
+				{
+					Name: "-invalid",
+					URL:  "https://packages.example.com/debian",
+				},
+			},
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	expected := []string{
+		"packages.repositories[2].name",
+		"packages.repositories[2].url",
+		"packages.repositories[2].components",
+		"packages.repositories[2].key",
+		"packages.repositories[2].priority",
+		"packages.repositories[3].name",
+		"packages.repositories[3].suite",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, found %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i := range expected {
+		if errs[i].Field != expected[i] {
+			t.Errorf("expected field %s at position %d, found %s", expected[i], i, errs[i].Field)
+		}
+	}
+
+	if !*s.Packages.Repositories[0].Enabled {
+		t.Errorf("expected repository to be enabled by default")
+	}
+
+	if s.Steps[0].Action.Action != ActionRepositories {
+		t.Errorf("expected repositories to be configured first, found %s", s.Steps[0].Action)
+	}
+}
+
+func TestValidateInstallFiles(t *testing.T) {
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/debian",
+			Tag:        "12.1-slim",
+			Distro:     linux.DistroWrapper{Distro: linux.Debian},
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		Packages: Packages{
+			InstallFiles: []string{
+				"/dist/mytool_1.0_amd64.deb",
+				"dist/othertool_1.0_amd64.deb",
+				"/dist/mytool-1.0-1.x86_64.rpm",
+				"/other/mytool_1.0_amd64.deb",
+			},
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	expected := []string{
+		"packages.install-files[1]",
+		"packages.install-files[2]",
+		"packages.install-files[3]",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, found %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i := range expected {
+		if errs[i].Field != expected[i] {
+			t.Errorf("expected field %s at position %d, found %s", expected[i], i, errs[i].Field)
+		}
+	}
+
+	if s.Steps[0].Action.Action != ActionInstallFiles {
+		t.Errorf("expected a step that installs package files, found %s", s.Steps[0].Action)
+	}
+}
+
+func TestValidateSecurityUpgrade(t *testing.T) {
+	var w UpgradeModeWrapper
+	for text, expected := range map[string]UpgradeMode{
+		"false":    0,
+		"true":     UpgradeAll,
+		"security": UpgradeSecurity,
+	} {
+		if err := w.UnmarshalText([]byte(text)); err != nil {
+			t.Errorf("decoding upgrade mode %q: %v", text, err)
+		} else if w.UpgradeMode != expected {
+			t.Errorf("expected upgrade mode %s for %q, found %s", expected, text, w.UpgradeMode)
+		}
+	}
+	if err := w.UnmarshalText([]byte("minor")); err == nil {
+		t.Errorf("expected error decoding upgrade mode \"minor\"")
+	}
+
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/alpine",
+			Tag:        "3.18.3",
+			Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		Packages: Packages{
+			Upgrade: UpgradeModeWrapper{UpgradeSecurity},
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	if len(errs) != 1 || errs[0].Field != "packages.upgrade" {
+		t.Errorf("expected a single error for packages.upgrade, found %v", errs)
+	}
+}
+
+func TestValidateWeakDeps(t *testing.T) {
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/alpine",
+			Tag:        "3.18.3",
+			Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		Packages: Packages{
+			Install:  PackageList{"curl"},
+			WeakDeps: true,
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	if len(errs) != 1 || errs[0].Field != "packages.weak-deps" {
+		t.Errorf("expected a single error for packages.weak-deps, found %v", errs)
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	s := Fill(Spec{
+		From: From{
+			Repository: "registry.fedoraproject.org/fedora",
+			Tag:        "38",
+			Distro:     linux.DistroWrapper{Distro: linux.Fedora},
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		Packages: Packages{
+			Groups:  []string{"development-tools", "nodejs:18/common", "@c-development"},
+			Virtual: ".build-deps",
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	expected := []string{
+		"packages.groups[2]",
+		"packages.virtual",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, found %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i := range expected {
+		if errs[i].Field != expected[i] {
+			t.Errorf("expected field %s at position %d, found %s", expected[i], i, errs[i].Field)
+		}
+	}
+
+	if s.Steps[0].Action.Action != ActionInstall {
+		t.Errorf("expected a step that installs packages, found %s", s.Steps[0].Action)
+	}
+}
+
+func TestValidateLocalRepository(t *testing.T) {
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/debian",
+			Tag:        "12.1-slim",
+			Distro:     linux.DistroWrapper{Distro: linux.Debian},
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		Packages: Packages{
+			Install: PackageList{"curl"},
+			Local:   &LocalRepository{Path: "repo"},
+		},
+		Run: []Run{
+			{Command: []string{"true"}},
+			{Command: []string{"curl", "https://example.com"}, Network: true},
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	expected := []string{
+		"packages.local.path",
+		"run[1].network",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, found %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i := range expected {
+		if errs[i].Field != expected[i] {
+			t.Errorf("expected field %s at position %d, found %s", expected[i], i, errs[i].Field)
+		}
+	}
+}
+
+func TestFillDefaultSteps(t *testing.T) {
+	s := Fill(Spec{
+		Packages: Packages{
+			Install: PackageList{"ca-certificates"},
+			Remove:  []string{"vim"},
+			Clean:   true,
+		},
+		Copy: []Copy{{}, {}},
+		Security: Security{
+			SpecialFiles: SpecialFiles{RemoveS: true},
+		},
+	})
+
+	expected := []Action{ActionInstall, ActionRemove, ActionClean, ActionCopy, ActionRemoveS}
+
+	if len(s.Steps) != len(expected) {
+		t.Fatalf("expected %d steps, found %d: %v", len(expected), len(s.Steps), s.Steps)
+	}
+
+	for i, a := range expected {
+		if s.Steps[i].Action.Action != a || s.Steps[i].Index != nil {
+			t.Errorf("expected action %s at position %d, found %s", a, i, s.Steps[i].Action)
+		}
+	}
+}
+
+func TestValidateSteps(t *testing.T) {
+	one := 1
+	five := 5
+
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/alpine",
+			Tag:        "3.18.2",
+			Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+		},
+		This: This{
+			Repository: "localhost/example",
+		},
+		Packages: Packages{
+			Install: PackageList{"ca-certificates"},
+			Clean:   true,
+		},
+		Copy: []Copy{
+			{Base: "/src", Destination: "/a", Sources: []string{"a"}, Owner: "user"},
+			{Base: "/src", Destination: "/b", Sources: []string{"b"}, Owner: "user"},
+			{Base: "/src", Destination: "/c", Sources: []string{"c"}, Owner: "user"},
+		},
+		Steps: []Step{
+			{Action: ActionWrapper{ActionCopy}, Index: &one},
+			{Action: ActionWrapper{ActionInstall}},
+			{Action: ActionWrapper{ActionCopy}},
+			{Action: ActionWrapper{ActionUpgrade}},
+			{Action: ActionWrapper{ActionInstall}, Index: &one},
+			{Action: ActionWrapper{ActionCopy}, Index: &five},
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	expected := []string{
+		"steps[2]",
+		"steps[3].action",
+		"steps[4].index",
+		"steps[5].index",
+		"steps",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, found %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i := range expected {
+		if errs[i].Field != expected[i] {
+			t.Errorf("expected field %s at position %d, found %s", expected[i], i, errs[i].Field)
+		}
+	}
+}
+
+func TestStageOrder(t *testing.T) {
+	s := Spec{
+		Stages: map[string]Stage{
+			"a": {Copy: []Copy{{FromStage: "c"}}},
+			"b": {},
+			"c": {Copy: []Copy{{FromStage: "b"}}},
+		},
+	}
+
+	order, err := StageOrder(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"b", "c", "a"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected order %v, found %v", expected, order)
+	}
+
+	s.Stages["b"] = Stage{Copy: []Copy{{FromStage: "a"}}}
+	if _, err := StageOrder(s); err == nil {
+		t.Error("expected error for cyclic stages, found nil")
+	}
+}
+
+func TestValidateStages(t *testing.T) {
+	from := From{
+		Repository: "docker.io/library/alpine",
+		Tag:        "3.18.2",
+		Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+	}
+
+	s := Fill(Spec{
+		From: from,
+		This: This{
+			Repository: "localhost/example",
+		},
+		Copy: []Copy{
+			{FromStage: "builder", Destination: "/usr/local/bin", Sources: []string{"tool"}, Owner: "user"},
+			{FromStage: "missing", Destination: "/usr/local/bin", Sources: []string{"tool"}, Owner: "user"},
+		},
+		Stages: map[string]Stage{
+			"builder": {
+				From: from,
+				User: &User{Name: "root"},
+			},
+		},
+	})
+
+	if s.Copy[0].Base != "/" {
+		t.Errorf("expected base / for copy from stage, found %q", s.Copy[0].Base)
+	}
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	expected := []string{
+		"copy[1].from-stage",
+		"stages.builder.user.name",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, found %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i := range expected {
+		if errs[i].Field != expected[i] {
+			t.Errorf("expected field %s at position %d, found %s", expected[i], i, errs[i].Field)
+		}
+	}
+}
+
+func TestValidateDestinations(t *testing.T) {
+	s := Fill(Spec{
+		From: From{
+			Repository: "docker.io/library/alpine",
+			Tag:        "3.18.3",
+			Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+		},
+		This: This{
+			Repository: "localhost/example",
+			Destinations: []string{
+				"docker://registry.example.com/example:1.0",
+				"oci-archive:/nonexistent/example.tar",
+				"docker://Registry.example.com/Example",
+				"s3://bucket/example",
+			},
+		},
+	})
+
+	err := Validate(s)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, found %v", err)
+	}
+
+	if len(errs) != 2 || errs[0].Field != "this.destinations[2]" || errs[1].Field != "this.destinations[3]" {
+		t.Errorf("expected errors for this.destinations[2] and this.destinations[3], found %v", errs)
+	}
+}
+
+func TestValidateCompression(t *testing.T) {
+	level := func(l int) *int {
+		return &l
+	}
+
+	cases := []struct {
+		this   This
+		fields []string
+	}{
+		{This{Compression: CompressionWrapper{Zstd}, CompressionLevel: level(19)}, nil},
+		{This{Compression: CompressionWrapper{ZstdChunked}, Format: ImageFormatWrapper{Docker}}, []string{"this.compression"}},
+		{This{Format: ImageFormatWrapper{Docker}, CompressionLevel: level(10)}, []string{"this.compression-level"}},
+		{This{Compression: CompressionWrapper{Uncompressed}, CompressionLevel: level(1)}, []string{"this.compression-level"}},
+	}
+
+	for _, c := range cases {
+		c.this.Repository = "localhost/example"
+		s := Fill(Spec{
+			From: From{
+				Repository: "docker.io/library/alpine",
+				Tag:        "3.18.3",
+				Distro:     linux.DistroWrapper{Distro: linux.Alpine},
+			},
+			This: c.this,
+		})
+
+		var fields []string
+		var errs ValidationErrors
+		if err := Validate(s); errors.As(err, &errs) {
+			for _, e := range errs {
+				fields = append(fields, e.Field)
+			}
+		} else if err != nil {
+			t.Fatalf("expected ValidationErrors, found %v", err)
+		}
+
+		if !reflect.DeepEqual(fields, c.fields) {
+			t.Errorf("expected errors for %v with compression %s and format %s, found %v", c.fields, c.this.Compression, c.this.Format, errs)
+		}
+	}
+}
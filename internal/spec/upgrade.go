@@ -0,0 +1,73 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	UpgradeAll UpgradeMode = 1 << iota
+	UpgradeSecurity
+)
+
+// UpgradeMode is a unique identifier for a way of upgrading pre-installed
+// packages. The zero value represents not upgrading packages.
+type UpgradeMode uint
+
+// String returns a string containing the stylized name of the upgrade mode.
+func (m UpgradeMode) String() string {
+	var s string
+	switch m {
+	case 0:
+		s = "none"
+	case UpgradeAll:
+		s = "all"
+	case UpgradeSecurity:
+		s = "security"
+	default:
+		s = "unknown"
+	}
+	return s
+}
+
+// UpgradeModeWrapper wraps UpgradeMode to facilitate its parsing from
+// serialized data, where it's either a boolean or the string "security".
+type UpgradeModeWrapper struct {
+	UpgradeMode
+}
+
+// UnmarshalText decodes the upgrade mode from the UTF-8-encoded text of a
+// boolean or a string.
+func (w *UpgradeModeWrapper) UnmarshalText(text []byte) error {
+	var err error
+	w.UpgradeMode, err = parseUpgradeModeString(string(text))
+	return err
+}
+
+// Schema returns the JSON Schema for the upgrade mode.
+func (w UpgradeModeWrapper) Schema() map[string]any {
+	return map[string]any{
+		"anyOf": []any{
+			map[string]any{"type": "boolean"},
+			map[string]any{"const": "security"},
+		},
+	}
+}
+
+func parseUpgradeModeString(s string) (UpgradeMode, error) {
+	var m UpgradeMode
+	switch strings.ToLower(s) {
+	case "false":
+		m = 0
+	case "true":
+		m = UpgradeAll
+	case "security":
+		m = UpgradeSecurity
+	default:
+		return 0, fmt.Errorf("unsupported upgrade mode %q; expected true, false or \"security\"", s)
+	}
+	return m, nil
+}
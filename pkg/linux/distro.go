@@ -116,6 +116,11 @@ func (w *DistroWrapper) UnmarshalText(text []byte) error {
 	return err
 }
 
+// Enum returns the identifiers from which the distro can be decoded.
+func (w DistroWrapper) Enum() []string {
+	return []string{"alpine", "arch", "chimera", "debian", "fedora", "opensuse", "void"}
+}
+
 func parseDistroString(s string) (Distro, error) {
 	var d Distro
 	switch strings.ToLower(s) {
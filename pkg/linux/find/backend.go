@@ -46,6 +46,11 @@ func (w *BackendWrapper) UnmarshalText(text []byte) error {
 	return err
 }
 
+// Enum returns the identifiers from which the finder can be decoded.
+func (w BackendWrapper) Enum() []string {
+	return []string{"bsd", "busybox", "gnu"}
+}
+
 func parseBackendString(s string) (Backend, error) {
 	var b Backend
 	switch strings.ToLower(s) {
@@ -38,6 +38,91 @@ func (b Backend) RePackageName() string {
 	return r
 }
 
+// RePackageVersion returns a regular expression to match valid package
+// versions for the package manager's ecosystem.
+func (b Backend) RePackageVersion() string {
+	var r string
+	switch b {
+	case APK:
+		r = `^[0-9]+(\.[0-9]+)*[a-z]?(_(alpha|beta|pre|rc|cvs|svn|git|hg|p)[0-9]*)*(-r[0-9]+)?$`
+	case APT:
+		r = `^([0-9]+:)?[0-9][+\-.0-9:A-Za-z~]*$`
+	case DNF, Zypper:
+		r = `^([0-9]+:)?[0-9A-Za-z][+.0-9A-Z^_a-z~]*(-[0-9A-Za-z][+.0-9A-Z^_a-z~]*)?$`
+	case Pacman:
+		r = `^([0-9]+:)?[0-9A-Za-z][+.0-9A-Z_a-z]*(-[0-9]+(\.[0-9]+)?)?$`
+	case XBPS:
+		r = `^[0-9A-Za-z][+.0-9A-Za-z~]*_[0-9]+$`
+	default:
+		r = ""
+	}
+	return r
+}
+
+// ReGroupName returns a regular expression to match valid package group names
+// for the package manager's ecosystem, i.e., the names of DNF groups and module
+// streams (NAME:STREAM, optionally followed by /PROFILE), zypper patterns,
+// pacman groups and APT tasks, or an empty string if the package manager has
+// no package groups.
+func (b Backend) ReGroupName() string {
+	var r string
+	switch b {
+	case APT:
+		r = `^[0-9a-z][+\-.0-9a-z]*[0-9a-z]$`
+	case DNF:
+		r = `^[0-9A-Za-z][+\-.0-9A-Z_a-z]*(:[0-9A-Za-z][+\-.0-9A-Z_a-z]*(/[0-9A-Za-z][\-.0-9A-Z_a-z]*)?)?$`
+	case Pacman:
+		r = `^[0-9a-z][+\-.0-9_a-z]*[0-9a-z]$`
+	case Zypper:
+		r = `^[0-9A-Za-z][+\-.0-9A-Z_a-z]*[0-9A-Za-z]$`
+	default:
+		r = ""
+	}
+	return r
+}
+
+// IsPackageFile reports whether a file name has the extension of the package
+// manager's package files.
+func (b Backend) IsPackageFile(name string) bool {
+	switch b {
+	case APK:
+		return strings.HasSuffix(name, ".apk")
+	case APT:
+		return strings.HasSuffix(name, ".deb")
+	case DNF, Zypper:
+		return strings.HasSuffix(name, ".rpm")
+	case Pacman:
+		return strings.Contains(name, ".pkg.tar") && !strings.HasSuffix(name, ".sig")
+	case XBPS:
+		return strings.HasSuffix(name, ".xbps")
+	default:
+		return false
+	}
+}
+
+// HasWeakDeps reports whether the package manager distinguishes weak
+// dependencies, i.e., packages that are installed together with a package by
+// default but that the package doesn't need to work.
+func (b Backend) HasWeakDeps() bool {
+	switch b {
+	case APT, DNF, Zypper:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasSecurityUpgrades reports whether the package manager can restrict upgrades
+// to the packages with security fixes.
+func (b Backend) HasSecurityUpgrades() bool {
+	switch b {
+	case APT, DNF, Zypper:
+		return true
+	default:
+		return false
+	}
+}
+
 // String returns a string containing the stylized name of the package manager.
 func (b Backend) String() string {
 	var s string
@@ -72,6 +157,11 @@ func (w *BackendWrapper) UnmarshalText(text []byte) error {
 	return err
 }
 
+// Enum returns the identifiers from which the package manager can be decoded.
+func (w BackendWrapper) Enum() []string {
+	return []string{"apk", "apt", "dnf", "pacman", "xbps", "zypper"}
+}
+
 func parseBackendString(s string) (Backend, error) {
 	var b Backend
 	switch strings.ToLower(s) {
@@ -14,14 +14,60 @@ type CommandFactory interface {
 
 	// NewInstallCmd returns (1) a command that installs one or more packages
 	// and (2) the Linux capabilities needed by that command.
-	NewInstallCmd(packages []string) (cmd, capabilities []string)
+	NewInstallCmd(packages []string, options InstallOptions) (cmd, capabilities []string)
+
+	// NewInstallGroupsCmd returns (1) a command that installs one or more
+	// package groups, i.e., DNF groups and module streams, zypper patterns,
+	// pacman groups or APT tasks, and (2) the Linux capabilities needed by
+	// that command. The command is empty if the package manager has no
+	// package groups.
+	NewInstallGroupsCmd(groups []string, options InstallOptions) (cmd, capabilities []string)
+
+	// NewDownloadCmds returns (1) the commands that download to the directory
+	// `dir` the files of the packages that installing one or more packages
+	// would install, including missing dependencies, and (2) the Linux
+	// capabilities needed by those commands.
+	NewDownloadCmds(packages []string, dir string, options InstallOptions) (cmds [][]string, capabilities []string)
+
+	// NewInstallFilesCmds returns (1) the commands that install the packages
+	// in one or more package files, which must share a directory, and (2) the
+	// Linux capabilities needed by those commands. If `offline` is true, then
+	// the commands resolve dependencies using only the package files and the
+	// installed packages, reading no repository.
+	NewInstallFilesCmds(files []string, offline bool, options InstallOptions) (cmds [][]string, capabilities []string)
 
 	// NewListInstalledPackagesCmd returns:
 	//
 	//   (1) a command that lists the installed packages;
 	//   (2) the Linux capabilities needed by that command, and
-	//   (3) a function to parse the package names from the command's output.
-	NewListInstalledPackagesCmd() (cmd, capabilities []string, parse func([]string) ([]string, error))
+	//   (3) a function to parse the packages from the command's output.
+	NewListInstalledPackagesCmd() (cmd, capabilities []string, parse func([]string) ([]Package, error))
+
+	// NewRemoveCmd returns (1) a command that removes one or more packages
+	// together with the dependencies that no other package needs and (2) the
+	// Linux capabilities needed by that command.
+	NewRemoveCmd(packages []string) (cmd, capabilities []string)
+
+	// Pin returns the argument that selects the given version of a package in
+	// the package manager's install command.
+	Pin(name, version string) string
+
+	// KeyPath returns the absolute path in the working container at which to
+	// place the key with which the repository `name` is signed, where `file`
+	// is the path to the key on the host.
+	KeyPath(name, file string) string
+
+	// NewAddRepositoryCmds returns (1) the files to write to the working
+	// container to configure a package repository, (2) the commands to run
+	// after writing the files and (3) the Linux capabilities needed by those
+	// commands.
+	NewAddRepositoryCmds(r Repository) (files []File, cmds [][]string, capabilities []string)
+
+	// LocalRepositoryOptions returns (1) the options that restrict the package
+	// manager to a local repository, which follow the executable in the
+	// commands that resolve packages, and (2) the files to which the options
+	// refer, which must be placed in the repository's configuration directory.
+	LocalRepositoryOptions(r LocalRepository) (options []string, files []File)
 
 	// NewUpdateIndexCmd returns (1) a command that updates the package index
 	// and (2) the Linux capabilities needed by that command.
@@ -31,11 +77,31 @@ type CommandFactory interface {
 	// and (2) the Linux capabilities needed by that command.
 	NewUpgradeCmd() (cmd, capabilities []string)
 
+	// NewSecurityUpgradeCmds returns (1) the temporary files to write to the
+	// working container before running the commands and to remove afterwards,
+	// (2) the commands that upgrade only the pre-installed packages with
+	// security fixes and (3) the Linux capabilities needed by those commands.
+	// The commands are empty if the package manager has no notion of security
+	// fixes.
+	NewSecurityUpgradeCmds() (files []File, cmds [][]string, capabilities []string)
+
 	// Backend returns a constant representing the package manager for which
 	// this factory makes commands.
 	Backend() Backend
 }
 
+// InstallOptions holds options for installing packages.
+type InstallOptions struct {
+	// Install the weak dependencies of packages, i.e., the packages that APT
+	// and zypper call recommended and DNF calls weak dependencies; apk,
+	// pacman and XBPS have no such dependencies
+	WeakDeps bool
+
+	// Name of a virtual package to create that depends on the installed
+	// packages so that removing it removes them; only used by apk
+	Virtual string
+}
+
 // NewCommandFactory creates an object that manufactures package management
 // commands for execution in a shell.
 func NewCommandFactory(b Backend) (CommandFactory, error) {
@@ -5,6 +5,7 @@ package pckg
 
 import (
 	"fmt"
+	"path"
 	"strings"
 )
 
@@ -14,16 +15,47 @@ func (f APKCommandFactory) NewCleanCacheCmd() (cmd, capabilities []string) {
 	return []string{}, []string{}
 }
 
-func (f APKCommandFactory) NewInstallCmd(packages []string) (cmd, capabilities []string) {
+func (f APKCommandFactory) NewInstallCmd(packages []string, options InstallOptions) (cmd, capabilities []string) {
 	cmd = []string{"apk", "--no-cache", "--no-progress", "--quiet", "add"}
+	cmd = append(cmd, apkVirtualOptions(options)...)
 	cmd = append(cmd, packages...)
 	return cmd, []string{}
 }
 
+func (f APKCommandFactory) NewInstallGroupsCmd(groups []string, options InstallOptions) (cmd, capabilities []string) {
+	return []string{}, []string{}
+}
+
+func (f APKCommandFactory) NewDownloadCmds(packages []string, dir string, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"apk", "--no-cache", "--no-progress", "--quiet", "fetch", "--recursive", "--output", dir}
+	cmd = append(cmd, packages...)
+	return [][]string{cmd}, []string{}
+}
+
+func (f APKCommandFactory) NewInstallFilesCmds(files []string, offline bool, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"apk", "--no-cache", "--no-progress", "--quiet", "--allow-untrusted"}
+	if offline {
+		cmd = append(cmd, "--repositories-file", "/dev/null")
+	}
+	cmd = append(cmd, "add")
+	cmd = append(cmd, apkVirtualOptions(options)...)
+	cmd = append(cmd, files...)
+	return [][]string{cmd}, []string{}
+}
+
+// apkVirtualOptions returns the options that make apk create a virtual package
+// depending on the packages it installs, if any.
+func apkVirtualOptions(options InstallOptions) []string {
+	if options.Virtual == "" {
+		return []string{}
+	}
+	return []string{"--virtual", options.Virtual}
+}
+
 func (f APKCommandFactory) NewListInstalledPackagesCmd() (
 	cmd []string,
 	capabilities []string,
-	parse func([]string) ([]string, error),
+	parse func([]string) ([]Package, error),
 ) {
 	cmd = []string{
 		"apk",
@@ -35,13 +67,14 @@ func (f APKCommandFactory) NewListInstalledPackagesCmd() (
 	}
 
 	// expected line format: name-version-revision arch {origin} (licenses) [status]
-	parse = func(lines []string) ([]string, error) {
-		result := make([]string, 0, len(lines))
+	parse = func(lines []string) ([]Package, error) {
+		result := make([]Package, 0, len(lines))
 		for _, l := range lines {
-			pkg, _, ok := strings.Cut(l, " ")
-			if !ok {
-				return nil, fmt.Errorf("expected space delimiter in line %q", l)
+			f := strings.Fields(l)
+			if len(f) < 2 {
+				return nil, fmt.Errorf("expected at least 2 fields in line %q", l)
 			}
+			pkg := f[0]
 			i := strings.LastIndex(pkg, "-")
 			if i == -1 {
 				return nil, fmt.Errorf("expected format 'name-version-revision' for field %q", pkg)
@@ -50,8 +83,24 @@ func (f APKCommandFactory) NewListInstalledPackagesCmd() (
 			if j == -1 {
 				return nil, fmt.Errorf("expected format 'name-version-revision' for field %q", pkg)
 			}
-			name := pkg[:j]
-			result = append(result, name)
+			p := Package{
+				Name:         pkg[:j],
+				Version:      pkg[j+1:],
+				Architecture: f[1],
+			}
+			rest := strings.Join(f[2:], " ")
+			if strings.HasPrefix(rest, "{") {
+				if k := strings.Index(rest, "}"); k != -1 {
+					p.Source = rest[1:k]
+					rest = strings.TrimSpace(rest[k+1:])
+				}
+			}
+			if strings.HasPrefix(rest, "(") {
+				if k := strings.LastIndex(rest, ")"); k != -1 {
+					p.License = rest[1:k]
+				}
+			}
+			result = append(result, p)
 		}
 		return result, nil
 	}
@@ -59,6 +108,39 @@ func (f APKCommandFactory) NewListInstalledPackagesCmd() (
 	return cmd, []string{}, parse
 }
 
+func (f APKCommandFactory) NewRemoveCmd(packages []string) (cmd, capabilities []string) {
+	cmd = []string{"apk", "--no-cache", "--no-progress", "--quiet", "del"}
+	cmd = append(cmd, packages...)
+	return cmd, []string{}
+}
+
+func (f APKCommandFactory) Pin(name, version string) string {
+	return name + "=" + version
+}
+
+// KeyPath preserves the base name of the key file because apk looks up the
+// key with which an index is signed by name.
+func (f APKCommandFactory) KeyPath(name, file string) string {
+	return path.Join("/etc/apk/keys", path.Base(file))
+}
+
+func (f APKCommandFactory) NewAddRepositoryCmds(r Repository) (files []File, cmds [][]string, capabilities []string) {
+	line := r.URL + "\n"
+	if !r.Enabled {
+		line = disable(line)
+	}
+	files = []File{{Path: "/etc/apk/repositories", Contents: line, Append: true}}
+	return files, [][]string{}, []string{}
+}
+
+func (f APKCommandFactory) LocalRepositoryOptions(r LocalRepository) (options []string, files []File) {
+	options = []string{"--repositories-file", "/dev/null", "--repository", r.Path}
+	if r.Trusted {
+		options = append(options, "--allow-untrusted")
+	}
+	return options, []File{}
+}
+
 func (f APKCommandFactory) NewUpdateIndexCmd() (cmd, capabilities []string) {
 	return []string{}, []string{}
 }
@@ -68,6 +150,10 @@ func (f APKCommandFactory) NewUpgradeCmd() (cmd, capabilities []string) {
 	return cmd, []string{}
 }
 
+func (f APKCommandFactory) NewSecurityUpgradeCmds() (files []File, cmds [][]string, capabilities []string) {
+	return []File{}, [][]string{}, []string{}
+}
+
 func (f APKCommandFactory) Backend() Backend {
 	return APK
 }
@@ -44,8 +44,19 @@ func TestParseAPKPackages(t *testing.T) {
 	}
 
 	for i := range expected {
-		if actual[i] != expected[i] {
-			t.Errorf("expected package %s at position %d, found %s", expected[i], i, actual[i])
+		if actual[i].Name != expected[i] {
+			t.Errorf("expected package %s at position %d, found %s", expected[i], i, actual[i].Name)
 		}
 	}
+
+	first := Package{
+		Name:         "alpine-baselayout",
+		Version:      "3.4.3-r1",
+		Architecture: "x86_64",
+		License:      "GPL-2.0-only",
+		Source:       "alpine-baselayout",
+	}
+	if actual[0] != first {
+		t.Errorf("expected first package %+v, found %+v", first, actual[0])
+	}
 }
@@ -3,6 +3,32 @@
 
 package pckg
 
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	// Absolute path to the temporary APT preferences file that restricts
+	// upgrades to the security suites
+	aptSecurityPreferencesPath string = "/tmp/turret-security.pref"
+
+	// APT preferences that lower the priority of every release except the
+	// security suites, e.g., bookworm-security, below that of the installed
+	// packages so that only security fixes are candidates for upgrade; APT
+	// uses the first general record that matches a release
+	aptSecurityPreferences string = `Package: *
+Pin: release a=*-security
+Pin-Priority: 500
+
+Package: *
+Pin: release o=*
+Pin-Priority: 50
+`
+)
+
 type APTCommandFactory struct{}
 
 func (f APTCommandFactory) NewCleanCacheCmd() (cmd, capabilities []string) {
@@ -15,8 +41,8 @@ func (f APTCommandFactory) NewCleanCacheCmd() (cmd, capabilities []string) {
 	return cmd, capabilities
 }
 
-func (f APTCommandFactory) NewInstallCmd(packages []string) (cmd, capabilities []string) {
-	cmd = []string{"apt", "--quiet", "--yes", "install"}
+func (f APTCommandFactory) NewInstallCmd(packages []string, options InstallOptions) (cmd, capabilities []string) {
+	cmd = []string{"apt", "--quiet", "--yes", "install", aptRecommendsOption(options)}
 	cmd = append(cmd, packages...)
 	capabilities = []string{
 		"CAP_CHOWN",
@@ -28,21 +54,172 @@ func (f APTCommandFactory) NewInstallCmd(packages []string) (cmd, capabilities [
 	return cmd, capabilities
 }
 
+// NewInstallGroupsCmd installs APT tasks by suffixing their names with a caret.
+func (f APTCommandFactory) NewInstallGroupsCmd(groups []string, options InstallOptions) (cmd, capabilities []string) {
+	tasks := make([]string, len(groups))
+	for i, g := range groups {
+		tasks[i] = g + "^"
+	}
+	return f.NewInstallCmd(tasks, options)
+}
+
+// NewDownloadCmds creates the partial directory in `dir` because APT fails
+// when it's missing from a custom archive directory.
+func (f APTCommandFactory) NewDownloadCmds(packages []string, dir string, options InstallOptions) (cmds [][]string, capabilities []string) {
+	download := []string{"apt-get", "--quiet", "--yes", "--download-only", "-o", "Dir::Cache::Archives=" + dir, "install", aptRecommendsOption(options)}
+	download = append(download, packages...)
+	cmds = [][]string{
+		{"apt", "--quiet", "update"},
+		{"mkdir", "-p", path.Join(dir, "partial")},
+		download,
+	}
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_FOWNER",
+		"CAP_SETGID",
+		"CAP_SETUID",
+	}
+	return cmds, capabilities
+}
+
+// NewInstallFilesCmds ignores `offline` because APT reads only the package
+// lists already in the working container when installing package files.
+func (f APTCommandFactory) NewInstallFilesCmds(files []string, offline bool, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"apt", "--quiet", "--yes", "install", aptRecommendsOption(options)}
+	cmd = append(cmd, files...)
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_FOWNER",
+		"CAP_SETGID",
+		"CAP_SETUID",
+	}
+	return [][]string{cmd}, capabilities
+}
+
+// aptRecommendsOption returns the option that makes APT install or skip the
+// recommended packages of the packages it installs.
+func aptRecommendsOption(options InstallOptions) string {
+	if options.WeakDeps {
+		return "--install-recommends"
+	}
+	return "--no-install-recommends"
+}
+
 func (f APTCommandFactory) NewListInstalledPackagesCmd() (
 	cmd []string,
 	capabilities []string,
-	parse func([]string) ([]string, error),
+	parse func([]string) ([]Package, error),
 ) {
-	cmd = []string{"apt-cache", "pkgnames"}
+	cmd = []string{
+		"dpkg-query",
+		"--show",
+		"--showformat=${db:Status-Abbrev}\t${Package}\t${Version}\t${Architecture}\t${source:Package}\n",
+	}
 
-	// expected line format: name
-	parse = func(lines []string) ([]string, error) {
-		return lines, nil
+	// expected line format: status\tname\tversion\tarch\tsource
+	//
+	// dpkg doesn't record licenses
+	//
+	parse = func(lines []string) ([]Package, error) {
+		result := make([]Package, 0, len(lines))
+		for _, l := range lines {
+			f := strings.Split(l, "\t")
+			if len(f) != 5 {
+				return nil, fmt.Errorf("expected 5 tab-delimited fields in line %q", l)
+			}
+			// The second character of the abbreviated status is the package's
+			// state; the first is its selection, e.g., "h" for held packages
+			if len(f[0]) < 2 || f[0][1] != 'i' {
+				continue
+			}
+			result = append(result, Package{
+				Name:         f[1],
+				Version:      f[2],
+				Architecture: f[3],
+				Source:       f[4],
+			})
+		}
+		return result, nil
 	}
 
 	return cmd, []string{}, parse
 }
 
+func (f APTCommandFactory) NewRemoveCmd(packages []string) (cmd, capabilities []string) {
+	cmd = []string{"apt", "--quiet", "--yes", "--autoremove", "purge"}
+	cmd = append(cmd, packages...)
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_FOWNER",
+		"CAP_SETGID",
+		"CAP_SETUID",
+	}
+	return cmd, capabilities
+}
+
+func (f APTCommandFactory) Pin(name, version string) string {
+	return name + "=" + version
+}
+
+// KeyPath preserves the extension of ASCII-armored keys, which APT reads only
+// from files ending in .asc.
+func (f APTCommandFactory) KeyPath(name, file string) string {
+	ext := ".gpg"
+	if path.Ext(file) == ".asc" {
+		ext = ".asc"
+	}
+	return path.Join("/etc/apt/keyrings", name+ext)
+}
+
+func (f APTCommandFactory) NewAddRepositoryCmds(r Repository) (files []File, cmds [][]string, capabilities []string) {
+	var b strings.Builder
+	b.WriteString("Types: deb\n")
+	fmt.Fprintf(&b, "URIs: %s\n", r.URL)
+	fmt.Fprintf(&b, "Suites: %s\n", r.Suite)
+	if len(r.Components) > 0 {
+		fmt.Fprintf(&b, "Components: %s\n", strings.Join(r.Components, " "))
+	}
+	if r.Key != "" {
+		fmt.Fprintf(&b, "Signed-By: %s\n", r.Key)
+	}
+	if !r.Enabled {
+		b.WriteString("Enabled: no\n")
+	}
+	files = []File{{Path: path.Join("/etc/apt/sources.list.d", r.Name+".sources"), Contents: b.String()}}
+
+	if r.Priority != 0 {
+		host := r.URL
+		if u, err := url.Parse(r.URL); err == nil && u.Hostname() != "" {
+			host = u.Hostname()
+		}
+		files = append(files, File{
+			Path:     path.Join("/etc/apt/preferences.d", r.Name+".pref"),
+			Contents: fmt.Sprintf("Package: *\nPin: origin %q\nPin-Priority: %d\n", host, r.Priority),
+		})
+	}
+
+	return files, [][]string{}, []string{}
+}
+
+// LocalRepositoryOptions replaces the sources of APT with a flat repository
+// and keeps the package lists of the replaced sources.
+func (f APTCommandFactory) LocalRepositoryOptions(r LocalRepository) (options []string, files []File) {
+	sourceList := path.Join(r.ConfigDir, "sources.list")
+	options = []string{
+		"-o", "Dir::Etc::SourceList=" + sourceList,
+		"-o", "Dir::Etc::SourceParts=-",
+		"-o", "APT::Get::List-Cleanup=0",
+	}
+	line := fmt.Sprintf("deb file:%s ./\n", r.Path)
+	if r.Trusted {
+		line = fmt.Sprintf("deb [trusted=yes] file:%s ./\n", r.Path)
+	}
+	return options, []File{{Path: sourceList, Contents: line}}
+}
+
 func (f APTCommandFactory) NewUpdateIndexCmd() (cmd, capabilities []string) {
 	cmd = []string{"apt", "--quiet", "update"}
 	capabilities = []string{
@@ -67,6 +244,16 @@ func (f APTCommandFactory) NewUpgradeCmd() (cmd, capabilities []string) {
 	return cmd, capabilities
 }
 
+// NewSecurityUpgradeCmds upgrades the packages using temporary preferences that
+// restrict the candidates to the security suites.
+func (f APTCommandFactory) NewSecurityUpgradeCmds() (files []File, cmds [][]string, capabilities []string) {
+	files = []File{{Path: aptSecurityPreferencesPath, Contents: aptSecurityPreferences}}
+	cmd, capabilities := f.NewUpgradeCmd()
+	options := []string{"-o", "Dir::Etc::Preferences=" + aptSecurityPreferencesPath}
+	cmd = append(cmd[:1], append(options, cmd[1:]...)...)
+	return files, [][]string{cmd}, capabilities
+}
+
 func (f APTCommandFactory) Backend() Backend {
 	return APT
 }
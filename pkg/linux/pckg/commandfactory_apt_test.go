@@ -2,6 +2,7 @@ package pckg
 
 import (
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -21,95 +22,548 @@ func TestParseAPTPackages(t *testing.T) {
 		t.Fatalf("parsing packages from test data: %v", err)
 	}
 
-	expected := []string{
-		"dpkg",
-		"libsmartcols1",
-		"libpam-runtime",
-		"coreutils",
-		"libext2fs2",
-		"libffi8",
-		"libaudit-common",
-		"apt",
-		"libnettle8",
-		"libacl1",
-		"libtinfo6",
-		"tzdata",
-		"libunistring2",
-		"libidn2-0",
-		"libtasn1-6",
-		"libselinux1",
-		"liblzma5",
-		"sed",
-		"tar",
-		"base-passwd",
-		"libdb5.3",
-		"libp11-kit0",
-		"libapt-pkg6.0",
-		"debconf",
-		"libsystemd0",
-		"libmount1",
-		"debianutils",
-		"libsepol2",
-		"libzstd1",
-		"util-linux",
-		"util-linux-extra",
-		"libudev1",
-		"libgpg-error0",
-		"usr-is-merged",
-		"debian-archive-keyring",
-		"libcom-err2",
-		"diffutils",
-		"libcap2",
-		"libc6",
-		"libpam-modules",
-		"libuuid1",
-		"gpgv",
-		"bash",
-		"libgcrypt20",
-		"grep",
-		"mawk",
-		"libcap-ng0",
-		"libsemanage2",
-		"base-files",
-		"ncurses-base",
-		"gzip",
-		"login",
-		"hostname",
-		"adduser",
-		"findutils",
-		"libgmp10",
-		"libxxhash0",
-		"libpcre2-8-0",
-		"libcrypt1",
-		"libpam-modules-bin",
-		"init-system-helpers",
-		"libsemanage-common",
-		"libseccomp2",
-		"mount",
-		"perl-base",
-		"libpam0g",
-		"libc-bin",
-		"libattr1",
-		"libaudit1",
-		"libhogweed6",
-		"libblkid1",
-		"libgnutls30",
-		"sysvinit-utils",
-		"logsave",
-		"liblz4-1",
-		"ncurses-bin",
-		"libbz2-1.0",
-		"zlib1g",
-		"gcc-12-base",
-		"libmd0",
-		"dash",
-		"bsdutils",
-		"libss2",
-		"libstdc++6",
-		"libdebconfclient0",
-		"e2fsprogs",
-		"passwd",
-		"libgcc-s1",
+	expected := []Package{
+		{Name: "adduser", Version: "3.134", Architecture: "all", Source: "adduser"},
+		{Name: "appstream", Version: "0.16.1-2", Architecture: "amd64", Source: "appstream"},
+		{Name: "apt", Version: "2.6.1", Architecture: "amd64", Source: "apt"},
+		{Name: "apt-transport-https", Version: "2.6.1", Architecture: "all", Source: "apt"},
+		{Name: "base-files", Version: "12.4+deb12u12", Architecture: "amd64", Source: "base-files"},
+		{Name: "base-passwd", Version: "3.6.1", Architecture: "amd64", Source: "base-passwd"},
+		{Name: "bash", Version: "5.2.15-2+b9", Architecture: "amd64", Source: "bash"},
+		{Name: "binfmt-support", Version: "2.2.2-2", Architecture: "amd64", Source: "binfmt-support"},
+		{Name: "binutils", Version: "2.40-2", Architecture: "amd64", Source: "binutils"},
+		{Name: "binutils-common", Version: "2.40-2", Architecture: "amd64", Source: "binutils"},
+		{Name: "binutils-x86-64-linux-gnu", Version: "2.40-2", Architecture: "amd64", Source: "binutils"},
+		{Name: "bsdutils", Version: "1:2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "build-essential", Version: "12.9", Architecture: "amd64", Source: "build-essential"},
+		{Name: "bzip2", Version: "1.0.8-5+b1", Architecture: "amd64", Source: "bzip2"},
+		{Name: "bzip2-doc", Version: "1.0.8-5", Architecture: "all", Source: "bzip2"},
+		{Name: "ca-certificates", Version: "20230311+deb12u1", Architecture: "all", Source: "ca-certificates"},
+		{Name: "coreutils", Version: "9.1-1", Architecture: "amd64", Source: "coreutils"},
+		{Name: "cpp", Version: "4:12.2.0-3", Architecture: "amd64", Source: "gcc-defaults"},
+		{Name: "cpp-12", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "curl", Version: "7.88.1-10+deb12u14", Architecture: "amd64", Source: "curl"},
+		{Name: "dash", Version: "0.5.12-2", Architecture: "amd64", Source: "dash"},
+		{Name: "dbus", Version: "1.14.10-1~deb12u1", Architecture: "amd64", Source: "dbus"},
+		{Name: "dbus-bin", Version: "1.14.10-1~deb12u1", Architecture: "amd64", Source: "dbus"},
+		{Name: "dbus-daemon", Version: "1.14.10-1~deb12u1", Architecture: "amd64", Source: "dbus"},
+		{Name: "dbus-session-bus-common", Version: "1.14.10-1~deb12u1", Architecture: "all", Source: "dbus"},
+		{Name: "dbus-system-bus-common", Version: "1.14.10-1~deb12u1", Architecture: "all", Source: "dbus"},
+		{Name: "dbus-user-session", Version: "1.14.10-1~deb12u1", Architecture: "amd64", Source: "dbus"},
+		{Name: "debconf", Version: "1.5.82", Architecture: "all", Source: "debconf"},
+		{Name: "debian-archive-keyring", Version: "2023.3+deb12u2", Architecture: "all", Source: "debian-archive-keyring"},
+		{Name: "debianutils", Version: "5.7-0.5~deb12u1", Architecture: "amd64", Source: "debianutils"},
+		{Name: "diffutils", Version: "1:3.8-4", Architecture: "amd64", Source: "diffutils"},
+		{Name: "dirmngr", Version: "2.2.40-1.1+deb12u1", Architecture: "amd64", Source: "gnupg2"},
+		{Name: "distro-info-data", Version: "0.58+deb12u5", Architecture: "all", Source: "distro-info-data"},
+		{Name: "dmsetup", Version: "2:1.02.185-2", Architecture: "amd64", Source: "lvm2"},
+		{Name: "dpkg", Version: "1.21.22", Architecture: "amd64", Source: "dpkg"},
+		{Name: "dpkg-dev", Version: "1.21.22", Architecture: "all", Source: "dpkg"},
+		{Name: "e2fsprogs", Version: "1.47.0-2+b2", Architecture: "amd64", Source: "e2fsprogs"},
+		{Name: "fakeroot", Version: "1.31-1.2", Architecture: "amd64", Source: "fakeroot"},
+		{Name: "findutils", Version: "4.9.0-4", Architecture: "amd64", Source: "findutils"},
+		{Name: "fontconfig-config", Version: "2.14.1-4", Architecture: "amd64", Source: "fontconfig"},
+		{Name: "fonts-dejavu-core", Version: "2.37-6", Architecture: "all", Source: "fonts-dejavu"},
+		{Name: "freeglut3-dev", Version: "3.4.0-1", Architecture: "amd64", Source: "freeglut"},
+		{Name: "g++", Version: "4:12.2.0-3", Architecture: "amd64", Source: "gcc-defaults"},
+		{Name: "g++-12", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "gcc", Version: "4:12.2.0-3", Architecture: "amd64", Source: "gcc-defaults"},
+		{Name: "gcc-12", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "gcc-12-base", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "gir1.2-glib-2.0", Version: "1.74.0-3", Architecture: "amd64", Source: "gobject-introspection"},
+		{Name: "gir1.2-packagekitglib-1.0", Version: "1.2.6-5", Architecture: "amd64", Source: "packagekit"},
+		{Name: "git", Version: "1:2.39.5-0+deb12u2", Architecture: "amd64", Source: "git"},
+		{Name: "git-man", Version: "1:2.39.5-0+deb12u2", Architecture: "all", Source: "git"},
+		{Name: "gnupg", Version: "2.2.40-1.1+deb12u1", Architecture: "all", Source: "gnupg2"},
+		{Name: "gnupg-l10n", Version: "2.2.40-1.1+deb12u1", Architecture: "all", Source: "gnupg2"},
+		{Name: "gnupg-utils", Version: "2.2.40-1.1+deb12u1", Architecture: "amd64", Source: "gnupg2"},
+		{Name: "gpg", Version: "2.2.40-1.1+deb12u1", Architecture: "amd64", Source: "gnupg2"},
+		{Name: "gpg-agent", Version: "2.2.40-1.1+deb12u1", Architecture: "amd64", Source: "gnupg2"},
+		{Name: "gpg-wks-client", Version: "2.2.40-1.1+deb12u1", Architecture: "amd64", Source: "gnupg2"},
+		{Name: "gpg-wks-server", Version: "2.2.40-1.1+deb12u1", Architecture: "amd64", Source: "gnupg2"},
+		{Name: "gpgconf", Version: "2.2.40-1.1+deb12u1", Architecture: "amd64", Source: "gnupg2"},
+		{Name: "gpgsm", Version: "2.2.40-1.1+deb12u1", Architecture: "amd64", Source: "gnupg2"},
+		{Name: "gpgv", Version: "2.2.40-1.1+deb12u1", Architecture: "amd64", Source: "gnupg2"},
+		{Name: "grep", Version: "3.8-5", Architecture: "amd64", Source: "grep"},
+		{Name: "gzip", Version: "1.12-1", Architecture: "amd64", Source: "gzip"},
+		{Name: "hostname", Version: "3.23+nmu1", Architecture: "amd64", Source: "hostname"},
+		{Name: "icu-devtools", Version: "72.1-3+deb12u1", Architecture: "amd64", Source: "icu"},
+		{Name: "init-system-helpers", Version: "1.65.2+deb12u1", Architecture: "all", Source: "init-system-helpers"},
+		{Name: "iproute2", Version: "6.1.0-3", Architecture: "amd64", Source: "iproute2"},
+		{Name: "iso-codes", Version: "4.15.0-1", Architecture: "all", Source: "iso-codes"},
+		{Name: "javascript-common", Version: "11+nmu1", Architecture: "all", Source: "javascript-common"},
+		{Name: "jq", Version: "1.6-2.1+deb12u1", Architecture: "amd64", Source: "jq"},
+		{Name: "krb5-locales", Version: "1.20.1-2+deb12u4", Architecture: "all", Source: "krb5"},
+		{Name: "less", Version: "590-2.1~deb12u2", Architecture: "amd64", Source: "less"},
+		{Name: "libabsl20220623", Version: "20220623.1-1+deb12u2", Architecture: "amd64", Source: "abseil"},
+		{Name: "libacl1", Version: "2.3.1-3", Architecture: "amd64", Source: "acl"},
+		{Name: "libalgorithm-diff-perl", Version: "1.201-1", Architecture: "all", Source: "libalgorithm-diff-perl"},
+		{Name: "libalgorithm-diff-xs-perl", Version: "0.04-8+b1", Architecture: "amd64", Source: "libalgorithm-diff-xs-perl"},
+		{Name: "libalgorithm-merge-perl", Version: "0.08-5", Architecture: "all", Source: "libalgorithm-merge-perl"},
+		{Name: "libaom3", Version: "3.6.0-1+deb12u2", Architecture: "amd64", Source: "aom"},
+		{Name: "libapparmor1", Version: "3.0.8-3", Architecture: "amd64", Source: "apparmor"},
+		{Name: "libappstream4", Version: "0.16.1-2", Architecture: "amd64", Source: "appstream"},
+		{Name: "libapt-pkg6.0", Version: "2.6.1", Architecture: "amd64", Source: "apt"},
+		{Name: "libargon2-1", Version: "0~20171227-0.3+deb12u1", Architecture: "amd64", Source: "argon2"},
+		{Name: "libasan8", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libassuan0", Version: "2.5.5-5", Architecture: "amd64", Source: "libassuan"},
+		{Name: "libatm1", Version: "1:2.5.1-4+b2", Architecture: "amd64", Source: "linux-atm"},
+		{Name: "libatomic1", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libattr1", Version: "1:2.5.1-4", Architecture: "amd64", Source: "attr"},
+		{Name: "libaudit-common", Version: "1:3.0.9-1", Architecture: "all", Source: "audit"},
+		{Name: "libaudit1", Version: "1:3.0.9-1", Architecture: "amd64", Source: "audit"},
+		{Name: "libavif15", Version: "0.11.1-1+deb12u1", Architecture: "amd64", Source: "libavif"},
+		{Name: "libbinutils", Version: "2.40-2", Architecture: "amd64", Source: "binutils"},
+		{Name: "libblkid1", Version: "2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "libbpf1", Version: "1:1.1.2-0+deb12u1", Architecture: "amd64", Source: "libbpf"},
+		{Name: "libbrotli-dev", Version: "1.0.9-2+b6", Architecture: "amd64", Source: "brotli"},
+		{Name: "libbrotli1", Version: "1.0.9-2+b6", Architecture: "amd64", Source: "brotli"},
+		{Name: "libbsd0", Version: "0.11.7-2", Architecture: "amd64", Source: "libbsd"},
+		{Name: "libbz2-1.0", Version: "1.0.8-5+b1", Architecture: "amd64", Source: "bzip2"},
+		{Name: "libbz2-dev", Version: "1.0.8-5+b1", Architecture: "amd64", Source: "bzip2"},
+		{Name: "libc-bin", Version: "2.36-9+deb12u13", Architecture: "amd64", Source: "glibc"},
+		{Name: "libc-dev-bin", Version: "2.36-9+deb12u13", Architecture: "amd64", Source: "glibc"},
+		{Name: "libc-devtools", Version: "2.36-9+deb12u13", Architecture: "amd64", Source: "glibc"},
+		{Name: "libc6", Version: "2.36-9+deb12u13", Architecture: "amd64", Source: "glibc"},
+		{Name: "libc6-dev", Version: "2.36-9+deb12u13", Architecture: "amd64", Source: "glibc"},
+		{Name: "libcap-ng0", Version: "0.8.3-1+b3", Architecture: "amd64", Source: "libcap-ng"},
+		{Name: "libcap2", Version: "1:2.66-4+deb12u2", Architecture: "amd64", Source: "libcap2"},
+		{Name: "libcap2-bin", Version: "1:2.66-4+deb12u2", Architecture: "amd64", Source: "libcap2"},
+		{Name: "libcbor0.8", Version: "0.8.0-2+b1", Architecture: "amd64", Source: "libcbor"},
+		{Name: "libcc1-0", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libclang-cpp14", Version: "1:14.0.6-12", Architecture: "amd64", Source: "llvm-toolchain-14"},
+		{Name: "libcom-err2", Version: "1.47.0-2+b2", Architecture: "amd64", Source: "e2fsprogs"},
+		{Name: "libcrypt-dev", Version: "1:4.4.33-2", Architecture: "amd64", Source: "libxcrypt"},
+		{Name: "libcrypt1", Version: "1:4.4.33-2", Architecture: "amd64", Source: "libxcrypt"},
+		{Name: "libcryptsetup12", Version: "2:2.6.1-4~deb12u2", Architecture: "amd64", Source: "cryptsetup"},
+		{Name: "libctf-nobfd0", Version: "2.40-2", Architecture: "amd64", Source: "binutils"},
+		{Name: "libctf0", Version: "2.40-2", Architecture: "amd64", Source: "binutils"},
+		{Name: "libcurl3-gnutls", Version: "7.88.1-10+deb12u14", Architecture: "amd64", Source: "curl"},
+		{Name: "libcurl3-nss", Version: "7.88.1-10+deb12u14", Architecture: "amd64", Source: "curl"},
+		{Name: "libcurl4", Version: "7.88.1-10+deb12u14", Architecture: "amd64", Source: "curl"},
+		{Name: "libdav1d6", Version: "1.0.0-2+deb12u1", Architecture: "amd64", Source: "dav1d"},
+		{Name: "libdb5.3", Version: "5.3.28+dfsg2-1", Architecture: "amd64", Source: "db5.3"},
+		{Name: "libdbus-1-3", Version: "1.14.10-1~deb12u1", Architecture: "amd64", Source: "dbus"},
+		{Name: "libde265-0", Version: "1.0.11-1+deb12u2", Architecture: "amd64", Source: "libde265"},
+		{Name: "libdebconfclient0", Version: "0.270", Architecture: "amd64", Source: "cdebconf"},
+		{Name: "libdeflate0", Version: "1.14-1", Architecture: "amd64", Source: "libdeflate"},
+		{Name: "libdevmapper1.02.1", Version: "2:1.02.185-2", Architecture: "amd64", Source: "lvm2"},
+		{Name: "libdpkg-perl", Version: "1.21.22", Architecture: "all", Source: "dpkg"},
+		{Name: "libdrm-amdgpu1", Version: "2.4.114-1+b1", Architecture: "amd64", Source: "libdrm"},
+		{Name: "libdrm-common", Version: "2.4.114-1", Architecture: "all", Source: "libdrm"},
+		{Name: "libdrm-intel1", Version: "2.4.114-1+b1", Architecture: "amd64", Source: "libdrm"},
+		{Name: "libdrm-nouveau2", Version: "2.4.114-1+b1", Architecture: "amd64", Source: "libdrm"},
+		{Name: "libdrm-radeon1", Version: "2.4.114-1+b1", Architecture: "amd64", Source: "libdrm"},
+		{Name: "libdrm2", Version: "2.4.114-1+b1", Architecture: "amd64", Source: "libdrm"},
+		{Name: "libduktape207", Version: "2.7.0-2", Architecture: "amd64", Source: "duktape"},
+		{Name: "libdw1", Version: "0.188-2.1", Architecture: "amd64", Source: "elfutils"},
+		{Name: "libedit2", Version: "3.1-20221030-2", Architecture: "amd64", Source: "libedit"},
+		{Name: "libegl-dev", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libegl-mesa0", Version: "22.3.6-1+deb12u1", Architecture: "amd64", Source: "mesa"},
+		{Name: "libegl1", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libelf1", Version: "0.188-2.1", Architecture: "amd64", Source: "elfutils"},
+		{Name: "liberror-perl", Version: "0.17029-2", Architecture: "all", Source: "liberror-perl"},
+		{Name: "libevent-2.1-7", Version: "2.1.12-stable-8", Architecture: "amd64", Source: "libevent"},
+		{Name: "libevent-core-2.1-7", Version: "2.1.12-stable-8", Architecture: "amd64", Source: "libevent"},
+		{Name: "libexpat1", Version: "2.5.0-1+deb12u2", Architecture: "amd64", Source: "expat"},
+		{Name: "libexpat1-dev", Version: "2.5.0-1+deb12u2", Architecture: "amd64", Source: "expat"},
+		{Name: "libext2fs2", Version: "1.47.0-2+b2", Architecture: "amd64", Source: "e2fsprogs"},
+		{Name: "libfakeroot", Version: "1.31-1.2", Architecture: "amd64", Source: "fakeroot"},
+		{Name: "libfdisk1", Version: "2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "libffi-dev", Version: "3.4.4-1", Architecture: "amd64", Source: "libffi"},
+		{Name: "libffi8", Version: "3.4.4-1", Architecture: "amd64", Source: "libffi"},
+		{Name: "libfido2-1", Version: "1.12.0-2+b1", Architecture: "amd64", Source: "libfido2"},
+		{Name: "libfile-fcntllock-perl", Version: "0.22-4+b1", Architecture: "amd64", Source: "libfile-fcntllock-perl"},
+		{Name: "libfontconfig-dev", Version: "2.14.1-4", Architecture: "amd64", Source: "fontconfig"},
+		{Name: "libfontconfig1", Version: "2.14.1-4", Architecture: "amd64", Source: "fontconfig"},
+		{Name: "libfontconfig1-dev", Version: "2.14.1-4", Architecture: "amd64", Source: "fontconfig"},
+		{Name: "libfreetype-dev", Version: "2.12.1+dfsg-5+deb12u4", Architecture: "amd64", Source: "freetype"},
+		{Name: "libfreetype6", Version: "2.12.1+dfsg-5+deb12u4", Architecture: "amd64", Source: "freetype"},
+		{Name: "libgav1-1", Version: "0.18.0-1+b1", Architecture: "amd64", Source: "libgav1"},
+		{Name: "libgbm1", Version: "22.3.6-1+deb12u1", Architecture: "amd64", Source: "mesa"},
+		{Name: "libgcc-12-dev", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libgcc-s1", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libgcrypt20", Version: "1.10.1-3", Architecture: "amd64", Source: "libgcrypt20"},
+		{Name: "libgcrypt20-dev", Version: "1.10.1-3", Architecture: "amd64", Source: "libgcrypt20"},
+		{Name: "libgd3", Version: "2.3.3-9", Architecture: "amd64", Source: "libgd2"},
+		{Name: "libgdbm-compat4", Version: "1.23-3", Architecture: "amd64", Source: "gdbm"},
+		{Name: "libgdbm6", Version: "1.23-3", Architecture: "amd64", Source: "gdbm"},
+		{Name: "libgirepository-1.0-1", Version: "1.74.0-3", Architecture: "amd64", Source: "gobject-introspection"},
+		{Name: "libgl-dev", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libgl1", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libgl1-mesa-dev", Version: "22.3.6-1+deb12u1", Architecture: "amd64", Source: "mesa"},
+		{Name: "libgl1-mesa-dri", Version: "22.3.6-1+deb12u1", Architecture: "amd64", Source: "mesa"},
+		{Name: "libgl1-mesa-glx", Version: "22.3.6-1+deb12u1", Architecture: "amd64", Source: "mesa"},
+		{Name: "libglapi-mesa", Version: "22.3.6-1+deb12u1", Architecture: "amd64", Source: "mesa"},
+		{Name: "libgles-dev", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libgles1", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libgles2", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libglib2.0-0", Version: "2.74.6-2+deb12u7", Architecture: "amd64", Source: "glib2.0"},
+		{Name: "libglib2.0-bin", Version: "2.74.6-2+deb12u7", Architecture: "amd64", Source: "glib2.0"},
+		{Name: "libglib2.0-data", Version: "2.74.6-2+deb12u7", Architecture: "all", Source: "glib2.0"},
+		{Name: "libglu1-mesa", Version: "9.0.2-1.1", Architecture: "amd64", Source: "libglu"},
+		{Name: "libglu1-mesa-dev", Version: "9.0.2-1.1", Architecture: "amd64", Source: "libglu"},
+		{Name: "libglut-dev", Version: "3.4.0-1", Architecture: "amd64", Source: "freeglut"},
+		{Name: "libglut3.12", Version: "3.4.0-1", Architecture: "amd64", Source: "freeglut"},
+		{Name: "libglvnd-core-dev", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libglvnd-dev", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libglvnd0", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libglx-dev", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libglx-mesa0", Version: "22.3.6-1+deb12u1", Architecture: "amd64", Source: "mesa"},
+		{Name: "libglx0", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libgmp-dev", Version: "2:6.2.1+dfsg1-1.1", Architecture: "amd64", Source: "gmp"},
+		{Name: "libgmp10", Version: "2:6.2.1+dfsg1-1.1", Architecture: "amd64", Source: "gmp"},
+		{Name: "libgmpxx4ldbl", Version: "2:6.2.1+dfsg1-1.1", Architecture: "amd64", Source: "gmp"},
+		{Name: "libgnutls-dane0", Version: "3.7.9-2+deb12u5", Architecture: "amd64", Source: "gnutls28"},
+		{Name: "libgnutls-openssl27", Version: "3.7.9-2+deb12u5", Architecture: "amd64", Source: "gnutls28"},
+		{Name: "libgnutls28-dev", Version: "3.7.9-2+deb12u5", Architecture: "amd64", Source: "gnutls28"},
+		{Name: "libgnutls30", Version: "3.7.9-2+deb12u5", Architecture: "amd64", Source: "gnutls28"},
+		{Name: "libgnutlsxx30", Version: "3.7.9-2+deb12u5", Architecture: "amd64", Source: "gnutls28"},
+		{Name: "libgomp1", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libgpg-error-dev", Version: "1.46-1", Architecture: "amd64", Source: "libgpg-error"},
+		{Name: "libgpg-error0", Version: "1.46-1", Architecture: "amd64", Source: "libgpg-error"},
+		{Name: "libgpm2", Version: "1.20.7-10+b1", Architecture: "amd64", Source: "gpm"},
+		{Name: "libgprofng0", Version: "2.40-2", Architecture: "amd64", Source: "binutils"},
+		{Name: "libgssapi-krb5-2", Version: "1.20.1-2+deb12u4", Architecture: "amd64", Source: "krb5"},
+		{Name: "libgstreamer1.0-0", Version: "1.22.0-2+deb12u1", Architecture: "amd64", Source: "gstreamer1.0"},
+		{Name: "libheif1", Version: "1.15.1-1+deb12u1", Architecture: "amd64", Source: "libheif"},
+		{Name: "libhogweed6", Version: "3.8.1-2", Architecture: "amd64", Source: "nettle"},
+		{Name: "libice-dev", Version: "2:1.0.10-1", Architecture: "amd64", Source: "libice"},
+		{Name: "libice6", Version: "2:1.0.10-1", Architecture: "amd64", Source: "libice"},
+		{Name: "libicu-dev", Version: "72.1-3+deb12u1", Architecture: "amd64", Source: "icu"},
+		{Name: "libicu72", Version: "72.1-3+deb12u1", Architecture: "amd64", Source: "icu"},
+		{Name: "libidn2-0", Version: "2.3.3-1+b1", Architecture: "amd64", Source: "libidn2"},
+		{Name: "libidn2-dev", Version: "2.3.3-1+b1", Architecture: "amd64", Source: "libidn2"},
+		{Name: "libip4tc2", Version: "1.8.9-2", Architecture: "amd64", Source: "iptables"},
+		{Name: "libisl23", Version: "0.25-1.1", Architecture: "amd64", Source: "isl"},
+		{Name: "libitm1", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libjansson4", Version: "2.14-2", Architecture: "amd64", Source: "jansson"},
+		{Name: "libjbig0", Version: "2.1-6.1", Architecture: "amd64", Source: "jbigkit"},
+		{Name: "libjpeg-dev", Version: "1:2.1.5-2", Architecture: "amd64", Source: "libjpeg-turbo"},
+		{Name: "libjpeg62-turbo", Version: "1:2.1.5-2", Architecture: "amd64", Source: "libjpeg-turbo"},
+		{Name: "libjpeg62-turbo-dev", Version: "1:2.1.5-2", Architecture: "amd64", Source: "libjpeg-turbo"},
+		{Name: "libjq1", Version: "1.6-2.1+deb12u1", Architecture: "amd64", Source: "jq"},
+		{Name: "libjs-jquery", Version: "3.6.1+dfsg+~3.5.14-1", Architecture: "all", Source: "node-jquery"},
+		{Name: "libjs-sphinxdoc", Version: "5.3.0-4", Architecture: "all", Source: "sphinx"},
+		{Name: "libjs-underscore", Version: "1.13.4~dfsg+~1.11.4-3", Architecture: "all", Source: "underscore"},
+		{Name: "libjson-c5", Version: "0.16-2", Architecture: "amd64", Source: "json-c"},
+		{Name: "libk5crypto3", Version: "1.20.1-2+deb12u4", Architecture: "amd64", Source: "krb5"},
+		{Name: "libkeyutils1", Version: "1.6.3-2", Architecture: "amd64", Source: "keyutils"},
+		{Name: "libkmod2", Version: "30+20221128-1", Architecture: "amd64", Source: "kmod"},
+		{Name: "libkrb5-3", Version: "1.20.1-2+deb12u4", Architecture: "amd64", Source: "krb5"},
+		{Name: "libkrb5support0", Version: "1.20.1-2+deb12u4", Architecture: "amd64", Source: "krb5"},
+		{Name: "libksba8", Version: "1.6.3-2", Architecture: "amd64", Source: "libksba"},
+		{Name: "libldap-2.5-0", Version: "2.5.13+dfsg-5", Architecture: "amd64", Source: "openldap"},
+		{Name: "libldap-common", Version: "2.5.13+dfsg-5", Architecture: "all", Source: "openldap"},
+		{Name: "liblerc4", Version: "4.0.0+ds-2", Architecture: "amd64", Source: "lerc"},
+		{Name: "libllvm14", Version: "1:14.0.6-12", Architecture: "amd64", Source: "llvm-toolchain-14"},
+		{Name: "libllvm15", Version: "1:15.0.6-4+b1", Architecture: "amd64", Source: "llvm-toolchain-15"},
+		{Name: "liblocale-gettext-perl", Version: "1.07-5", Architecture: "amd64", Source: "liblocale-gettext-perl"},
+		{Name: "liblsan0", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "liblz4-1", Version: "1.9.4-1", Architecture: "amd64", Source: "lz4"},
+		{Name: "liblzma-dev", Version: "5.4.1-1", Architecture: "amd64", Source: "xz-utils"},
+		{Name: "liblzma5", Version: "5.4.1-1", Architecture: "amd64", Source: "xz-utils"},
+		{Name: "libmagic-dev", Version: "1:5.44-3", Architecture: "amd64", Source: "file"},
+		{Name: "libmagic-mgc", Version: "1:5.44-3", Architecture: "amd64", Source: "file"},
+		{Name: "libmagic1", Version: "1:5.44-3", Architecture: "amd64", Source: "file"},
+		{Name: "libmd0", Version: "1.0.4-2", Architecture: "amd64", Source: "libmd"},
+		{Name: "libmnl0", Version: "1.0.4-3", Architecture: "amd64", Source: "libmnl"},
+		{Name: "libmount1", Version: "2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "libmpc3", Version: "1.3.1-1", Architecture: "amd64", Source: "mpclib3"},
+		{Name: "libmpfr6", Version: "4.2.0-1", Architecture: "amd64", Source: "mpfr4"},
+		{Name: "libncurses-dev", Version: "6.4-4", Architecture: "amd64", Source: "ncurses"},
+		{Name: "libncurses5-dev", Version: "6.4-4", Architecture: "amd64", Source: "ncurses"},
+		{Name: "libncurses6", Version: "6.4-4", Architecture: "amd64", Source: "ncurses"},
+		{Name: "libncursesw5-dev", Version: "6.4-4", Architecture: "amd64", Source: "ncurses"},
+		{Name: "libncursesw6", Version: "6.4-4", Architecture: "amd64", Source: "ncurses"},
+		{Name: "libnettle8", Version: "3.8.1-2", Architecture: "amd64", Source: "nettle"},
+		{Name: "libnghttp2-14", Version: "1.52.0-1+deb12u2", Architecture: "amd64", Source: "nghttp2"},
+		{Name: "libnpth0", Version: "1.6-3", Architecture: "amd64", Source: "npth"},
+		{Name: "libnsl-dev", Version: "1.3.0-2", Architecture: "amd64", Source: "libnsl"},
+		{Name: "libnsl2", Version: "1.3.0-2", Architecture: "amd64", Source: "libnsl"},
+		{Name: "libnspr4", Version: "2:4.35-1", Architecture: "amd64", Source: "nspr"},
+		{Name: "libnspr4-dev", Version: "2:4.35-1", Architecture: "amd64", Source: "nspr"},
+		{Name: "libnss-systemd", Version: "252.39-1~deb12u1", Architecture: "amd64", Source: "systemd"},
+		{Name: "libnss3", Version: "2:3.87.1-1+deb12u1", Architecture: "amd64", Source: "nss"},
+		{Name: "libnss3-dev", Version: "2:3.87.1-1+deb12u1", Architecture: "amd64", Source: "nss"},
+		{Name: "libnuma1", Version: "2.0.16-1", Architecture: "amd64", Source: "numactl"},
+		{Name: "libonig5", Version: "6.9.8-1", Architecture: "amd64", Source: "libonig"},
+		{Name: "libopengl-dev", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libopengl0", Version: "1.6.0-1", Architecture: "amd64", Source: "libglvnd"},
+		{Name: "libp11-kit-dev", Version: "0.24.1-2", Architecture: "amd64", Source: "p11-kit"},
+		{Name: "libp11-kit0", Version: "0.24.1-2", Architecture: "amd64", Source: "p11-kit"},
+		{Name: "libpackagekit-glib2-18", Version: "1.2.6-5", Architecture: "amd64", Source: "packagekit"},
+		{Name: "libpam-cap", Version: "1:2.66-4+deb12u2", Architecture: "amd64", Source: "libcap2"},
+		{Name: "libpam-modules", Version: "1.5.2-6+deb12u1", Architecture: "amd64", Source: "pam"},
+		{Name: "libpam-modules-bin", Version: "1.5.2-6+deb12u1", Architecture: "amd64", Source: "pam"},
+		{Name: "libpam-runtime", Version: "1.5.2-6+deb12u1", Architecture: "all", Source: "pam"},
+		{Name: "libpam-systemd", Version: "252.39-1~deb12u1", Architecture: "amd64", Source: "systemd"},
+		{Name: "libpam0g", Version: "1.5.2-6+deb12u1", Architecture: "amd64", Source: "pam"},
+		{Name: "libpciaccess0", Version: "0.17-2", Architecture: "amd64", Source: "libpciaccess"},
+		{Name: "libpcre2-8-0", Version: "10.42-1", Architecture: "amd64", Source: "pcre2"},
+		{Name: "libperl5.36", Version: "5.36.0-7+deb12u3", Architecture: "amd64", Source: "perl"},
+		{Name: "libpfm4", Version: "4.13.0-1", Architecture: "amd64", Source: "libpfm4"},
+		{Name: "libpipeline1", Version: "1.5.7-1", Architecture: "amd64", Source: "libpipeline"},
+		{Name: "libpkgconf3", Version: "1.8.1-1", Architecture: "amd64", Source: "pkgconf"},
+		{Name: "libpng-dev", Version: "1.6.39-2", Architecture: "amd64", Source: "libpng1.6"},
+		{Name: "libpng-tools", Version: "1.6.39-2", Architecture: "amd64", Source: "libpng1.6"},
+		{Name: "libpng16-16", Version: "1.6.39-2", Architecture: "amd64", Source: "libpng1.6"},
+		{Name: "libpolkit-agent-1-0", Version: "122-3", Architecture: "amd64", Source: "policykit-1"},
+		{Name: "libpolkit-gobject-1-0", Version: "122-3", Architecture: "amd64", Source: "policykit-1"},
+		{Name: "libpq-dev", Version: "15.14-0+deb12u1", Architecture: "amd64", Source: "postgresql-15"},
+		{Name: "libpq5", Version: "15.14-0+deb12u1", Architecture: "amd64", Source: "postgresql-15"},
+		{Name: "libproc2-0", Version: "2:4.0.2-3", Architecture: "amd64", Source: "procps"},
+		{Name: "libpsl5", Version: "0.21.2-1", Architecture: "amd64", Source: "libpsl"},
+		{Name: "libpthread-stubs0-dev", Version: "0.4-1", Architecture: "amd64", Source: "libpthread-stubs"},
+		{Name: "libpython3-dev", Version: "3.11.2-1+b1", Architecture: "amd64", Source: "python3-defaults"},
+		{Name: "libpython3-stdlib", Version: "3.11.2-1+b1", Architecture: "amd64", Source: "python3-defaults"},
+		{Name: "libpython3.11", Version: "3.11.2-6+deb12u6", Architecture: "amd64", Source: "python3.11"},
+		{Name: "libpython3.11-dev", Version: "3.11.2-6+deb12u6", Architecture: "amd64", Source: "python3.11"},
+		{Name: "libpython3.11-minimal", Version: "3.11.2-6+deb12u6", Architecture: "amd64", Source: "python3.11"},
+		{Name: "libpython3.11-stdlib", Version: "3.11.2-6+deb12u6", Architecture: "amd64", Source: "python3.11"},
+		{Name: "libquadmath0", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "librav1e0", Version: "0.5.1-6", Architecture: "amd64", Source: "rust-rav1e"},
+		{Name: "libreadline-dev", Version: "8.2-1.3", Architecture: "amd64", Source: "readline"},
+		{Name: "libreadline8", Version: "8.2-1.3", Architecture: "amd64", Source: "readline"},
+		{Name: "librtmp1", Version: "2.4+20151223.gitfa8646d.1-2+b2", Architecture: "amd64", Source: "rtmpdump"},
+		{Name: "libsasl2-2", Version: "2.1.28+dfsg-10", Architecture: "amd64", Source: "cyrus-sasl2"},
+		{Name: "libsasl2-modules", Version: "2.1.28+dfsg-10", Architecture: "amd64", Source: "cyrus-sasl2"},
+		{Name: "libsasl2-modules-db", Version: "2.1.28+dfsg-10", Architecture: "amd64", Source: "cyrus-sasl2"},
+		{Name: "libseccomp2", Version: "2.5.4-1+deb12u1", Architecture: "amd64", Source: "libseccomp"},
+		{Name: "libselinux1", Version: "3.4-1+b6", Architecture: "amd64", Source: "libselinux"},
+		{Name: "libsemanage-common", Version: "3.4-1", Architecture: "all", Source: "libsemanage"},
+		{Name: "libsemanage2", Version: "3.4-1+b5", Architecture: "amd64", Source: "libsemanage"},
+		{Name: "libsensors-config", Version: "1:3.6.0-7.1", Architecture: "all", Source: "lm-sensors"},
+		{Name: "libsensors5", Version: "1:3.6.0-7.1", Architecture: "amd64", Source: "lm-sensors"},
+		{Name: "libsepol2", Version: "3.4-2.1", Architecture: "amd64", Source: "libsepol"},
+		{Name: "libsm-dev", Version: "2:1.2.3-1", Architecture: "amd64", Source: "libsm"},
+		{Name: "libsm6", Version: "2:1.2.3-1", Architecture: "amd64", Source: "libsm"},
+		{Name: "libsmartcols1", Version: "2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "libsodium23", Version: "1.0.18-1", Architecture: "amd64", Source: "libsodium"},
+		{Name: "libsqlite3-0", Version: "3.40.1-2+deb12u2", Architecture: "amd64", Source: "sqlite3"},
+		{Name: "libsqlite3-dev", Version: "3.40.1-2+deb12u2", Architecture: "amd64", Source: "sqlite3"},
+		{Name: "libss2", Version: "1.47.0-2+b2", Architecture: "amd64", Source: "e2fsprogs"},
+		{Name: "libssh2-1", Version: "1.10.0-3+b1", Architecture: "amd64", Source: "libssh2"},
+		{Name: "libssl-dev", Version: "3.0.17-1~deb12u2", Architecture: "amd64", Source: "openssl"},
+		{Name: "libssl3", Version: "3.0.17-1~deb12u2", Architecture: "amd64", Source: "openssl"},
+		{Name: "libstdc++-12-dev", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libstdc++6", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libstemmer0d", Version: "2.2.0-2", Architecture: "amd64", Source: "snowball"},
+		{Name: "libsvtav1enc1", Version: "1.4.1+dfsg-1", Architecture: "amd64", Source: "svt-av1"},
+		{Name: "libsystemd-shared", Version: "252.39-1~deb12u1", Architecture: "amd64", Source: "systemd"},
+		{Name: "libsystemd0", Version: "252.39-1~deb12u1", Architecture: "amd64", Source: "systemd"},
+		{Name: "libtasn1-6", Version: "4.19.0-2+deb12u1", Architecture: "amd64", Source: "libtasn1-6"},
+		{Name: "libtasn1-6-dev", Version: "4.19.0-2+deb12u1", Architecture: "amd64", Source: "libtasn1-6"},
+		{Name: "libtasn1-doc", Version: "4.19.0-2+deb12u1", Architecture: "all", Source: "libtasn1-6"},
+		{Name: "libtcl8.6", Version: "8.6.13+dfsg-2", Architecture: "amd64", Source: "tcl8.6"},
+		{Name: "libtiff6", Version: "4.5.0-6+deb12u2", Architecture: "amd64", Source: "tiff"},
+		{Name: "libtinfo6", Version: "6.4-4", Architecture: "amd64", Source: "ncurses"},
+		{Name: "libtirpc-common", Version: "1.3.3+ds-1", Architecture: "all", Source: "libtirpc"},
+		{Name: "libtirpc-dev", Version: "1.3.3+ds-1", Architecture: "amd64", Source: "libtirpc"},
+		{Name: "libtirpc3", Version: "1.3.3+ds-1", Architecture: "amd64", Source: "libtirpc"},
+		{Name: "libtk8.6", Version: "8.6.13-2", Architecture: "amd64", Source: "tk8.6"},
+		{Name: "libtsan2", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libubsan1", Version: "12.2.0-14+deb12u1", Architecture: "amd64", Source: "gcc-12"},
+		{Name: "libudev1", Version: "252.39-1~deb12u1", Architecture: "amd64", Source: "systemd"},
+		{Name: "libunbound8", Version: "1.17.1-2+deb12u3", Architecture: "amd64", Source: "unbound"},
+		{Name: "libunistring2", Version: "1.0-2", Architecture: "amd64", Source: "libunistring"},
+		{Name: "libunwind8", Version: "1.6.2-3", Architecture: "amd64", Source: "libunwind"},
+		{Name: "libutempter0", Version: "1.2.1-3", Architecture: "amd64", Source: "libutempter"},
+		{Name: "libuuid1", Version: "2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "libwayland-client0", Version: "1.21.0-1", Architecture: "amd64", Source: "wayland"},
+		{Name: "libwayland-server0", Version: "1.21.0-1", Architecture: "amd64", Source: "wayland"},
+		{Name: "libwebp7", Version: "1.2.4-0.2+deb12u1", Architecture: "amd64", Source: "libwebp"},
+		{Name: "libx11-6", Version: "2:1.8.4-2+deb12u2", Architecture: "amd64", Source: "libx11"},
+		{Name: "libx11-data", Version: "2:1.8.4-2+deb12u2", Architecture: "all", Source: "libx11"},
+		{Name: "libx11-dev", Version: "2:1.8.4-2+deb12u2", Architecture: "amd64", Source: "libx11"},
+		{Name: "libx11-xcb1", Version: "2:1.8.4-2+deb12u2", Architecture: "amd64", Source: "libx11"},
+		{Name: "libx265-199", Version: "3.5-2+b1", Architecture: "amd64", Source: "x265"},
+		{Name: "libxau-dev", Version: "1:1.0.9-1", Architecture: "amd64", Source: "libxau"},
+		{Name: "libxau6", Version: "1:1.0.9-1", Architecture: "amd64", Source: "libxau"},
+		{Name: "libxcb-cursor0", Version: "0.1.4-1", Architecture: "amd64", Source: "xcb-util-cursor"},
+		{Name: "libxcb-dri2-0", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb-dri3-0", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb-glx0", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb-image0", Version: "0.4.0-2", Architecture: "amd64", Source: "xcb-util-image"},
+		{Name: "libxcb-present0", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb-randr0", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb-render-util0", Version: "0.3.9-1+b1", Architecture: "amd64", Source: "xcb-util-renderutil"},
+		{Name: "libxcb-render0", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb-shm0", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb-sync1", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb-util1", Version: "0.4.0-1+b1", Architecture: "amd64", Source: "xcb-util"},
+		{Name: "libxcb-xfixes0", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb-xkb1", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb1", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcb1-dev", Version: "1.15-1", Architecture: "amd64", Source: "libxcb"},
+		{Name: "libxcomposite-dev", Version: "1:0.4.5-1", Architecture: "amd64", Source: "libxcomposite"},
+		{Name: "libxcomposite1", Version: "1:0.4.5-1", Architecture: "amd64", Source: "libxcomposite"},
+		{Name: "libxdmcp-dev", Version: "1:1.1.2-3", Architecture: "amd64", Source: "libxdmcp"},
+		{Name: "libxdmcp6", Version: "1:1.1.2-3", Architecture: "amd64", Source: "libxdmcp"},
+		{Name: "libxext-dev", Version: "2:1.3.4-1+b1", Architecture: "amd64", Source: "libxext"},
+		{Name: "libxext6", Version: "2:1.3.4-1+b1", Architecture: "amd64", Source: "libxext"},
+		{Name: "libxfixes-dev", Version: "1:6.0.0-2", Architecture: "amd64", Source: "libxfixes"},
+		{Name: "libxfixes3", Version: "1:6.0.0-2", Architecture: "amd64", Source: "libxfixes"},
+		{Name: "libxft-dev", Version: "2.3.6-1", Architecture: "amd64", Source: "xft"},
+		{Name: "libxft2", Version: "2.3.6-1", Architecture: "amd64", Source: "xft"},
+		{Name: "libxi6", Version: "2:1.8-1+b1", Architecture: "amd64", Source: "libxi"},
+		{Name: "libxkbcommon-x11-0", Version: "1.5.0-1", Architecture: "amd64", Source: "libxkbcommon"},
+		{Name: "libxkbcommon0", Version: "1.5.0-1", Architecture: "amd64", Source: "libxkbcommon"},
+		{Name: "libxml2", Version: "2.9.14+dfsg-1.3~deb12u4", Architecture: "amd64", Source: "libxml2"},
+		{Name: "libxml2-dev", Version: "2.9.14+dfsg-1.3~deb12u4", Architecture: "amd64", Source: "libxml2"},
+		{Name: "libxmlb2", Version: "0.3.10-2", Architecture: "amd64", Source: "libxmlb"},
+		{Name: "libxmlsec1", Version: "1.2.37-2", Architecture: "amd64", Source: "xmlsec1"},
+		{Name: "libxmlsec1-dev", Version: "1.2.37-2", Architecture: "amd64", Source: "xmlsec1"},
+		{Name: "libxmlsec1-gcrypt", Version: "1.2.37-2", Architecture: "amd64", Source: "xmlsec1"},
+		{Name: "libxmlsec1-gnutls", Version: "1.2.37-2", Architecture: "amd64", Source: "xmlsec1"},
+		{Name: "libxmlsec1-nss", Version: "1.2.37-2", Architecture: "amd64", Source: "xmlsec1"},
+		{Name: "libxmlsec1-openssl", Version: "1.2.37-2", Architecture: "amd64", Source: "xmlsec1"},
+		{Name: "libxmuu1", Version: "2:1.1.3-3", Architecture: "amd64", Source: "libxmu"},
+		{Name: "libxpm4", Version: "1:3.5.12-1.1+deb12u1", Architecture: "amd64", Source: "libxpm"},
+		{Name: "libxrender-dev", Version: "1:0.9.10-1.1", Architecture: "amd64", Source: "libxrender"},
+		{Name: "libxrender1", Version: "1:0.9.10-1.1", Architecture: "amd64", Source: "libxrender"},
+		{Name: "libxshmfence1", Version: "1.3-1", Architecture: "amd64", Source: "libxshmfence"},
+		{Name: "libxslt1-dev", Version: "1.1.35-1+deb12u3", Architecture: "amd64", Source: "libxslt"},
+		{Name: "libxslt1.1", Version: "1.1.35-1+deb12u3", Architecture: "amd64", Source: "libxslt"},
+		{Name: "libxss-dev", Version: "1:1.2.3-1", Architecture: "amd64", Source: "libxss"},
+		{Name: "libxss1", Version: "1:1.2.3-1", Architecture: "amd64", Source: "libxss"},
+		{Name: "libxt-dev", Version: "1:1.2.1-1.1", Architecture: "amd64", Source: "libxt"},
+		{Name: "libxt6", Version: "1:1.2.1-1.1", Architecture: "amd64", Source: "libxt"},
+		{Name: "libxtables12", Version: "1.8.9-2", Architecture: "amd64", Source: "iptables"},
+		{Name: "libxxf86vm1", Version: "1:1.1.4-1+b2", Architecture: "amd64", Source: "libxxf86vm"},
+		{Name: "libxxhash0", Version: "0.8.1-1", Architecture: "amd64", Source: "xxhash"},
+		{Name: "libyaml-0-2", Version: "0.2.5-1", Architecture: "amd64", Source: "libyaml"},
+		{Name: "libyaml-dev", Version: "0.2.5-1", Architecture: "amd64", Source: "libyaml"},
+		{Name: "libyuv0", Version: "0.0~git20230123.b2528b0-1", Architecture: "amd64", Source: "libyuv"},
+		{Name: "libz3-4", Version: "4.8.12-3.1", Architecture: "amd64", Source: "z3"},
+		{Name: "libz3-dev", Version: "4.8.12-3.1", Architecture: "amd64", Source: "z3"},
+		{Name: "libzstd1", Version: "1.5.4+dfsg2-5", Architecture: "amd64", Source: "libzstd"},
+		{Name: "linux-libc-dev", Version: "6.1.153-1", Architecture: "amd64", Source: "linux"},
+		{Name: "llvm", Version: "1:14.0-55.7~deb12u1", Architecture: "amd64", Source: "llvm-defaults"},
+		{Name: "llvm-14", Version: "1:14.0.6-12", Architecture: "amd64", Source: "llvm-toolchain-14"},
+		{Name: "llvm-14-dev", Version: "1:14.0.6-12", Architecture: "amd64", Source: "llvm-toolchain-14"},
+		{Name: "llvm-14-linker-tools", Version: "1:14.0.6-12", Architecture: "amd64", Source: "llvm-toolchain-14"},
+		{Name: "llvm-14-runtime", Version: "1:14.0.6-12", Architecture: "amd64", Source: "llvm-toolchain-14"},
+		{Name: "llvm-14-tools", Version: "1:14.0.6-12", Architecture: "amd64", Source: "llvm-toolchain-14"},
+		{Name: "llvm-runtime", Version: "1:14.0-55.7~deb12u1", Architecture: "amd64", Source: "llvm-defaults"},
+		{Name: "login", Version: "1:4.13+dfsg1-1+deb12u1", Architecture: "amd64", Source: "shadow"},
+		{Name: "logsave", Version: "1.47.0-2+b2", Architecture: "amd64", Source: "e2fsprogs"},
+		{Name: "lsb-release", Version: "12.0-1", Architecture: "all", Source: "lsb-release-minimal"},
+		{Name: "lsof", Version: "4.95.0-1", Architecture: "amd64", Source: "lsof"},
+		{Name: "make", Version: "4.3-4.1", Architecture: "amd64", Source: "make-dfsg"},
+		{Name: "manpages", Version: "6.03-2", Architecture: "all", Source: "manpages"},
+		{Name: "manpages-dev", Version: "6.03-2", Architecture: "all", Source: "manpages"},
+		{Name: "mawk", Version: "1.3.4.20200120-3.1", Architecture: "amd64", Source: "mawk"},
+		{Name: "media-types", Version: "10.0.0", Architecture: "all", Source: "media-types"},
+		{Name: "mount", Version: "2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "ncurses-base", Version: "6.4-4", Architecture: "all", Source: "ncurses"},
+		{Name: "ncurses-bin", Version: "6.4-4", Architecture: "amd64", Source: "ncurses"},
+		{Name: "net-tools", Version: "2.10-0.1+deb12u2", Architecture: "amd64", Source: "net-tools"},
+		{Name: "netbase", Version: "6.4", Architecture: "all", Source: "netbase"},
+		{Name: "nettle-dev", Version: "3.8.1-2", Architecture: "amd64", Source: "nettle"},
+		{Name: "nodejs", Version: "20.19.5-1nodesource1", Architecture: "amd64", Source: "nodejs"},
+		{Name: "nss-plugin-pem", Version: "1.0.8+1-1", Architecture: "amd64", Source: "nss-pem"},
+		{Name: "openssh-client", Version: "1:9.2p1-2+deb12u7", Architecture: "amd64", Source: "openssh"},
+		{Name: "openssl", Version: "3.0.17-1~deb12u2", Architecture: "amd64", Source: "openssl"},
+		{Name: "packagekit", Version: "1.2.6-5", Architecture: "amd64", Source: "packagekit"},
+		{Name: "packagekit-tools", Version: "1.2.6-5", Architecture: "amd64", Source: "packagekit"},
+		{Name: "passwd", Version: "1:4.13+dfsg1-1+deb12u1", Architecture: "amd64", Source: "shadow"},
+		{Name: "patch", Version: "2.7.6-7", Architecture: "amd64", Source: "patch"},
+		{Name: "perl", Version: "5.36.0-7+deb12u3", Architecture: "amd64", Source: "perl"},
+		{Name: "perl-base", Version: "5.36.0-7+deb12u3", Architecture: "amd64", Source: "perl"},
+		{Name: "perl-modules-5.36", Version: "5.36.0-7+deb12u3", Architecture: "all", Source: "perl"},
+		{Name: "pinentry-curses", Version: "1.2.1-1", Architecture: "amd64", Source: "pinentry"},
+		{Name: "pkg-config", Version: "1.8.1-1", Architecture: "amd64", Source: "pkgconf"},
+		{Name: "pkgconf", Version: "1.8.1-1", Architecture: "amd64", Source: "pkgconf"},
+		{Name: "pkgconf-bin", Version: "1.8.1-1", Architecture: "amd64", Source: "pkgconf"},
+		{Name: "polkitd", Version: "122-3", Architecture: "amd64", Source: "policykit-1"},
+		{Name: "procps", Version: "2:4.0.2-3", Architecture: "amd64", Source: "procps"},
+		{Name: "psmisc", Version: "23.6-1", Architecture: "amd64", Source: "psmisc"},
+		{Name: "publicsuffix", Version: "20230209.2326-1", Architecture: "all", Source: "publicsuffix"},
+		{Name: "python-apt-common", Version: "2.6.0", Architecture: "all", Source: "python-apt"},
+		{Name: "python3", Version: "3.11.2-1+b1", Architecture: "amd64", Source: "python3-defaults"},
+		{Name: "python3-apt", Version: "2.6.0", Architecture: "amd64", Source: "python-apt"},
+		{Name: "python3-argcomplete", Version: "2.0.0-1", Architecture: "all", Source: "python-argcomplete"},
+		{Name: "python3-blinker", Version: "1.5-1", Architecture: "all", Source: "blinker"},
+		{Name: "python3-cffi-backend", Version: "1.15.1-5+b1", Architecture: "amd64", Source: "python-cffi"},
+		{Name: "python3-cryptography", Version: "38.0.4-3+deb12u1", Architecture: "amd64", Source: "python-cryptography"},
+		{Name: "python3-dbus", Version: "1.3.2-4+b1", Architecture: "amd64", Source: "dbus-python"},
+		{Name: "python3-dev", Version: "3.11.2-1+b1", Architecture: "amd64", Source: "python3-defaults"},
+		{Name: "python3-distro", Version: "1.8.0-1", Architecture: "all", Source: "python-distro"},
+		{Name: "python3-distutils", Version: "3.11.2-3", Architecture: "all", Source: "python3-stdlib-extensions"},
+		{Name: "python3-gi", Version: "3.42.2-3+b1", Architecture: "amd64", Source: "pygobject"},
+		{Name: "python3-httplib2", Version: "0.20.4-3", Architecture: "all", Source: "python-httplib2"},
+		{Name: "python3-jwt", Version: "2.6.0-1", Architecture: "all", Source: "pyjwt"},
+		{Name: "python3-lazr.restfulclient", Version: "0.14.5-1", Architecture: "all", Source: "lazr.restfulclient"},
+		{Name: "python3-lazr.uri", Version: "1.0.6-3", Architecture: "all", Source: "lazr.uri"},
+		{Name: "python3-lib2to3", Version: "3.11.2-3", Architecture: "all", Source: "python3-stdlib-extensions"},
+		{Name: "python3-minimal", Version: "3.11.2-1+b1", Architecture: "amd64", Source: "python3-defaults"},
+		{Name: "python3-oauthlib", Version: "3.2.2-1", Architecture: "all", Source: "python-oauthlib"},
+		{Name: "python3-openssl", Version: "23.0.0-1", Architecture: "all", Source: "pyopenssl"},
+		{Name: "python3-pip", Version: "23.0.1+dfsg-1", Architecture: "all", Source: "python-pip"},
+		{Name: "python3-pip-whl", Version: "23.0.1+dfsg-1", Architecture: "all", Source: "python-pip"},
+		{Name: "python3-pkg-resources", Version: "66.1.1-1+deb12u2", Architecture: "all", Source: "setuptools"},
+		{Name: "python3-pygments", Version: "2.14.0+dfsg-1", Architecture: "all", Source: "pygments"},
+		{Name: "python3-pyparsing", Version: "3.0.9-1", Architecture: "all", Source: "pyparsing"},
+		{Name: "python3-setuptools", Version: "66.1.1-1+deb12u2", Architecture: "all", Source: "setuptools"},
+		{Name: "python3-setuptools-whl", Version: "66.1.1-1+deb12u2", Architecture: "all", Source: "setuptools"},
+		{Name: "python3-six", Version: "1.16.0-4", Architecture: "all", Source: "six"},
+		{Name: "python3-software-properties", Version: "0.99.30-4.1~deb12u1", Architecture: "all", Source: "software-properties"},
+		{Name: "python3-toml", Version: "0.10.2-1", Architecture: "all", Source: "python-toml"},
+		{Name: "python3-venv", Version: "3.11.2-1+b1", Architecture: "amd64", Source: "python3-defaults"},
+		{Name: "python3-wadllib", Version: "1.3.6-4", Architecture: "all", Source: "python-wadllib"},
+		{Name: "python3-wheel", Version: "0.38.4-2", Architecture: "all", Source: "wheel"},
+		{Name: "python3-xmltodict", Version: "0.13.0-1", Architecture: "all", Source: "python-xmltodict"},
+		{Name: "python3-yaml", Version: "6.0-3+b2", Architecture: "amd64", Source: "pyyaml"},
+		{Name: "python3.11", Version: "3.11.2-6+deb12u6", Architecture: "amd64", Source: "python3.11"},
+		{Name: "python3.11-dev", Version: "3.11.2-6+deb12u6", Architecture: "amd64", Source: "python3.11"},
+		{Name: "python3.11-minimal", Version: "3.11.2-6+deb12u6", Architecture: "amd64", Source: "python3.11"},
+		{Name: "python3.11-venv", Version: "3.11.2-6+deb12u6", Architecture: "amd64", Source: "python3.11"},
+		{Name: "readline-common", Version: "8.2-1.3", Architecture: "all", Source: "readline"},
+		{Name: "rpcsvc-proto", Version: "1.4.3-1", Architecture: "amd64", Source: "rpcsvc-proto"},
+		{Name: "sed", Version: "4.9-1", Architecture: "amd64", Source: "sed"},
+		{Name: "sgml-base", Version: "1.31", Architecture: "all", Source: "sgml-base"},
+		{Name: "shared-mime-info", Version: "2.2-1", Architecture: "amd64", Source: "shared-mime-info"},
+		{Name: "software-properties-common", Version: "0.99.30-4.1~deb12u1", Architecture: "all", Source: "software-properties"},
+		{Name: "systemd", Version: "252.39-1~deb12u1", Architecture: "amd64", Source: "systemd"},
+		{Name: "systemd-sysv", Version: "252.39-1~deb12u1", Architecture: "amd64", Source: "systemd"},
+		{Name: "systemd-timesyncd", Version: "252.39-1~deb12u1", Architecture: "amd64", Source: "systemd"},
+		{Name: "sysvinit-utils", Version: "3.06-4", Architecture: "amd64", Source: "sysvinit"},
+		{Name: "tar", Version: "1.34+dfsg-1.2+deb12u1", Architecture: "amd64", Source: "tar"},
+		{Name: "tcl", Version: "8.6.13", Architecture: "amd64", Source: "tcltk-defaults"},
+		{Name: "tcl-dev", Version: "8.6.13", Architecture: "amd64", Source: "tcltk-defaults"},
+		{Name: "tcl8.6", Version: "8.6.13+dfsg-2", Architecture: "amd64", Source: "tcl8.6"},
+		{Name: "tcl8.6-dev", Version: "8.6.13+dfsg-2", Architecture: "amd64", Source: "tcl8.6"},
+		{Name: "tk", Version: "8.6.13", Architecture: "amd64", Source: "tcltk-defaults"},
+		{Name: "tk-dev", Version: "8.6.13", Architecture: "amd64", Source: "tcltk-defaults"},
+		{Name: "tk8.6", Version: "8.6.13-2", Architecture: "amd64", Source: "tk8.6"},
+		{Name: "tk8.6-dev", Version: "8.6.13-2", Architecture: "amd64", Source: "tk8.6"},
+		{Name: "tmux", Version: "3.3a-3", Architecture: "amd64", Source: "tmux"},
+		{Name: "tzdata", Version: "2025b-0+deb12u2", Architecture: "all", Source: "tzdata"},
+		{Name: "unzip", Version: "6.0-28", Architecture: "amd64", Source: "unzip"},
+		{Name: "usr-is-merged", Version: "37~deb12u1", Architecture: "all", Source: "usrmerge"},
+		{Name: "util-linux", Version: "2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "util-linux-extra", Version: "2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "uuid-dev", Version: "2.38.1-5+deb12u3", Architecture: "amd64", Source: "util-linux"},
+		{Name: "vim", Version: "2:9.0.1378-2+deb12u2", Architecture: "amd64", Source: "vim"},
+		{Name: "vim-common", Version: "2:9.0.1378-2+deb12u2", Architecture: "all", Source: "vim"},
+		{Name: "vim-runtime", Version: "2:9.0.1378-2+deb12u2", Architecture: "all", Source: "vim"},
+		{Name: "wget", Version: "1.21.3-1+deb12u1", Architecture: "amd64", Source: "wget"},
+		{Name: "x11-common", Version: "1:7.7+23", Architecture: "all", Source: "xorg"},
+		{Name: "x11proto-core-dev", Version: "2022.1-1", Architecture: "all", Source: "xorgproto"},
+		{Name: "x11proto-dev", Version: "2022.1-1", Architecture: "all", Source: "xorgproto"},
+		{Name: "xauth", Version: "1:1.1.2-1", Architecture: "amd64", Source: "xauth"},
+		{Name: "xdg-user-dirs", Version: "0.18-1", Architecture: "amd64", Source: "xdg-user-dirs"},
+		{Name: "xkb-data", Version: "2.35.1-1", Architecture: "all", Source: "xkeyboard-config"},
+		{Name: "xml-core", Version: "0.18+nmu1", Architecture: "all", Source: "xml-core"},
+		{Name: "xorg-sgml-doctools", Version: "1:1.11-1.1", Architecture: "all", Source: "xorg-sgml-doctools"},
+		{Name: "xtrans-dev", Version: "1.4.0-1", Architecture: "all", Source: "xtrans"},
+		{Name: "xxd", Version: "2:9.0.1378-2+deb12u2", Architecture: "amd64", Source: "vim"},
+		{Name: "xz-utils", Version: "5.4.1-1", Architecture: "amd64", Source: "xz-utils"},
+		{Name: "yq", Version: "3.1.0-3", Architecture: "all", Source: "yq"},
+		{Name: "zip", Version: "3.0-13", Architecture: "amd64", Source: "zip"},
+		{Name: "zlib1g", Version: "1:1.2.13.dfsg-1", Architecture: "amd64", Source: "zlib"},
+		{Name: "zlib1g-dev", Version: "1:1.2.13.dfsg-1", Architecture: "amd64", Source: "zlib"},
 	}
 
 	if len(actual) != len(expected) {
@@ -118,7 +572,47 @@ func TestParseAPTPackages(t *testing.T) {
 
 	for i := range expected {
 		if actual[i] != expected[i] {
-			t.Errorf("expected package %s at position %d, found %s", expected[i], i, actual[i])
+			t.Errorf("expected package %+v at position %d, found %+v", expected[i], i, actual[i])
 		}
 	}
 }
+
+func TestParseAPTPackageStates(t *testing.T) {
+	cf := APTCommandFactory{}
+	_, _, parse := cf.NewListInstalledPackagesCmd()
+
+	lines := []string{
+		"ii \tbash\t5.2.15-2+b2\tamd64\tbash",
+		"hi \tlibc6\t2.36-9+deb12u1\tamd64\tglibc",
+		"rc \tlibgdbm6\t1.23-3\tamd64\tgdbm",
+		"un \tlibpam-modules\t\t\t",
+	}
+	actual, err := parse(lines)
+	if err != nil {
+		t.Fatalf("parsing packages: %v", err)
+	}
+
+	expected := []Package{
+		{Name: "bash", Version: "5.2.15-2+b2", Architecture: "amd64", Source: "bash"},
+		{Name: "libc6", Version: "2.36-9+deb12u1", Architecture: "amd64", Source: "glibc"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected packages %+v, found %+v", expected, actual)
+	}
+}
+
+func TestAPTInstallWeakDeps(t *testing.T) {
+	cf := APTCommandFactory{}
+
+	cmd, _ := cf.NewInstallCmd([]string{"curl"}, InstallOptions{})
+	expected := []string{"apt", "--quiet", "--yes", "install", "--no-install-recommends", "curl"}
+	if !reflect.DeepEqual(cmd, expected) {
+		t.Errorf("expected install command %q, found %q", expected, cmd)
+	}
+
+	cmd, _ = cf.NewInstallCmd([]string{"curl"}, InstallOptions{WeakDeps: true})
+	expected = []string{"apt", "--quiet", "--yes", "install", "--install-recommends", "curl"}
+	if !reflect.DeepEqual(cmd, expected) {
+		t.Errorf("expected install command %q, found %q", expected, cmd)
+	}
+}
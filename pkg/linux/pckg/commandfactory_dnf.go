@@ -5,6 +5,7 @@ package pckg
 
 import (
 	"fmt"
+	"path"
 	"strings"
 )
 
@@ -15,8 +16,8 @@ func (f DNFCommandFactory) NewCleanCacheCmd() (cmd, capabilities []string) {
 	return cmd, []string{}
 }
 
-func (f DNFCommandFactory) NewInstallCmd(packages []string) (cmd, capabilities []string) {
-	cmd = []string{"dnf", "--assumeyes", "--quiet", "--setopt=install_weak_deps=False", "install"}
+func (f DNFCommandFactory) NewInstallCmd(packages []string, options InstallOptions) (cmd, capabilities []string) {
+	cmd = []string{"dnf", "--assumeyes", "--quiet", dnfWeakDepsOption(options), "install"}
 	cmd = append(cmd, packages...)
 	capabilities = []string{
 		"CAP_CHOWN",
@@ -26,41 +27,105 @@ func (f DNFCommandFactory) NewInstallCmd(packages []string) (cmd, capabilities [
 	return cmd, capabilities
 }
 
+// NewInstallGroupsCmd installs DNF groups and module streams by prefixing their
+// names with an at sign.
+func (f DNFCommandFactory) NewInstallGroupsCmd(groups []string, options InstallOptions) (cmd, capabilities []string) {
+	specs := make([]string, len(groups))
+	for i, g := range groups {
+		specs[i] = "@" + g
+	}
+	return f.NewInstallCmd(specs, options)
+}
+
+func (f DNFCommandFactory) NewDownloadCmds(packages []string, dir string, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"dnf", "--assumeyes", "--quiet", dnfWeakDepsOption(options), "install", "--downloadonly", "--destdir=" + dir}
+	cmd = append(cmd, packages...)
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_SETFCAP",
+	}
+	return [][]string{cmd}, capabilities
+}
+
+func (f DNFCommandFactory) NewInstallFilesCmds(files []string, offline bool, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"dnf", "--assumeyes", "--quiet", dnfWeakDepsOption(options)}
+	if offline {
+		cmd = append(cmd, "--disablerepo=*")
+	}
+	cmd = append(cmd, "install")
+	cmd = append(cmd, files...)
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_SETFCAP",
+	}
+	return [][]string{cmd}, capabilities
+}
+
+// dnfWeakDepsOption returns the option that makes DNF install or skip the weak
+// dependencies of the packages it installs.
+func dnfWeakDepsOption(options InstallOptions) string {
+	if options.WeakDeps {
+		return "--setopt=install_weak_deps=True"
+	}
+	return "--setopt=install_weak_deps=False"
+}
+
 func (f DNFCommandFactory) NewListInstalledPackagesCmd() (
 	cmd []string,
 	capabilities []string,
-	parse func([]string) ([]string, error),
+	parse func([]string) ([]Package, error),
 ) {
-	cmd = []string{
-		"dnf",
-		"--color=never",
-		"--quiet",
-		"list",
-		"--installed",
+	return newRPMListInstalledPackagesCmd()
+}
+
+func (f DNFCommandFactory) NewRemoveCmd(packages []string) (cmd, capabilities []string) {
+	cmd = []string{"dnf", "--assumeyes", "--quiet", "--setopt=clean_requirements_on_remove=True", "remove"}
+	cmd = append(cmd, packages...)
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_SETFCAP",
 	}
+	return cmd, capabilities
+}
 
-	// expected line format: name.arch version repo
-	parse = func(lines []string) ([]string, error) {
-		if len(lines) < 2 {
-			return []string{}, nil
-		}
-		result := make([]string, 0, len(lines)-1)
-		for _, l := range lines[1:] {
-			pkg, _, ok := strings.Cut(l, " ")
-			if !ok {
-				return nil, fmt.Errorf("expected space delimiter in line %q", l)
-			}
-			i := strings.LastIndex(pkg, ".")
-			if i == -1 {
-				return nil, fmt.Errorf("expected format 'name.arch' for field %q", pkg)
-			}
-			name := pkg[:i]
-			result = append(result, name)
-		}
-		return result, nil
+func (f DNFCommandFactory) Pin(name, version string) string {
+	return name + "-" + version
+}
+
+func (f DNFCommandFactory) KeyPath(name, file string) string {
+	return path.Join("/etc/pki/rpm-gpg", "RPM-GPG-KEY-"+name)
+}
+
+func (f DNFCommandFactory) NewAddRepositoryCmds(r Repository) (files []File, cmds [][]string, capabilities []string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]\n", r.Name)
+	fmt.Fprintf(&b, "name=%s\n", r.Name)
+	fmt.Fprintf(&b, "baseurl=%s\n", r.URL)
+	if r.Enabled {
+		b.WriteString("enabled=1\n")
+	} else {
+		b.WriteString("enabled=0\n")
+	}
+	b.WriteString("gpgcheck=1\n")
+	if r.Key != "" {
+		fmt.Fprintf(&b, "gpgkey=file://%s\n", r.Key)
 	}
+	if r.Priority != 0 {
+		fmt.Fprintf(&b, "priority=%d\n", r.Priority)
+	}
+	files = []File{{Path: path.Join("/etc/yum.repos.d", r.Name+".repo"), Contents: b.String()}}
+	return files, [][]string{}, []string{}
+}
 
-	return cmd, []string{}, parse
+func (f DNFCommandFactory) LocalRepositoryOptions(r LocalRepository) (options []string, files []File) {
+	options = []string{"--repofrompath=turret," + r.Path, "--repo=turret"}
+	if r.Trusted {
+		options = append(options, "--nogpgcheck")
+	}
+	return options, []File{}
 }
 
 func (f DNFCommandFactory) NewUpdateIndexCmd() (cmd, capabilities []string) {
@@ -77,6 +142,14 @@ func (f DNFCommandFactory) NewUpgradeCmd() (cmd, capabilities []string) {
 	return cmd, capabilities
 }
 
+// NewSecurityUpgradeCmds upgrades the packages for which the repositories
+// publish security advisories.
+func (f DNFCommandFactory) NewSecurityUpgradeCmds() (files []File, cmds [][]string, capabilities []string) {
+	cmd, capabilities := f.NewUpgradeCmd()
+	cmd = append(cmd, "--security")
+	return []File{}, [][]string{cmd}, capabilities
+}
+
 func (f DNFCommandFactory) Backend() Backend {
 	return DNF
 }
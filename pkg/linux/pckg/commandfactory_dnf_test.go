@@ -1,180 +1,43 @@
 package pckg
 
 import (
-	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
 
+// dnfRPMQuery is hand-written input in the format of the output of the RPM
+// query that lists the packages in a Fedora working container, covering an
+// epoch, compound licenses, several packages built from one source RPM and
+// the public keys that RPM records as packages.
+const dnfRPMQuery = `alternatives	1.25-1.fc39	x86_64	GPL-2.0-only	chkconfig-1.25-1.fc39.src.rpm
+audit-libs	3.1.2-1.fc39	x86_64	LGPL-2.0-or-later	audit-3.1.2-1.fc39.src.rpm
+bash	5.2.15-5.fc39	x86_64	GPL-3.0-or-later	bash-5.2.15-5.fc39.src.rpm
+ca-certificates	2023.2.60_v7.0.306-2.fc39	noarch	MIT AND GPL-2.0-or-later	ca-certificates-2023.2.60_v7.0.306-2.fc39.src.rpm
+curl	8.2.1-2.fc39	x86_64	curl	curl-8.2.1-2.fc39.src.rpm
+gpg-pubkey	18b8e74c-62f2920f	(none)	pubkey	(none)
+libcurl	8.2.1-2.fc39	x86_64	curl	curl-8.2.1-2.fc39.src.rpm
+vim-minimal	2:9.0.1677-1.fc39	x86_64	Vim AND MIT	vim-9.0.1677-1.fc39.src.rpm`
+
 func TestParseDNFPackages(t *testing.T) {
 	cf := DNFCommandFactory{}
 	_, _, parse := cf.NewListInstalledPackagesCmd()
 
-	raw, err := os.ReadFile("testdata/dnf.txt")
-	if err != nil {
-		t.Fatalf("reading test data: %v", err)
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
-	actual, err := parse(lines)
+	actual, err := parse(strings.Split(dnfRPMQuery, "\n"))
 	if err != nil {
-		t.Fatalf("parsing packages from test data: %v", err)
+		t.Fatalf("parsing packages: %v", err)
 	}
 
-	expected := []string{
-		"alternatives",
-		"audit-libs",
-		"authselect",
-		"authselect-libs",
-		"basesystem",
-		"bash",
-		"bzip2-libs",
-		"ca-certificates",
-		"coreutils",
-		"coreutils-common",
-		"cracklib",
-		"crypto-policies",
-		"curl",
-		"cyrus-sasl-lib",
-		"dnf",
-		"dnf-data",
-		"elfutils-default-yama-scope",
-		"elfutils-libelf",
-		"elfutils-libs",
-		"expat",
-		"fedora-gpg-keys",
-		"fedora-release-common",
-		"fedora-release-container",
-		"fedora-release-identity-container",
-		"fedora-repos",
-		"file-libs",
-		"filesystem",
-		"findutils",
-		"gawk",
-		"gdbm-libs",
-		"glib2",
-		"glibc",
-		"glibc-common",
-		"glibc-minimal-langpack",
-		"gmp",
-		"gnupg2",
-		"gnutls",
-		"gpgme",
-		"grep",
-		"gzip",
-		"ima-evm-utils",
-		"json-c",
-		"keyutils-libs",
-		"krb5-libs",
-		"libacl",
-		"libarchive",
-		"libassuan",
-		"libattr",
-		"libb2",
-		"libblkid",
-		"libbrotli",
-		"libcap",
-		"libcap-ng",
-		"libcom_err",
-		"libcomps",
-		"libcurl",
-		"libdb",
-		"libdnf",
-		"libeconf",
-		"libevent",
-		"libffi",
-		"libfsverity",
-		"libgcc",
-		"libgcrypt",
-		"libgomp",
-		"libgpg-error",
-		"libidn2",
-		"libksba",
-		"libmodulemd",
-		"libmount",
-		"libnghttp2",
-		"libnsl2",
-		"libpsl",
-		"libpwquality",
-		"librepo",
-		"libreport-filesystem",
-		"libselinux",
-		"libsemanage",
-		"libsepol",
-		"libsigsegv",
-		"libsmartcols",
-		"libsolv",
-		"libssh",
-		"libssh-config",
-		"libstdc++",
-		"libtasn1",
-		"libtirpc",
-		"libunistring",
-		"libuuid",
-		"libverto",
-		"libxcrypt",
-		"libxml2",
-		"libyaml",
-		"libzstd",
-		"lua-libs",
-		"lz4-libs",
-		"mpdecimal",
-		"mpfr",
-		"ncurses-base",
-		"ncurses-libs",
-		"nettle",
-		"npth",
-		"openldap",
-		"openssl-libs",
-		"p11-kit",
-		"p11-kit-trust",
-		"pam",
-		"pam-libs",
-		"pcre2",
-		"pcre2-syntax",
-		"popt",
-		"publicsuffix-list-dafsa",
-		"python-pip-wheel",
-		"python3",
-		"python3-dnf",
-		"python3-gpg",
-		"python3-hawkey",
-		"python3-libcomps",
-		"python3-libdnf",
-		"python3-libs",
-		"python3-rpm",
-		"readline",
-		"rootfiles",
-		"rpm",
-		"rpm-build-libs",
-		"rpm-libs",
-		"rpm-sequoia",
-		"rpm-sign-libs",
-		"sed",
-		"setup",
-		"shadow-utils",
-		"sqlite-libs",
-		"sudo",
-		"systemd-libs",
-		"tar",
-		"tpm2-tss",
-		"tzdata",
-		"util-linux-core",
-		"vim-data",
-		"vim-minimal",
-		"xz-libs",
-		"yum",
-		"zchunk-libs",
-		"zlib",
+	expected := []Package{
+		{Name: "alternatives", Version: "1.25-1.fc39", Architecture: "x86_64", License: "GPL-2.0-only", Source: "chkconfig"},
+		{Name: "audit-libs", Version: "3.1.2-1.fc39", Architecture: "x86_64", License: "LGPL-2.0-or-later", Source: "audit"},
+		{Name: "bash", Version: "5.2.15-5.fc39", Architecture: "x86_64", License: "GPL-3.0-or-later", Source: "bash"},
+		{Name: "ca-certificates", Version: "2023.2.60_v7.0.306-2.fc39", Architecture: "noarch", License: "MIT AND GPL-2.0-or-later", Source: "ca-certificates"},
+		{Name: "curl", Version: "8.2.1-2.fc39", Architecture: "x86_64", License: "curl", Source: "curl"},
+		{Name: "libcurl", Version: "8.2.1-2.fc39", Architecture: "x86_64", License: "curl", Source: "curl"},
+		{Name: "vim-minimal", Version: "2:9.0.1677-1.fc39", Architecture: "x86_64", License: "Vim AND MIT", Source: "vim"},
 	}
-
-	if len(actual) != len(expected) {
-		t.Fatalf("expected %d packages, found %d", len(expected), len(actual))
-	}
-
-	for i := range expected {
-		if actual[i] != expected[i] {
-			t.Errorf("expected package %s at position %d, found %s", expected[i], i, actual[i])
-		}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected packages %+v, found %+v", expected, actual)
 	}
 }
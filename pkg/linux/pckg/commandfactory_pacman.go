@@ -3,6 +3,12 @@
 
 package pckg
 
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
 type PacmanCommandFactory struct{}
 
 func (f PacmanCommandFactory) NewCleanCacheCmd() (cmd, capabilities []string) {
@@ -10,7 +16,7 @@ func (f PacmanCommandFactory) NewCleanCacheCmd() (cmd, capabilities []string) {
 	return cmd, []string{}
 }
 
-func (f PacmanCommandFactory) NewInstallCmd(packages []string) (cmd, capabilities []string) {
+func (f PacmanCommandFactory) NewInstallCmd(packages []string, options InstallOptions) (cmd, capabilities []string) {
 	cmd = []string{"pacman", "--sync", "--noconfirm", "--noprogressbar", "--quiet"}
 	cmd = append(cmd, packages...)
 	capabilities = []string{
@@ -22,28 +28,172 @@ func (f PacmanCommandFactory) NewInstallCmd(packages []string) (cmd, capabilitie
 	return cmd, capabilities
 }
 
+// NewInstallGroupsCmd installs every package in one or more pacman groups.
+func (f PacmanCommandFactory) NewInstallGroupsCmd(groups []string, options InstallOptions) (cmd, capabilities []string) {
+	return f.NewInstallCmd(groups, options)
+}
+
+func (f PacmanCommandFactory) NewDownloadCmds(packages []string, dir string, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"pacman", "--sync", "--refresh", "--downloadonly", "--noconfirm", "--noprogressbar", "--quiet", "--cachedir", dir}
+	cmd = append(cmd, packages...)
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_FOWNER",
+		"CAP_SYS_CHROOT",
+	}
+	return [][]string{cmd}, capabilities
+}
+
+// NewInstallFilesCmds ignores `offline` because pacman reads only the sync
+// databases already in the working container when installing package files.
+func (f PacmanCommandFactory) NewInstallFilesCmds(files []string, offline bool, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"pacman", "--upgrade", "--noconfirm", "--noprogressbar", "--quiet"}
+	cmd = append(cmd, files...)
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_FOWNER",
+		"CAP_SYS_CHROOT",
+	}
+	return [][]string{cmd}, capabilities
+}
+
 func (f PacmanCommandFactory) NewListInstalledPackagesCmd() (
 	cmd []string,
 	capabilities []string,
-	parse func([]string) ([]string, error),
+	parse func([]string) ([]Package, error),
 ) {
 	cmd = []string{
 		"pacman",
 		"--color", "never",
 		"--query",
-		"--quiet",
+		"--info",
 	}
 
-	// expected line format: name
-	parse = func(lines []string) ([]string, error) {
-		return lines, nil
+	// expected line format: key : value, with one block of lines per package
+	// and continuation lines starting with whitespace
+	parse = func(lines []string) ([]Package, error) {
+		result := []Package{}
+		for _, l := range lines {
+			if l == "" || strings.TrimLeft(l, " \t") != l {
+				continue
+			}
+			k, v, ok := strings.Cut(l, ":")
+			if !ok {
+				return nil, fmt.Errorf("expected colon delimiter in line %q", l)
+			}
+			k = strings.TrimSpace(k)
+			v = strings.TrimSpace(v)
+			if k == "Name" {
+				result = append(result, Package{Name: v})
+				continue
+			}
+			if k != "Version" && k != "Architecture" && k != "Licenses" {
+				continue
+			}
+			if len(result) == 0 {
+				return nil, fmt.Errorf("expected field 'Name' before line %q", l)
+			}
+			switch k {
+			case "Version":
+				result[len(result)-1].Version = v
+			case "Architecture":
+				result[len(result)-1].Architecture = v
+			case "Licenses":
+				// pacman separates licenses with two spaces
+				if v != "None" {
+					result[len(result)-1].License = strings.Join(strings.Split(v, "  "), " AND ")
+				}
+			}
+		}
+		return result, nil
 	}
 
 	return cmd, []string{}, parse
 }
 
+func (f PacmanCommandFactory) NewRemoveCmd(packages []string) (cmd, capabilities []string) {
+	cmd = []string{"pacman", "--remove", "--nosave", "--recursive", "--noconfirm", "--noprogressbar", "--quiet"}
+	cmd = append(cmd, packages...)
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_FOWNER",
+		"CAP_SYS_CHROOT",
+	}
+	return cmd, capabilities
+}
+
+func (f PacmanCommandFactory) Pin(name, version string) string {
+	return name + "=" + version
+}
+
+func (f PacmanCommandFactory) KeyPath(name, file string) string {
+	return path.Join("/etc/pacman.d/keys", name+path.Ext(file))
+}
+
+// NewAddRepositoryCmds adds the key with which the repository is signed to
+// pacman's keyring and trusts all keys in the keyring for the repository,
+// sparing the need to sign the key locally by its fingerprint.
+func (f PacmanCommandFactory) NewAddRepositoryCmds(r Repository) (files []File, cmds [][]string, capabilities []string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]\n", r.Name)
+	if r.Key != "" {
+		b.WriteString("SigLevel = Required TrustAll\n")
+	}
+	fmt.Fprintf(&b, "Server = %s\n", r.URL)
+	section := b.String()
+	if !r.Enabled {
+		section = disable(section)
+	}
+	files = []File{{Path: "/etc/pacman.conf", Contents: "\n" + section, Append: true}}
+
+	cmds = [][]string{}
+	if r.Key != "" {
+		cmds = append(cmds, []string{"pacman-key", "--add", r.Key})
+	}
+
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_FOWNER",
+	}
+
+	return files, cmds, capabilities
+}
+
+// LocalRepositoryOptions replaces the configuration of pacman with one that
+// declares only the repository turret, whose database must therefore be named
+// turret.db, and refreshes the database before resolving packages.
+func (f PacmanCommandFactory) LocalRepositoryOptions(r LocalRepository) (options []string, files []File) {
+	config := path.Join(r.ConfigDir, "pacman.conf")
+	options = []string{"--config", config, "--refresh"}
+
+	sigLevel := "Required DatabaseOptional"
+	if r.Trusted {
+		sigLevel = "Never"
+	}
+
+	var b strings.Builder
+	b.WriteString("[options]\n")
+	b.WriteString("Architecture = auto\n")
+	fmt.Fprintf(&b, "SigLevel = %s\n", sigLevel)
+	b.WriteString("\n[turret]\n")
+	fmt.Fprintf(&b, "Server = file://%s\n", r.Path)
+
+	return options, []File{{Path: config, Contents: b.String()}}
+}
+
 func (f PacmanCommandFactory) NewUpdateIndexCmd() (cmd, capabilities []string) {
-	return []string{}, []string{}
+	cmd = []string{"pacman", "--sync", "--refresh", "--noconfirm", "--noprogressbar", "--quiet"}
+	capabilities = []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_FOWNER",
+		"CAP_SYS_CHROOT",
+	}
+	return cmd, capabilities
 }
 
 func (f PacmanCommandFactory) NewUpgradeCmd() (cmd, capabilities []string) {
@@ -57,6 +207,10 @@ func (f PacmanCommandFactory) NewUpgradeCmd() (cmd, capabilities []string) {
 	return cmd, capabilities
 }
 
+func (f PacmanCommandFactory) NewSecurityUpgradeCmds() (files []File, cmds [][]string, capabilities []string) {
+	return []File{}, [][]string{}, []string{}
+}
+
 func (f PacmanCommandFactory) Backend() Backend {
 	return Pacman
 }
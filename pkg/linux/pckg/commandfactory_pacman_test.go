@@ -1,141 +1,118 @@
 package pckg
 
 import (
-	"os"
 	"strings"
 	"testing"
 )
 
+// pacmanInfo is hand-written input in the format of the output of
+// `pacman --color never --query --info`, covering multi-line fields, an
+// architecture-independent package and a custom license.
+const pacmanInfo = `Name            : acl
+Version         : 2.3.1-3
+Description     : Access control list utilities, libraries and headers
+Architecture    : x86_64
+URL             : https://savannah.nongnu.org/projects/acl
+Licenses        : LGPL
+Groups          : None
+Provides        : xfsacl  libacl.so=1-64
+Depends On      : attr  libattr.so=1-64
+Optional Deps   : None
+Required By     : coreutils  gettext  libarchive  sed  shadow  systemd  tar
+Optional For    : None
+Conflicts With  : xfsacl
+Replaces        : xfsacl
+Installed Size  : 337.17 KiB
+Packager        : Christian Hesse <eworm@archlinux.org>
+Build Date      : Mon 27 Mar 2023 08:46:22 AM UTC
+Install Date    : Sun 13 Aug 2023 12:00:00 AM UTC
+Install Reason  : Installed as a dependency for another package
+Install Script  : No
+Validated By    : Signature
+
+Name            : bash
+Version         : 5.1.016-4
+Description     : The GNU Bourne Again shell
+Architecture    : x86_64
+URL             : https://www.gnu.org/software/bash/bash.html
+Licenses        : GPL
+Groups          : None
+Provides        : sh
+Depends On      : readline  libreadline.so=8-64  glibc  ncurses
+Optional Deps   : bash-completion: for tab completion
+                  python: for the man2html script
+Required By     : base  gzip  systemd
+Optional For    : None
+Conflicts With  : None
+Replaces        : None
+Installed Size  : 8.23 MiB
+Packager        : Felix Yan <felixonmars@archlinux.org>
+Build Date      : Wed 05 Jul 2023 10:14:51 AM UTC
+Install Date    : Sun 13 Aug 2023 12:00:00 AM UTC
+Install Reason  : Installed as a dependency for another package
+Install Script  : No
+Validated By    : Signature
+
+Name            : ca-certificates
+Version         : 20220905-1
+Description     : Common CA certificates (default providers)
+Architecture    : any
+URL             : https://src.fedoraproject.org/rpms/ca-certificates
+Licenses        : GPL2
+Groups          : None
+Provides        : None
+Depends On      : ca-certificates-mozilla
+Optional Deps   : None
+Required By     : curl
+Optional For    : None
+Conflicts With  : None
+Replaces        : None
+Installed Size  : 0.00 B
+Packager        : Jan Alexander Steffens (heftig) <heftig@archlinux.org>
+Build Date      : Mon 05 Sep 2022 07:44:54 PM UTC
+Install Date    : Sun 13 Aug 2023 12:00:00 AM UTC
+Install Reason  : Installed as a dependency for another package
+Install Script  : No
+Validated By    : Signature
+
+Name            : tzdata
+Version         : 2023c-2
+Description     : Sources for time zone and daylight saving time data
+Architecture    : x86_64
+URL             : https://www.iana.org/time-zones
+Licenses        : custom: public domain
+Groups          : None
+Provides        : None
+Depends On      : None
+Optional Deps   : bash: for tzselect [installed]
+                  glibc: for zdump, zic [installed]
+Required By     : glibc
+Optional For    : None
+Conflicts With  : None
+Replaces        : None
+Installed Size  : 1607.95 KiB
+Packager        : Andreas Radke <andyrtr@archlinux.org>
+Build Date      : Fri 21 Jul 2023 06:58:17 AM UTC
+Install Date    : Sun 13 Aug 2023 12:00:00 AM UTC
+Install Reason  : Installed as a dependency for another package
+Install Script  : No
+Validated By    : Signature`
+
 func TestParsePacmanPackages(t *testing.T) {
 	cf := PacmanCommandFactory{}
 	_, _, parse := cf.NewListInstalledPackagesCmd()
 
-	raw, err := os.ReadFile("testdata/pacman.txt")
-	if err != nil {
-		t.Fatalf("reading test data: %v", err)
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	lines := strings.Split(pacmanInfo, "\n")
 	actual, err := parse(lines)
 	if err != nil {
-		t.Fatalf("parsing packages from test data: %v", err)
+		t.Fatalf("parsing packages: %v", err)
 	}
 
-	expected := []string{
-		"acl",
-		"archlinux-keyring",
-		"argon2",
-		"attr",
-		"audit",
-		"base",
-		"bash",
-		"brotli",
-		"bzip2",
-		"ca-certificates",
-		"ca-certificates-mozilla",
-		"ca-certificates-utils",
-		"coreutils",
-		"cryptsetup",
-		"curl",
-		"dbus",
-		"device-mapper",
-		"e2fsprogs",
-		"expat",
-		"file",
-		"filesystem",
-		"findutils",
-		"gawk",
-		"gcc-libs",
-		"gdbm",
-		"gettext",
-		"glib2",
-		"glibc",
-		"gmp",
-		"gnupg",
-		"gnutls",
-		"gpgme",
-		"grep",
-		"gzip",
-		"hwdata",
-		"iana-etc",
-		"icu",
-		"iproute2",
-		"iptables",
-		"iputils",
-		"json-c",
-		"kbd",
-		"keyutils",
-		"kmod",
-		"krb5",
-		"less",
-		"libarchive",
-		"libassuan",
-		"libbpf",
-		"libcap",
-		"libcap-ng",
-		"libelf",
-		"libevent",
-		"libffi",
-		"libgcrypt",
-		"libgpg-error",
-		"libidn2",
-		"libksba",
-		"libldap",
-		"libmnl",
-		"libnetfilter_conntrack",
-		"libnfnetlink",
-		"libnftnl",
-		"libnghttp2",
-		"libnl",
-		"libp11-kit",
-		"libpcap",
-		"libpsl",
-		"libsasl",
-		"libseccomp",
-		"libsecret",
-		"libssh2",
-		"libsysprof-capture",
-		"libtasn1",
-		"libtirpc",
-		"libunistring",
-		"libutempter",
-		"libverto",
-		"libxcrypt",
-		"libxml2",
-		"licenses",
-		"linux-api-headers",
-		"lz4",
-		"mpfr",
-		"ncurses",
-		"nettle",
-		"npth",
-		"openssl",
-		"p11-kit",
-		"pacman",
-		"pacman-mirrorlist",
-		"pam",
-		"pambase",
-		"pciutils",
-		"pcre2",
-		"pinentry",
-		"popt",
-		"procps-ng",
-		"psmisc",
-		"readline",
-		"sed",
-		"shadow",
-		"sqlite",
-		"systemd",
-		"systemd-libs",
-		"systemd-sysvcompat",
-		"tar",
-		"tpm2-tss",
-		"tzdata",
-		"util-linux",
-		"util-linux-libs",
-		"xz",
-		"zlib",
-		"zstd",
+	expected := []Package{
+		{Name: "acl", Version: "2.3.1-3", Architecture: "x86_64", License: "LGPL"},
+		{Name: "bash", Version: "5.1.016-4", Architecture: "x86_64", License: "GPL"},
+		{Name: "ca-certificates", Version: "20220905-1", Architecture: "any", License: "GPL2"},
+		{Name: "tzdata", Version: "2023c-2", Architecture: "x86_64", License: "custom: public domain"},
 	}
 
 	if len(actual) != len(expected) {
@@ -144,7 +121,7 @@ func TestParsePacmanPackages(t *testing.T) {
 
 	for i := range expected {
 		if actual[i] != expected[i] {
-			t.Errorf("expected package %s at position %d, found %s", expected[i], i, actual[i])
+			t.Errorf("expected package %+v at position %d, found %+v", expected[i], i, actual[i])
 		}
 	}
 }
@@ -0,0 +1,55 @@
+package pckg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInstallGroupsCmd(t *testing.T) {
+	cases := []struct {
+		factory  CommandFactory
+		groups   []string
+		expected []string
+	}{
+		{
+			factory:  APKCommandFactory{},
+			groups:   []string{"build-base"},
+			expected: []string{},
+		},
+		{
+			factory:  APTCommandFactory{},
+			groups:   []string{"ssh-server"},
+			expected: []string{"apt", "--quiet", "--yes", "install", "--no-install-recommends", "ssh-server^"},
+		},
+		{
+			factory:  DNFCommandFactory{},
+			groups:   []string{"development-tools", "nodejs:18"},
+			expected: []string{"dnf", "--assumeyes", "--quiet", "--setopt=install_weak_deps=False", "install", "@development-tools", "@nodejs:18"},
+		},
+		{
+			factory:  PacmanCommandFactory{},
+			groups:   []string{"base-devel"},
+			expected: []string{"pacman", "--sync", "--noconfirm", "--noprogressbar", "--quiet", "base-devel"},
+		},
+		{
+			factory:  ZypperCommandFactory{},
+			groups:   []string{"devel_basis"},
+			expected: []string{"zypper", "--non-interactive", "--quiet", "install", "--type", "pattern", "--no-recommends", "devel_basis"},
+		},
+	}
+
+	for _, c := range cases {
+		actual, _ := c.factory.NewInstallGroupsCmd(c.groups, InstallOptions{})
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected command %q, found %q", c.factory.Backend(), c.expected, actual)
+		}
+	}
+}
+
+func TestAPKVirtual(t *testing.T) {
+	cmd, _ := APKCommandFactory{}.NewInstallCmd([]string{"gcc", "make"}, InstallOptions{Virtual: ".build-deps"})
+	expected := []string{"apk", "--no-cache", "--no-progress", "--quiet", "add", "--virtual", ".build-deps", "gcc", "make"}
+	if !reflect.DeepEqual(cmd, expected) {
+		t.Errorf("expected install command %q, found %q", expected, cmd)
+	}
+}
@@ -5,6 +5,7 @@ package pckg
 
 import (
 	"fmt"
+	"path"
 	"strings"
 )
 
@@ -15,23 +16,68 @@ func (f XBPSCommandFactory) NewCleanCacheCmd() (cmd, capabilities []string) {
 	return cmd, []string{}
 }
 
-func (f XBPSCommandFactory) NewInstallCmd(packages []string) (cmd, capabilities []string) {
+func (f XBPSCommandFactory) NewInstallCmd(packages []string, options InstallOptions) (cmd, capabilities []string) {
 	cmd = []string{"xbps-install", "--yes"}
 	cmd = append(cmd, packages...)
 	capabilities = []string{"CAP_DAC_OVERRIDE"}
 	return cmd, capabilities
 }
 
+func (f XBPSCommandFactory) NewInstallGroupsCmd(groups []string, options InstallOptions) (cmd, capabilities []string) {
+	return []string{}, []string{}
+}
+
+func (f XBPSCommandFactory) NewDownloadCmds(packages []string, dir string, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"xbps-install", "--sync", "--download-only", "--yes", "--cachedir", dir}
+	cmd = append(cmd, packages...)
+	return [][]string{cmd}, []string{"CAP_DAC_OVERRIDE"}
+}
+
+// NewInstallFilesCmds indexes the package files so that their directory can
+// serve as a repository, since XBPS can't install package files directly.
+func (f XBPSCommandFactory) NewInstallFilesCmds(files []string, offline bool, options InstallOptions) (cmds [][]string, capabilities []string) {
+	if len(files) == 0 {
+		return [][]string{}, []string{}
+	}
+
+	index := []string{"xbps-rindex", "--add"}
+	index = append(index, files...)
+
+	install := []string{"xbps-install", "--repository", path.Dir(files[0])}
+	if offline {
+		install = append(install, "--ignore-conf-repos")
+	}
+	install = append(install, "--yes")
+	for _, file := range files {
+		install = append(install, xbpsPackageName(file))
+	}
+
+	return [][]string{index, install}, []string{"CAP_DAC_OVERRIDE"}
+}
+
+// xbpsPackageName returns the name of the package in an XBPS package file,
+// whose base name has the form NAME-VERSION_REVISION.ARCH.xbps.
+func xbpsPackageName(file string) string {
+	pkgver := strings.TrimSuffix(path.Base(file), ".xbps")
+	if i := strings.LastIndex(pkgver, "."); i >= 0 {
+		pkgver = pkgver[:i]
+	}
+	if i := strings.LastIndex(pkgver, "-"); i >= 0 {
+		return pkgver[:i]
+	}
+	return pkgver
+}
+
 func (f XBPSCommandFactory) NewListInstalledPackagesCmd() (
 	cmd []string,
 	capabilities []string,
-	parse func([]string) ([]string, error),
+	parse func([]string) ([]Package, error),
 ) {
 	cmd = []string{"xbps-query", "--list-pkgs"}
 
 	// expected line format: status name-version_revision description
-	parse = func(lines []string) ([]string, error) {
-		result := make([]string, 0, len(lines))
+	parse = func(lines []string) ([]Package, error) {
+		result := make([]Package, 0, len(lines))
 		for _, l := range lines {
 			f := strings.Fields(l)
 			if len(f) < 3 {
@@ -41,8 +87,10 @@ func (f XBPSCommandFactory) NewListInstalledPackagesCmd() (
 			if i == -1 {
 				return nil, fmt.Errorf("expected format 'name-version_revision' for field %q", f[1])
 			}
-			name := f[1][:i]
-			result = append(result, name)
+			result = append(result, Package{
+				Name:    f[1][:i],
+				Version: f[1][i+1:],
+			})
 		}
 		return result, nil
 	}
@@ -50,8 +98,43 @@ func (f XBPSCommandFactory) NewListInstalledPackagesCmd() (
 	return cmd, []string{}, parse
 }
 
+func (f XBPSCommandFactory) NewRemoveCmd(packages []string) (cmd, capabilities []string) {
+	cmd = []string{"xbps-remove", "--recursive", "--yes"}
+	cmd = append(cmd, packages...)
+	capabilities = []string{"CAP_DAC_OVERRIDE"}
+	return cmd, capabilities
+}
+
+func (f XBPSCommandFactory) Pin(name, version string) string {
+	return name + "-" + version
+}
+
+// KeyPath preserves the base name of the key file because XBPS looks up the
+// key with which a repository is signed by its fingerprint, which names the
+// file.
+func (f XBPSCommandFactory) KeyPath(name, file string) string {
+	return path.Join("/var/db/xbps/keys", path.Base(file))
+}
+
+func (f XBPSCommandFactory) NewAddRepositoryCmds(r Repository) (files []File, cmds [][]string, capabilities []string) {
+	line := fmt.Sprintf("repository=%s\n", r.URL)
+	if !r.Enabled {
+		line = disable(line)
+	}
+	files = []File{{Path: path.Join("/etc/xbps.d", r.Name+".conf"), Contents: line}}
+	return files, [][]string{}, []string{}
+}
+
+// LocalRepositoryOptions ignores `r.Trusted` because XBPS doesn't require
+// local repositories to be signed.
+func (f XBPSCommandFactory) LocalRepositoryOptions(r LocalRepository) (options []string, files []File) {
+	return []string{"--repository", r.Path, "--ignore-conf-repos"}, []File{}
+}
+
 func (f XBPSCommandFactory) NewUpdateIndexCmd() (cmd, capabilities []string) {
-	return []string{}, []string{}
+	cmd = []string{"xbps-install", "--sync", "--yes"}
+	capabilities = []string{"CAP_DAC_OVERRIDE"}
+	return cmd, capabilities
 }
 
 func (f XBPSCommandFactory) NewUpgradeCmd() (cmd, capabilities []string) {
@@ -60,6 +143,10 @@ func (f XBPSCommandFactory) NewUpgradeCmd() (cmd, capabilities []string) {
 	return cmd, capabilities
 }
 
+func (f XBPSCommandFactory) NewSecurityUpgradeCmds() (files []File, cmds [][]string, capabilities []string) {
+	return []File{}, [][]string{}, []string{}
+}
+
 func (f XBPSCommandFactory) Backend() Backend {
 	return XBPS
 }
@@ -2,6 +2,7 @@ package pckg
 
 import (
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -85,8 +86,34 @@ func TestParseXBPSPackages(t *testing.T) {
 	}
 
 	for i := range expected {
-		if actual[i] != expected[i] {
-			t.Errorf("expected package %s at position %d, found %s", expected[i], i, actual[i])
+		if actual[i].Name != expected[i] {
+			t.Errorf("expected package %s at position %d, found %s", expected[i], i, actual[i].Name)
 		}
 	}
+
+	first := Package{
+		Name:    "acl",
+		Version: "2.3.1_1",
+	}
+	if actual[0] != first {
+		t.Errorf("expected first package %+v, found %+v", first, actual[0])
+	}
+}
+
+func TestXBPSInstallFilesCmds(t *testing.T) {
+	files := []string{
+		"/tmp/packages/curl-8.2.1_1.x86_64.xbps",
+		"/tmp/packages/ca-certificates-20230311+3.89_1.noarch.xbps",
+	}
+
+	cmds, _ := XBPSCommandFactory{}.NewInstallFilesCmds(files, true, InstallOptions{})
+
+	expected := [][]string{
+		append([]string{"xbps-rindex", "--add"}, files...),
+		{"xbps-install", "--repository", "/tmp/packages", "--ignore-conf-repos", "--yes", "curl", "ca-certificates"},
+	}
+
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("expected commands %q, found %q", expected, cmds)
+	}
 }
@@ -5,6 +5,8 @@ package pckg
 
 import (
 	"fmt"
+	"path"
+	"strconv"
 	"strings"
 )
 
@@ -15,45 +17,106 @@ func (f ZypperCommandFactory) NewCleanCacheCmd() (cmd, capabilities []string) {
 	return cmd, []string{}
 }
 
-func (f ZypperCommandFactory) NewInstallCmd(packages []string) (cmd, capabilities []string) {
-	cmd = []string{"zypper", "--non-interactive", "--quiet", "install", "--no-recommends"}
+func (f ZypperCommandFactory) NewInstallCmd(packages []string, options InstallOptions) (cmd, capabilities []string) {
+	cmd = []string{"zypper", "--non-interactive", "--quiet", "install", zypperRecommendsOption(options)}
 	cmd = append(cmd, packages...)
 	return cmd, []string{}
 }
 
+// NewInstallGroupsCmd installs zypper patterns.
+func (f ZypperCommandFactory) NewInstallGroupsCmd(groups []string, options InstallOptions) (cmd, capabilities []string) {
+	cmd = []string{"zypper", "--non-interactive", "--quiet", "install", "--type", "pattern", zypperRecommendsOption(options)}
+	cmd = append(cmd, groups...)
+	return cmd, []string{}
+}
+
+func (f ZypperCommandFactory) NewDownloadCmds(packages []string, dir string, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"zypper", "--non-interactive", "--quiet", "--pkg-cache-dir", dir, "install", zypperRecommendsOption(options), "--download-only"}
+	cmd = append(cmd, packages...)
+	return [][]string{cmd}, []string{}
+}
+
+func (f ZypperCommandFactory) NewInstallFilesCmds(files []string, offline bool, options InstallOptions) (cmds [][]string, capabilities []string) {
+	cmd := []string{"zypper", "--non-interactive", "--quiet"}
+	if offline {
+		cmd = append(cmd, "--disable-repositories")
+	}
+	cmd = append(cmd, "install", zypperRecommendsOption(options), "--allow-unsigned-rpm")
+	cmd = append(cmd, files...)
+	return [][]string{cmd}, []string{}
+}
+
+// zypperRecommendsOption returns the option that makes zypper install or skip
+// the recommended packages of the packages it installs.
+func zypperRecommendsOption(options InstallOptions) string {
+	if options.WeakDeps {
+		return "--recommends"
+	}
+	return "--no-recommends"
+}
+
 func (f ZypperCommandFactory) NewListInstalledPackagesCmd() (
 	cmd []string,
 	capabilities []string,
-	parse func([]string) ([]string, error),
+	parse func([]string) ([]Package, error),
 ) {
-	cmd = []string{
-		"zypper",
-		"--non-interactive",
-		"--quiet",
-		"--terse",
-		"--no-remote",
-		"packages",
-		"--installed-only",
+	return newRPMListInstalledPackagesCmd()
+}
+
+func (f ZypperCommandFactory) NewRemoveCmd(packages []string) (cmd, capabilities []string) {
+	cmd = []string{"zypper", "--non-interactive", "--quiet", "remove", "--clean-deps"}
+	cmd = append(cmd, packages...)
+	return cmd, []string{}
+}
+
+func (f ZypperCommandFactory) Pin(name, version string) string {
+	return name + "=" + version
+}
+
+func (f ZypperCommandFactory) KeyPath(name, file string) string {
+	return path.Join("/etc/pki/rpm-gpg", "RPM-GPG-KEY-"+name)
+}
+
+func (f ZypperCommandFactory) NewAddRepositoryCmds(r Repository) (files []File, cmds [][]string, capabilities []string) {
+	cmds = [][]string{}
+	if r.Key != "" {
+		cmds = append(cmds, []string{"rpm", "--import", r.Key})
 	}
 
-	// expected line format: status | repo | name | version | arch
-	parse = func(lines []string) ([]string, error) {
-		if len(lines) < 3 {
-			return []string{}, nil
-		}
-		result := make([]string, 0, len(lines)-2)
-		for _, l := range lines[2:] {
-			f := strings.Split(l, "|")
-			if len(f) != 5 {
-				return nil, fmt.Errorf("expected 5 fields in line %q", l)
-			}
-			name := strings.TrimSpace(f[2])
-			result = append(result, name)
-		}
-		return result, nil
+	cmd := []string{"zypper", "--non-interactive", "--quiet", "addrepo", "--gpgcheck"}
+	if r.Priority != 0 {
+		cmd = append(cmd, "--priority", strconv.Itoa(r.Priority))
 	}
+	if !r.Enabled {
+		cmd = append(cmd, "--disable")
+	}
+	cmd = append(cmd, r.URL, r.Name)
+	cmds = append(cmds, cmd)
 
-	return cmd, []string{}, parse
+	return []File{}, cmds, []string{}
+}
+
+// LocalRepositoryOptions replaces the repository definitions of zypper with
+// one that declares only the local repository.
+func (f ZypperCommandFactory) LocalRepositoryOptions(r LocalRepository) (options []string, files []File) {
+	reposDir := path.Join(r.ConfigDir, "repos.d")
+	options = []string{"--reposd-dir", reposDir}
+
+	gpgCheck := 1
+	if r.Trusted {
+		gpgCheck = 0
+	}
+
+	var b strings.Builder
+	b.WriteString("[turret]\n")
+	b.WriteString("name=turret\n")
+	fmt.Fprintf(&b, "baseurl=dir:%s\n", r.Path)
+	b.WriteString("type=rpm-md\n")
+	b.WriteString("enabled=1\n")
+	b.WriteString("autorefresh=1\n")
+	fmt.Fprintf(&b, "gpgcheck=%d\n", gpgCheck)
+
+	return options, []File{{Path: path.Join(reposDir, "turret.repo"), Contents: b.String()}}
 }
 
 func (f ZypperCommandFactory) NewUpdateIndexCmd() (cmd, capabilities []string) {
@@ -65,6 +128,12 @@ func (f ZypperCommandFactory) NewUpgradeCmd() (cmd, capabilities []string) {
 	return cmd, []string{}
 }
 
+// NewSecurityUpgradeCmds applies only the patches in the security category.
+func (f ZypperCommandFactory) NewSecurityUpgradeCmds() (files []File, cmds [][]string, capabilities []string) {
+	cmd := []string{"zypper", "--non-interactive", "--quiet", "patch", "--category", "security"}
+	return []File{}, [][]string{cmd}, []string{}
+}
+
 func (f ZypperCommandFactory) Backend() Backend {
 	return Zypper
 }
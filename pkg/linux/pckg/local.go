@@ -0,0 +1,73 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package pckg
+
+// LocalRepository describes a package repository on the host's file system
+// that is mounted into the working container.
+type LocalRepository struct {
+	// Absolute path at which the repository is mounted in the working
+	// container
+	Path string
+
+	// Absolute path at which the directory holding the files returned by
+	// CommandFactory.LocalRepositoryOptions is mounted in the working
+	// container
+	ConfigDir string
+
+	// Skip verifying the signatures of the repository and its packages
+	Trusted bool
+}
+
+// LocalCommandFactory wraps a CommandFactory so that the commands that
+// resolve packages, i.e., the commands that update the package index and
+// install and upgrade packages and package groups, use only a local
+// repository.
+type LocalCommandFactory struct {
+	CommandFactory
+
+	// Local repository to use
+	Repository LocalRepository
+}
+
+func (f LocalCommandFactory) NewInstallCmd(packages []string, options InstallOptions) (cmd, capabilities []string) {
+	cmd, capabilities = f.CommandFactory.NewInstallCmd(packages, options)
+	return f.restrict(cmd), capabilities
+}
+
+func (f LocalCommandFactory) NewInstallGroupsCmd(groups []string, options InstallOptions) (cmd, capabilities []string) {
+	cmd, capabilities = f.CommandFactory.NewInstallGroupsCmd(groups, options)
+	return f.restrict(cmd), capabilities
+}
+
+func (f LocalCommandFactory) NewUpdateIndexCmd() (cmd, capabilities []string) {
+	cmd, capabilities = f.CommandFactory.NewUpdateIndexCmd()
+	return f.restrict(cmd), capabilities
+}
+
+func (f LocalCommandFactory) NewUpgradeCmd() (cmd, capabilities []string) {
+	cmd, capabilities = f.CommandFactory.NewUpgradeCmd()
+	return f.restrict(cmd), capabilities
+}
+
+func (f LocalCommandFactory) NewSecurityUpgradeCmds() (files []File, cmds [][]string, capabilities []string) {
+	files, cmds, capabilities = f.CommandFactory.NewSecurityUpgradeCmds()
+	for i, cmd := range cmds {
+		cmds[i] = f.restrict(cmd)
+	}
+	return files, cmds, capabilities
+}
+
+// restrict inserts the options that restrict the package manager to the local
+// repository after the executable of a command.
+func (f LocalCommandFactory) restrict(cmd []string) []string {
+	if len(cmd) == 0 {
+		return cmd
+	}
+	options, _ := f.CommandFactory.LocalRepositoryOptions(f.Repository)
+	result := make([]string, 0, len(cmd)+len(options))
+	result = append(result, cmd[0])
+	result = append(result, options...)
+	result = append(result, cmd[1:]...)
+	return result
+}
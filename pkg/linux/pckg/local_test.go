@@ -0,0 +1,84 @@
+package pckg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLocalCommandFactory(t *testing.T) {
+	f := LocalCommandFactory{
+		CommandFactory: DNFCommandFactory{},
+		Repository: LocalRepository{
+			Path:      "/run/repository",
+			ConfigDir: "/run/config",
+			Trusted:   true,
+		},
+	}
+
+	cmd, _ := f.NewInstallCmd([]string{"curl"}, InstallOptions{})
+	expected := []string{
+		"dnf",
+		"--repofrompath=turret,/run/repository",
+		"--repo=turret",
+		"--nogpgcheck",
+		"--assumeyes",
+		"--quiet",
+		"--setopt=install_weak_deps=False",
+		"install",
+		"curl",
+	}
+	if !reflect.DeepEqual(cmd, expected) {
+		t.Errorf("expected install command %q, found %q", expected, cmd)
+	}
+
+	cmd, _ = f.NewRemoveCmd([]string{"curl"})
+	expected, _ = DNFCommandFactory{}.NewRemoveCmd([]string{"curl"})
+	if !reflect.DeepEqual(cmd, expected) {
+		t.Errorf("expected remove command %q, found %q", expected, cmd)
+	}
+
+	if cmd, _ := (LocalCommandFactory{CommandFactory: DNFCommandFactory{}}).NewUpdateIndexCmd(); len(cmd) != 0 {
+		t.Errorf("expected no update command, found %q", cmd)
+	}
+}
+
+func TestLocalRepositoryOptions(t *testing.T) {
+	r := LocalRepository{
+		Path:      "/run/repository",
+		ConfigDir: "/run/config",
+	}
+
+	options, files := APTCommandFactory{}.LocalRepositoryOptions(r)
+
+	expectedOptions := []string{
+		"-o", "Dir::Etc::SourceList=/run/config/sources.list",
+		"-o", "Dir::Etc::SourceParts=-",
+		"-o", "APT::Get::List-Cleanup=0",
+	}
+	if !reflect.DeepEqual(options, expectedOptions) {
+		t.Errorf("expected options %q, found %q", expectedOptions, options)
+	}
+
+	expectedFiles := []File{
+		{
+			Path:     "/run/config/sources.list",
+			Contents: "deb file:/run/repository ./\n",
+		},
+	}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("expected files %+v, found %+v", expectedFiles, files)
+	}
+
+	r.Trusted = true
+	_, files = ZypperCommandFactory{}.LocalRepositoryOptions(r)
+
+	expectedFiles = []File{
+		{
+			Path:     "/run/config/repos.d/turret.repo",
+			Contents: "[turret]\nname=turret\nbaseurl=dir:/run/repository\ntype=rpm-md\nenabled=1\nautorefresh=1\ngpgcheck=0\n",
+		},
+	}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("expected files %+v, found %+v", expectedFiles, files)
+	}
+}
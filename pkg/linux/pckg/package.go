@@ -0,0 +1,40 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package pckg
+
+// Package identifies a package and, optionally, the version of the package to
+// select.
+type Package struct {
+	// Name of the package
+	Name string `toml:"name" json:"name"`
+
+	// Version of the package; any version if empty
+	Version string `toml:"version,omitempty" json:"version,omitempty"`
+
+	// Architecture for which the package was built, e.g., x86_64 or noarch;
+	// empty if unknown
+	Architecture string `toml:"arch,omitempty" json:"arch,omitempty"`
+
+	// License of the package as declared by its packagers, preferably as an
+	// SPDX license expression; empty if unknown
+	License string `toml:"-" json:"-"`
+
+	// Name of the source package from which the package was built; empty if
+	// unknown
+	Source string `toml:"-" json:"-"`
+}
+
+// Args returns the arguments that select the packages in the install command
+// of the package manager for which `f` makes commands.
+func Args(f CommandFactory, packages []Package) []string {
+	args := make([]string, len(packages))
+	for i, p := range packages {
+		if p.Version == "" {
+			args[i] = p.Name
+		} else {
+			args[i] = f.Pin(p.Name, p.Version)
+		}
+	}
+	return args
+}
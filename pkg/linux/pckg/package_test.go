@@ -0,0 +1,37 @@
+package pckg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgs(t *testing.T) {
+	packages := []Package{
+		{Name: "ca-certificates"},
+		{Name: "curl", Version: "8.2.1"},
+	}
+
+	cases := []struct {
+		backend  Backend
+		expected []string
+	}{
+		{APK, []string{"ca-certificates", "curl=8.2.1"}},
+		{APT, []string{"ca-certificates", "curl=8.2.1"}},
+		{DNF, []string{"ca-certificates", "curl-8.2.1"}},
+		{Pacman, []string{"ca-certificates", "curl=8.2.1"}},
+		{XBPS, []string{"ca-certificates", "curl-8.2.1"}},
+		{Zypper, []string{"ca-certificates", "curl=8.2.1"}},
+	}
+
+	for _, c := range cases {
+		f, err := NewCommandFactory(c.backend)
+		if err != nil {
+			t.Fatalf("creating %s command factory: %v", c.backend, err)
+		}
+
+		actual := Args(f, packages)
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected %v, found %v", c.backend, c.expected, actual)
+		}
+	}
+}
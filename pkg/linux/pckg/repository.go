@@ -0,0 +1,70 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package pckg
+
+import "strings"
+
+// Repository describes a package repository to configure in addition to the
+// repositories that the distro configures.
+type Repository struct {
+	// Unique name of the repository
+	Name string
+
+	// URL of the repository
+	URL string
+
+	// Distribution suite, e.g., bookworm; only used by APT
+	Suite string
+
+	// Archive areas, e.g., main; only used by APT
+	Components []string
+
+	// Absolute path to the key with which the repository is signed in the
+	// working container, as returned by CommandFactory.KeyPath; empty if the
+	// repository is signed with a key that the package manager already trusts
+	Key string
+
+	// Priority of the repository in the package manager's own terms; 0 for
+	// the default priority
+	Priority int
+
+	// Use the repository; a disabled repository is configured but not used
+	Enabled bool
+}
+
+// File describes the contents of a file in the working container.
+type File struct {
+	// Absolute path to the file
+	Path string
+
+	// Contents of the file
+	Contents string
+
+	// Append the contents to the file instead of replacing the file
+	Append bool
+}
+
+// RepositoryPriorityRange returns the smallest and largest priorities that
+// can be assigned to a repository with the package manager; both are 0 if the
+// package manager doesn't support repository priorities.
+func (b Backend) RepositoryPriorityRange() (min, max int) {
+	switch b {
+	case APT:
+		min, max = -32768, 32767
+	case DNF, Zypper:
+		min, max = 1, 99
+	}
+	return min, max
+}
+
+// disable returns the lines of `text` commented out with a number sign.
+func disable(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, l := range lines {
+		if l != "" && l != "\n" {
+			lines[i] = "#" + l
+		}
+	}
+	return strings.Join(lines, "")
+}
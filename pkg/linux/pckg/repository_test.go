@@ -0,0 +1,79 @@
+package pckg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddRepositoryCmds(t *testing.T) {
+	r := Repository{
+		Name:       "example",
+		URL:        "https://packages.example.com/debian",
+		Suite:      "bookworm",
+		Components: []string{"main", "contrib"},
+		Key:        "/etc/apt/keyrings/example.asc",
+		Priority:   900,
+		Enabled:    true,
+	}
+
+	files, _, _ := APTCommandFactory{}.NewAddRepositoryCmds(r)
+
+	expected := []File{
+		{
+			Path:     "/etc/apt/sources.list.d/example.sources",
+			Contents: "Types: deb\nURIs: https://packages.example.com/debian\nSuites: bookworm\nComponents: main contrib\nSigned-By: /etc/apt/keyrings/example.asc\n",
+		},
+		{
+			Path:     "/etc/apt/preferences.d/example.pref",
+			Contents: "Package: *\nPin: origin \"packages.example.com\"\nPin-Priority: 900\n",
+		},
+	}
+
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %+v, found %+v", expected, files)
+	}
+
+	r = Repository{
+		Name:    "example",
+		URL:     "https://packages.example.com/arch/$repo/os/$arch",
+		Key:     "/etc/pacman.d/keys/example.asc",
+		Enabled: false,
+	}
+
+	files, cmds, _ := PacmanCommandFactory{}.NewAddRepositoryCmds(r)
+
+	expected = []File{
+		{
+			Path:     "/etc/pacman.conf",
+			Contents: "\n#[example]\n#SigLevel = Required TrustAll\n#Server = https://packages.example.com/arch/$repo/os/$arch\n",
+			Append:   true,
+		},
+	}
+
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %+v, found %+v", expected, files)
+	}
+
+	expectedCmds := [][]string{{"pacman-key", "--add", "/etc/pacman.d/keys/example.asc"}}
+	if !reflect.DeepEqual(cmds, expectedCmds) {
+		t.Errorf("expected commands %v, found %v", expectedCmds, cmds)
+	}
+
+	r = Repository{
+		Name:     "example",
+		URL:      "https://packages.example.com/opensuse",
+		Key:      "/etc/pki/rpm-gpg/RPM-GPG-KEY-example",
+		Priority: 90,
+		Enabled:  true,
+	}
+
+	_, cmds, _ = ZypperCommandFactory{}.NewAddRepositoryCmds(r)
+
+	expectedCmds = [][]string{
+		{"rpm", "--import", "/etc/pki/rpm-gpg/RPM-GPG-KEY-example"},
+		{"zypper", "--non-interactive", "--quiet", "addrepo", "--gpgcheck", "--priority", "90", "https://packages.example.com/opensuse", "example"},
+	}
+	if !reflect.DeepEqual(cmds, expectedCmds) {
+		t.Errorf("expected commands %v, found %v", expectedCmds, cmds)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 OK Ryoko
+// SPDX-License-Identifier: Apache-2.0
+
+package pckg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// newRPMListInstalledPackagesCmd returns (1) a command that lists the packages
+// in the RPM database, which DNF and zypper share, (2) the Linux capabilities
+// needed by that command and (3) a function that parses its output.
+func newRPMListInstalledPackagesCmd() (
+	cmd []string,
+	capabilities []string,
+	parse func([]string) ([]Package, error),
+) {
+	cmd = []string{
+		"rpm",
+		"--query",
+		"--all",
+		"--queryformat",
+		"%{NAME}\\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\\t%{ARCH}\\t%{LICENSE}\\t%{SOURCERPM}\\n",
+	}
+
+	// expected line format: name\t[epoch:]version-release\tarch\tlicense\tsourcerpm
+	parse = func(lines []string) ([]Package, error) {
+		result := make([]Package, 0, len(lines))
+		for _, l := range lines {
+			f := strings.Split(l, "\t")
+			if len(f) != 5 {
+				return nil, fmt.Errorf("expected 5 tab-delimited fields in line %q", l)
+			}
+
+			// The public keys that RPM trusts are recorded as packages
+			if f[0] == "gpg-pubkey" {
+				continue
+			}
+
+			p := Package{
+				Name:         f[0],
+				Version:      f[1],
+				Architecture: f[2],
+				Source:       rpmSourceName(f[4]),
+			}
+			if f[3] != "(none)" {
+				p.License = f[3]
+			}
+			result = append(result, p)
+		}
+		return result, nil
+	}
+
+	return cmd, []string{}, parse
+}
+
+// rpmSourceName returns the name of the source package in the file name of a
+// source RPM, e.g., curl in curl-8.2.1-1.fc39.src.rpm, or an empty string if
+// the file name is malformed.
+func rpmSourceName(file string) string {
+	nvr, ok := strings.CutSuffix(file, ".src.rpm")
+	if !ok {
+		nvr, ok = strings.CutSuffix(file, ".nosrc.rpm")
+	}
+	if !ok {
+		return ""
+	}
+	for i := 0; i < 2; i++ {
+		j := strings.LastIndex(nvr, "-")
+		if j == -1 {
+			return ""
+		}
+		nvr = nvr[:j]
+	}
+	return nvr
+}
@@ -45,6 +45,12 @@ func (w *BackendWrapper) UnmarshalText(text []byte) error {
 	return err
 }
 
+// Enum returns the identifiers from which the user and group management
+// utility can be decoded.
+func (w BackendWrapper) Enum() []string {
+	return []string{"busybox", "shadow", "shadow-utils"}
+}
+
 func parseBackendString(s string) (Backend, error) {
 	var b Backend
 	switch strings.ToLower(s) {
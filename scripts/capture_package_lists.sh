@@ -0,0 +1,83 @@
+#!/bin/sh -
+#
+# Copyright 2023 OK Ryoko
+# SPDX-License-Identifier: Apache-2.0
+#
+# Captures the lists of installed packages that the parsers of the package
+# command factories are tested against, running each listing command in the
+# reference image pinned by digest, and prints the metadata.json entry of each
+# capture.
+#
+# Usage: ./scripts/capture_package_lists.sh PACKAGE-MANAGER...
+
+set -o errexit
+set -o nounset
+
+testdata="$(dirname "$0")/../pkg/linux/pckg/testdata"
+
+# capture runs a shell command in an image, writing its output to the testdata
+# file of a package manager, and prints the metadata.json entry of the capture.
+#
+# Parameters:
+#   $1: package manager, e.g., apt
+#   $2: image reference
+#   $3: image digest
+#   $4: listing command, a shell command string
+#   $5: version command, a shell command string printing the version of the
+#       package manager
+#
+capture() {
+	manager="$1"; reference="$2"; digest="$3"; list="$4"; version="$5"
+	podman run --rm "${reference}@${digest}" sh -c "${list}" >"${testdata}/${manager}.txt"
+	cat <<-EOF
+	{
+	  "packageManager": "${manager}",
+	  "version": "$(podman run --rm "${reference}@${digest}" sh -c "${version}")",
+	  "reference": "${reference}",
+	  "digest": "${digest}",
+	  "command": "$(printf '%s' "${list}" | sed -e 's/[\\"]/\\&/g')"
+	}
+	EOF
+}
+
+if [ $# -eq 0 ]; then
+	echo "usage: $0 PACKAGE-MANAGER..." >&2
+	exit 2
+fi
+
+for manager in "$@"; do
+	case "${manager}" in
+	apt)
+		capture apt \
+			docker.io/library/debian:12.1-slim \
+			sha256:89468107e4c2b9fdea2f15fc582bf92c25aa4296a661ca0202f7ea2f4fc3f48c \
+			"dpkg-query --show --showformat='\${db:Status-Abbrev}\\t\${Package}\\t\${Version}\\t\${Architecture}\\t\${source:Package}\\n'" \
+			"dpkg-query --show --showformat='\${Version}' apt"
+		;;
+	dnf)
+		capture dnf \
+			registry.fedoraproject.org/fedora:39-x86_64 \
+			sha256:3774a4671f4212a82a85ae8942137c2982288623c1c24435a9cd83c4fc440174 \
+			"rpm --query --all --queryformat '%{NAME}\\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\\t%{ARCH}\\t%{LICENSE}\\t%{SOURCERPM}\\n'" \
+			"rpm --query --queryformat '%{VERSION}' dnf"
+		;;
+	pacman)
+		capture pacman \
+			docker.io/library/archlinux:latest \
+			sha256:b29dab0d5f362cfe2ed4662c46f27855681d56fb548fd2f6d927fd573131178a \
+			"pacman --color never --query --info" \
+			"pacman --query pacman | cut -d ' ' -f 2"
+		;;
+	zypper)
+		capture zypper \
+			registry.opensuse.org/opensuse/tumbleweed:latest \
+			sha256:70e271ca97780bdaa73e25fdd71173140239b5ea547f32c928dcfcd8eab50c39 \
+			"rpm --query --all --queryformat '%{NAME}\\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\\t%{ARCH}\\t%{LICENSE}\\t%{SOURCERPM}\\n'" \
+			"rpm --query --queryformat '%{VERSION}' zypper"
+		;;
+	*)
+		echo "$0: unsupported package manager '${manager}'" >&2
+		exit 2
+		;;
+	esac
+done